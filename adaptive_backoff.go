@@ -0,0 +1,109 @@
+package httpretrier
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// adaptiveSmoothing is the weight of the latest attempt in a host's failure rate
+	adaptiveSmoothing = 0.2
+
+	// adaptiveMaxFactor is the factor applied to the delays of a host whose
+	// every recent attempt failed
+	adaptiveMaxFactor = 4.0
+
+	// adaptiveResetSuccesses is the number of consecutive successes after
+	// which a host's failures are forgotten
+	adaptiveResetSuccesses = 10
+
+	// adaptiveMaxHosts bounds the number of hosts whose failures are remembered
+	adaptiveMaxHosts = 1024
+)
+
+// hostFailures is the recent failure history of a single host
+type hostFailures struct {
+	rate      float64 // Exponentially weighted moving average of the failures, from 0 to 1
+	successes int     // Consecutive successes since the last failure
+}
+
+// adaptiveBackoff remembers the recent failures of each host, so the retries
+// to a host that has just been failing start from longer delays, and get back
+// to normal as its attempts succeed again. Only hosts with recent failures
+// are tracked: a host is forgotten after adaptiveResetSuccesses consecutive
+// successes, and at most adaptiveMaxHosts hosts are tracked.
+type adaptiveBackoff struct {
+	mu    sync.Mutex
+	hosts map[string]*hostFailures
+}
+
+// newAdaptiveBackoff creates an adaptiveBackoff without any failure
+func newAdaptiveBackoff() *adaptiveBackoff {
+	return &adaptiveBackoff{hosts: make(map[string]*hostFailures)}
+}
+
+// record updates the host's failure rate with the outcome of an attempt
+func (a *adaptiveBackoff) record(host string, failed bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	h, ok := a.hosts[host]
+	if !ok {
+		// Healthy hosts aren't tracked
+		if !failed {
+			return
+		}
+
+		// Make room by forgetting an arbitrary host
+		if len(a.hosts) >= adaptiveMaxHosts {
+			for other := range a.hosts {
+				delete(a.hosts, other)
+				break
+			}
+		}
+		h = &hostFailures{}
+		a.hosts[host] = h
+	}
+
+	if failed {
+		h.rate += adaptiveSmoothing * (1 - h.rate)
+		h.successes = 0
+		return
+	}
+
+	h.rate -= adaptiveSmoothing * h.rate
+	h.successes++
+	if h.successes >= adaptiveResetSuccesses {
+		delete(a.hosts, host)
+	}
+}
+
+// factor returns the factor to apply to the delays of the host's retries,
+// from 1 for a host without recent failures to adaptiveMaxFactor for one
+// whose every recent attempt failed
+func (a *adaptiveBackoff) factor(host string) float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	h, ok := a.hosts[host]
+	if !ok {
+		return 1
+	}
+
+	return 1 + h.rate*(adaptiveMaxFactor-1)
+}
+
+// scaleDelay returns delay multiplied by factor, without going past maxDelay
+// unless it is NoMaxDelay. A delay already past maxDelay is kept as is.
+func scaleDelay(delay time.Duration, factor float64, maxDelay time.Duration) time.Duration {
+	if factor <= 1 {
+		return delay
+	}
+
+	scaled := time.Duration(float64(delay) * factor)
+	if maxDelay != NoMaxDelay && scaled > maxDelay {
+		scaled = max(delay, maxDelay)
+	}
+
+	return scaled
+}
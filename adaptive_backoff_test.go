@@ -0,0 +1,147 @@
+package httpretrier
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveBackoff_FactorAndReset(t *testing.T) {
+	a := newAdaptiveBackoff()
+	if f := a.factor("a"); f != 1 {
+		t.Fatalf("Expected factor 1 for an unknown host, got %v", f)
+	}
+
+	// Successes of healthy hosts aren't tracked
+	a.record("a", false)
+	if len(a.hosts) != 0 {
+		t.Fatalf("Expected healthy hosts not to be tracked, got %d hosts", len(a.hosts))
+	}
+
+	// Failures raise the factor, towards the max
+	var previous float64 = 1
+	for range 20 {
+		a.record("a", true)
+		f := a.factor("a")
+		if f <= previous || f > adaptiveMaxFactor {
+			t.Fatalf("Expected the factor to grow up to %v, got %v after %v", adaptiveMaxFactor, f, previous)
+		}
+		previous = f
+	}
+
+	// Other hosts are not affected
+	if f := a.factor("b"); f != 1 {
+		t.Errorf("Expected factor 1 for another host, got %v", f)
+	}
+
+	// Successes make it decay, and a run of them resets it
+	for i := range adaptiveResetSuccesses - 1 {
+		a.record("a", false)
+		f := a.factor("a")
+		if f >= previous || f <= 1 {
+			t.Fatalf("Expected the factor to decay after %d successes, got %v after %v", i+1, f, previous)
+		}
+		previous = f
+	}
+	a.record("a", false)
+	if f := a.factor("a"); f != 1 {
+		t.Errorf("Expected the factor to be reset after %d successes, got %v", adaptiveResetSuccesses, f)
+	}
+}
+
+func TestAdaptiveBackoff_Bounded(t *testing.T) {
+	a := newAdaptiveBackoff()
+	for i := range adaptiveMaxHosts + 10 {
+		a.record(fmt.Sprintf("host-%d", i), true)
+	}
+	if len(a.hosts) != adaptiveMaxHosts {
+		t.Errorf("Expected at most %d hosts, got %d", adaptiveMaxHosts, len(a.hosts))
+	}
+}
+
+func TestScaleDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		delay    time.Duration
+		factor   float64
+		maxDelay time.Duration
+		expected time.Duration
+	}{
+		{name: "No Factor", delay: time.Second, factor: 1, maxDelay: 10 * time.Second, expected: time.Second},
+		{name: "Scaled", delay: time.Second, factor: 2.5, maxDelay: 10 * time.Second, expected: 2500 * time.Millisecond},
+		{name: "Capped", delay: 5 * time.Second, factor: 4, maxDelay: 10 * time.Second, expected: 10 * time.Second},
+		{name: "No Max Delay", delay: 5 * time.Second, factor: 4, maxDelay: NoMaxDelay, expected: 20 * time.Second},
+		{name: "Already Past Max", delay: 20 * time.Second, factor: 2, maxDelay: 10 * time.Second, expected: 20 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := scaleDelay(tt.delay, tt.factor, tt.maxDelay); actual != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestClientBuilder_WithAdaptiveBackoff(t *testing.T) {
+	var failing bool
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			status := http.StatusOK
+			if failing {
+				status = http.StatusServiceUnavailable
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader("body")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	clock := &fakeClock{now: time.Now()}
+	httpClient := NewClientBuilder().
+		WithBaseTransport(mockRT).
+		WithMaxRetries(2).
+		WithRetryStrategy(FixedDelayStrategy).
+		WithRetryBaseDelay(time.Second).
+		WithClock(clock).
+		WithAdaptiveBackoff().
+		Build()
+
+	send := func() []time.Duration {
+		t.Helper()
+		clock.sleeps = nil
+		req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		if resp, err := httpClient.Do(req); err == nil {
+			resp.Body.Close()
+		}
+		return clock.sleeps
+	}
+
+	// The first failing request retries with the base delay
+	failing = true
+	if sleeps := send(); !slices.Equal(sleeps, []time.Duration{time.Second, time.Second}) {
+		t.Fatalf("Expected the base delays for a host without failures, got %v", sleeps)
+	}
+
+	// The next one waits longer, the host having just failed
+	sleeps := send()
+	if len(sleeps) != 2 || sleeps[0] <= time.Second || sleeps[0] != sleeps[1] {
+		t.Fatalf("Expected longer delays after recent failures, got %v", sleeps)
+	}
+
+	// A run of successes brings the delays back to normal
+	failing = false
+	for range adaptiveResetSuccesses {
+		send()
+	}
+	failing = true
+	if sleeps := send(); !slices.Equal(sleeps, []time.Duration{time.Second, time.Second}) {
+		t.Errorf("Expected the base delays after the host recovered, got %v", sleeps)
+	}
+}
@@ -0,0 +1,95 @@
+package httpretrier
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// responseInfoKey is the context key for the responseInfo of a response
+type responseInfoKey struct{}
+
+// responseInfo describes how the retry transport obtained a response
+type responseInfo struct {
+	attempts    int           // Attempts made, including the first one
+	backoffTime time.Duration // Total backoff delay waited between them
+}
+
+// responseInfoFrom returns the responseInfo stored in the context of resp.Request
+func responseInfoFrom(resp *http.Response) responseInfo {
+	if resp == nil || resp.Request == nil {
+		return responseInfo{}
+	}
+
+	info, _ := resp.Request.Context().Value(responseInfoKey{}).(responseInfo)
+	return info
+}
+
+// AttemptsFromResponse returns the number of attempts made to obtain resp,
+// including the first one, so 1 means no retries were needed.
+// The count is stored in the context of resp.Request by the retry transport,
+// for successful responses as well as the last failed response returned with
+// WithReturnLastResponse. It returns 0 if resp didn't come from the retry transport.
+func AttemptsFromResponse(resp *http.Response) int {
+	return responseInfoFrom(resp).attempts
+}
+
+// BackoffTimeFromResponse returns the total backoff delay waited between the
+// attempts made to obtain resp, 0 if it took a single attempt.
+// Like the attempts count, it is stored in the context of resp.Request by
+// the retry transport, for successful responses as well as the last failed
+// response returned with WithReturnLastResponse. With fallback hosts, both
+// only cover the host the response came from. It returns 0 if resp didn't
+// come from the retry transport.
+func BackoffTimeFromResponse(resp *http.Response) time.Duration {
+	return responseInfoFrom(resp).backoffTime
+}
+
+// AttemptInfo describes an attempt sent by the retry transport
+type AttemptInfo struct {
+	Attempt int           // Index of the attempt, starting at 0 for the first one
+	Delay   time.Duration // Backoff delay waited before the attempt, 0 for the first one
+}
+
+// AttemptInfoKey is the context key under which the retry transport stores
+// the AttemptInfo of every attempt's request, before passing it to the
+// transport below, so that the round trippers it wraps, e.g. for logging or
+// tracing, know which attempt they handle.
+// AttemptInfoFromContext and AttemptFromContext read it; the key is exported
+// for code reading context values by key, ctx.Value(AttemptInfoKey{}) being
+// an AttemptInfo.
+type AttemptInfoKey struct{}
+
+// AttemptInfoFromContext returns the AttemptInfo carried by the context of an
+// attempt's request, as seen by the transport sending the attempt, e.g. an
+// attempt middleware. It returns false outside of an attempt.
+func AttemptInfoFromContext(ctx context.Context) (AttemptInfo, bool) {
+	info, ok := ctx.Value(AttemptInfoKey{}).(AttemptInfo)
+	return info, ok
+}
+
+// AttemptFromContext returns the index of the attempt whose request carries
+// ctx, starting at 0 for the first attempt, so that the transports below the
+// retry transport can log it without counting attempts themselves. It also
+// returns 0 outside of an attempt, see AttemptInfoFromContext to tell apart.
+func AttemptFromContext(ctx context.Context) int {
+	info, _ := AttemptInfoFromContext(ctx)
+	return info.Attempt
+}
+
+// finishResponse prepares the response returned by RoundTrip: it records the
+// number of attempts and the backoff time, and keeps the attempt's context
+// alive until the body is closed
+func (r *retryTransport) finishResponse(resp *http.Response, attemptReq *http.Request, info responseInfo, cancel context.CancelFunc) *http.Response {
+	// The attempt's context must outlive RoundTrip until the body is consumed
+	if r.PerAttemptTimeout > 0 {
+		resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	}
+
+	if resp.Request == nil {
+		resp.Request = attemptReq
+	}
+	resp.Request = resp.Request.WithContext(context.WithValue(resp.Request.Context(), responseInfoKey{}, info))
+
+	return resp
+}
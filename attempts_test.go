@@ -0,0 +1,179 @@
+package httpretrier
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAttemptsFromResponse(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(3, FixedDelay(1*time.Millisecond), nil)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := AttemptsFromResponse(resp); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+	if got := BackoffTimeFromResponse(resp); got != 2*time.Millisecond {
+		t.Errorf("Expected 2ms of backoff, got %v", got)
+	}
+}
+
+func TestAttemptsFromResponse_FirstAttempt(t *testing.T) {
+	retryRT := &retryTransport{
+		Transport: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				// Responses from mocks often lack a request
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("OK")),
+					Header:     make(http.Header),
+				}, nil
+			},
+		},
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+	}
+
+	resp, err := retryRT.RoundTrip(httptest.NewRequest("GET", "http://example.com", nil))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := AttemptsFromResponse(resp); got != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got)
+	}
+	if got := BackoffTimeFromResponse(resp); got != 0 {
+		t.Errorf("Expected no backoff, got %v", got)
+	}
+
+	// Responses that didn't go through the retry transport have no count
+	if got := AttemptsFromResponse(&http.Response{}); got != 0 {
+		t.Errorf("Expected 0 for a foreign response, got %d", got)
+	}
+	if got := AttemptsFromResponse(nil); got != 0 {
+		t.Errorf("Expected 0 for a nil response, got %d", got)
+	}
+	if got := BackoffTimeFromResponse(&http.Response{}); got != 0 {
+		t.Errorf("Expected no backoff for a foreign response, got %v", got)
+	}
+}
+
+func TestReturnLastResponse(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("try later"))
+	}))
+	defer server.Close()
+
+	client := NewClientBuilder().
+		WithMaxRetries(1).
+		WithRetryStrategy(FixedDelayStrategy).
+		WithRetryBaseDelay(ValidMinBaseDelay).
+		WithReturnLastResponse(true).
+		Build()
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the last response instead of an error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "try later" {
+		t.Errorf("Expected the last response body to be readable, got %q", body)
+	}
+	if got := AttemptsFromResponse(resp); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+	if got := BackoffTimeFromResponse(resp); got != ValidMinBaseDelay {
+		t.Errorf("Expected %v of backoff, got %v", ValidMinBaseDelay, got)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("Expected 2 requests, got %d", got)
+	}
+}
+
+func TestRetryTransport_AttemptHeader(t *testing.T) {
+	tests := []struct {
+		name          string
+		header        string
+		expectHeaders []string
+	}{
+		{name: "Default Header", header: DefaultAttemptHeader, expectHeaders: []string{"0", "1", "2"}},
+		{name: "Custom Header", header: "X-Attempt", expectHeaders: []string{"0", "1", "2"}},
+		{name: "Disabled", header: "", expectHeaders: []string{"", "", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			mockRT := &mockRoundTripper{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					name := tt.header
+					if name == "" {
+						name = DefaultAttemptHeader
+					}
+					got = append(got, req.Header.Get(name))
+					return &http.Response{
+						StatusCode: http.StatusServiceUnavailable,
+						Body:       io.NopCloser(strings.NewReader("")),
+						Header:     make(http.Header),
+					}, nil
+				},
+			}
+
+			retryRT := &retryTransport{
+				Transport:     mockRT,
+				MaxRetries:    2,
+				RetryStrategy: FixedDelay(1 * time.Millisecond),
+				AttemptHeader: tt.header,
+			}
+
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			_, _ = retryRT.RoundTrip(req)
+
+			if strings.Join(got, ",") != strings.Join(tt.expectHeaders, ",") {
+				t.Errorf("Expected attempt headers %q, got %q", tt.expectHeaders, got)
+			}
+			if len(req.Header) != 0 {
+				t.Errorf("Expected the original request to stay clean, got %v", req.Header)
+			}
+		})
+	}
+}
+
+func TestClientBuilder_WithAttemptHeader(t *testing.T) {
+	rt, _ := NewClientBuilder().Build().Transport.(*retryTransport)
+	if rt.AttemptHeader != DefaultAttemptHeader {
+		t.Errorf("Expected default header %q, got %q", DefaultAttemptHeader, rt.AttemptHeader)
+	}
+
+	rt, _ = NewClientBuilder().WithAttemptHeader("").Build().Transport.(*retryTransport)
+	if rt.AttemptHeader != "" {
+		t.Errorf("Expected the header to be disabled, got %q", rt.AttemptHeader)
+	}
+}
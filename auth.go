@@ -0,0 +1,156 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ErrAuthRefresh is returned, wrapping the refresh function's error, when the
+// credentials can't be refreshed after a 401 response
+var ErrAuthRefresh = errors.New("failed to refresh credentials")
+
+// maxAuthRefreshes caps the credential refreshes within an attempt, so a
+// server rejecting fresh credentials doesn't cause an endless loop
+const maxAuthRefreshes = 2
+
+// authRefresher keeps the credentials shared by the requests of a transport
+// and refreshes them when the server rejects them
+type authRefresher struct {
+	header string
+
+	// refresh returns fresh credentials, set as is as the value of the header,
+	// e.g. "Bearer <token>" for the Authorization header
+	refresh func(ctx context.Context) (string, error)
+
+	mu          sync.Mutex
+	credentials string
+	inflight    *authRefresh // The refresh in progress, if any
+}
+
+// authRefresh is a credentials refresh shared by the requests waiting for it
+type authRefresh struct {
+	done        chan struct{} // Closed once the refresh is over
+	credentials string
+	err         error
+}
+
+// newAuthRefresher creates an authRefresher setting the credentials in header
+func newAuthRefresher(header string, refresh func(ctx context.Context) (string, error)) *authRefresher {
+	return &authRefresher{header: header, refresh: refresh}
+}
+
+// current returns the latest credentials, empty until the first refresh
+func (a *authRefresher) current() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.credentials
+}
+
+// renew returns credentials newer than rejected, refreshing them unless
+// another request already did. Concurrent rejections wait for a single
+// refresh, run without holding the lock so that current keeps returning the
+// old credentials meanwhile. A request giving up while waiting doesn't cancel
+// the refresh for the others, unless it is the one running it.
+func (a *authRefresher) renew(ctx context.Context, rejected string) (string, error) {
+	a.mu.Lock()
+	if a.credentials != rejected {
+		credentials := a.credentials
+		a.mu.Unlock()
+		return credentials, nil
+	}
+
+	f := a.inflight
+	if f == nil {
+		f = &authRefresh{done: make(chan struct{})}
+		a.inflight = f
+		a.mu.Unlock()
+		a.runRefresh(ctx, f)
+		return f.credentials, f.err
+	}
+	a.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.credentials, f.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// runRefresh runs the refresh f, storing the fresh credentials on success
+func (a *authRefresher) runRefresh(ctx context.Context, f *authRefresh) {
+	credentials, err := a.refresh(ctx)
+	if err != nil {
+		f.err = fmt.Errorf("%w: %w", ErrAuthRefresh, err)
+	} else {
+		f.credentials = credentials
+	}
+
+	a.mu.Lock()
+	if err == nil {
+		a.credentials = credentials
+	}
+	a.inflight = nil
+	a.mu.Unlock()
+
+	close(f.done)
+}
+
+// roundTrip sends req through next with the latest credentials and, on a
+// 401 response, refreshes them and sends req again right away, without
+// backoff. Requests whose body can't be replayed get the 401 response as is.
+func (a *authRefresher) roundTrip(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	credentials := a.current()
+
+	for refreshes := 0; ; refreshes++ {
+		// Hedged copies of an attempt may share its headers, so set the
+		// credentials on a copy of them
+		if credentials != "" {
+			req = req.Clone(req.Context())
+			if req.Header == nil {
+				req.Header = make(http.Header)
+			}
+			req.Header.Set(a.header, credentials)
+		}
+
+		resp, err := next.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized || refreshes == maxAuthRefreshes {
+			return resp, err
+		}
+
+		replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+		if !replayable {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		credentials, err = a.renew(req.Context(), credentials)
+		if err != nil {
+			return nil, err
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get request body for retry: %w", err)
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// authTransport sends attempts through an authRefresher
+type authTransport struct {
+	next http.RoundTripper
+	auth *authRefresher
+}
+
+// RoundTrip sends req with the latest credentials, refreshing them on a 401 response
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.auth.roundTrip(t.next, req)
+}
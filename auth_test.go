@@ -0,0 +1,264 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenServer answers 401 unless the request carries the valid token, recording the tokens received
+func tokenServer(valid *atomic.Value, received *[]string) *mockRoundTripper {
+	return &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			token := req.Header.Get("Authorization")
+			*received = append(*received, token)
+			if req.Body != nil {
+				if data, _ := io.ReadAll(req.Body); string(data) != "payload" {
+					return nil, errors.New("body not replayed")
+				}
+			}
+
+			status := http.StatusOK
+			if token != valid.Load().(string) {
+				status = http.StatusUnauthorized
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader("body")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+}
+
+func TestRetryTransport_AuthRefresh(t *testing.T) {
+	var valid atomic.Value
+	valid.Store("Bearer first")
+	var received []string
+
+	var refreshes int
+	retryRT := &retryTransport{
+		Transport:     tokenServer(&valid, &received),
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Hour), // Would block the test if the refresh waited
+		auth: newAuthRefresher("Authorization", func(ctx context.Context) (string, error) {
+			refreshes++
+			return valid.Load().(string), nil
+		}),
+	}
+
+	send := func() {
+		t.Helper()
+		req, _ := http.NewRequest("POST", "http://example.com", strings.NewReader("payload"))
+		resp, err := retryRT.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", resp.StatusCode)
+		}
+	}
+
+	// The first request has no credentials yet, gets them on the 401
+	send()
+	// The next one reuses them
+	send()
+	// Once they expire, they are refreshed again
+	valid.Store("Bearer second")
+	send()
+
+	want := []string{"", "Bearer first", "Bearer first", "Bearer first", "Bearer second"}
+	if strings.Join(received, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected credentials %q, got %q", want, received)
+	}
+	if refreshes != 2 {
+		t.Errorf("Expected 2 refreshes, got %d", refreshes)
+	}
+}
+
+func TestRetryTransport_AuthRefreshCapped(t *testing.T) {
+	var valid atomic.Value
+	valid.Store("never matched")
+	var received []string
+
+	retryRT := &retryTransport{
+		Transport:     tokenServer(&valid, &received),
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		auth: newAuthRefresher("Authorization", func(ctx context.Context) (string, error) {
+			return "Bearer rejected", nil
+		}),
+	}
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected the 401 response, got %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", resp.StatusCode)
+	}
+	if len(received) != maxAuthRefreshes+1 {
+		t.Errorf("Expected %d sends, got %d", maxAuthRefreshes+1, len(received))
+	}
+}
+
+func TestRetryTransport_AuthRefreshFails(t *testing.T) {
+	var valid atomic.Value
+	valid.Store("Bearer valid")
+	var received []string
+
+	refreshErr := errors.New("token endpoint down")
+	retryRT := &retryTransport{
+		Transport:     tokenServer(&valid, &received),
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		auth: newAuthRefresher("Authorization", func(ctx context.Context) (string, error) {
+			return "", refreshErr
+		}),
+	}
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	_, err := retryRT.RoundTrip(req)
+	if !errors.Is(err, ErrAuthRefresh) || !errors.Is(err, refreshErr) {
+		t.Errorf("Expected the refresh error, got %v", err)
+	}
+	if len(received) != 1 {
+		t.Errorf("Expected a single send without retries, got %d", len(received))
+	}
+}
+
+func TestRetryTransport_UnauthorizedWithoutAuthRefresh(t *testing.T) {
+	var valid atomic.Value
+	valid.Store("Bearer valid")
+	var received []string
+
+	retryRT := &retryTransport{
+		Transport:     tokenServer(&valid, &received),
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+	}
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected the 401 response, got %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized || len(received) != 1 {
+		t.Errorf("Expected a single 401, got %d after %d sends", resp.StatusCode, len(received))
+	}
+}
+
+func TestClientBuilder_WithAuthRefresh(t *testing.T) {
+	rt := NewClientBuilder().Build().Transport.(*retryTransport)
+	if rt.auth != nil {
+		t.Error("Expected no auth refresh by default")
+	}
+
+	refresh := func(ctx context.Context) (string, error) { return "token", nil }
+	rt = NewClientBuilder().WithAuthRefresh(refresh).Build().Transport.(*retryTransport)
+	if rt.auth == nil || rt.auth.header != DefaultAuthHeader {
+		t.Fatalf("Expected auth refresh with the default header, got %+v", rt.auth)
+	}
+
+	rt = NewClientBuilder().WithAuthRefresh(refresh).WithAuthHeader("X-Api-Key").Build().Transport.(*retryTransport)
+	if rt.auth.header != "X-Api-Key" {
+		t.Errorf("Expected the X-Api-Key header, got %q", rt.auth.header)
+	}
+}
+
+func TestAuthRefresher_ConcurrentRenew(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	var refreshes int32
+	auth := newAuthRefresher("Authorization", func(ctx context.Context) (string, error) {
+		if atomic.AddInt32(&refreshes, 1) == 1 {
+			close(started)
+		}
+		<-unblock
+		return "Bearer fresh", nil
+	})
+	auth.credentials = "Bearer stale"
+
+	const waiters = 5
+	results := make(chan string, waiters)
+	for range waiters {
+		go func() {
+			credentials, err := auth.renew(context.Background(), "Bearer stale")
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			results <- credentials
+		}()
+	}
+
+	// Readers keep getting the old credentials during the refresh
+	<-started
+	done := make(chan string)
+	go func() { done <- auth.current() }()
+	select {
+	case got := <-done:
+		if got != "Bearer stale" {
+			t.Errorf("Expected the old credentials during the refresh, got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected current not to wait for the refresh")
+	}
+
+	// A waiter giving up doesn't affect the others
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := auth.renew(ctx, "Bearer stale"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a canceled waiter to give up, got %v", err)
+	}
+
+	close(unblock)
+	for range waiters {
+		if got := <-results; got != "Bearer fresh" {
+			t.Errorf("Expected the shared fresh credentials, got %q", got)
+		}
+	}
+	if got := atomic.LoadInt32(&refreshes); got != 1 {
+		t.Errorf("Expected a single refresh, got %d", got)
+	}
+}
+
+func TestAuthRefresher_SharedRefreshFailure(t *testing.T) {
+	unblock := make(chan struct{})
+	auth := newAuthRefresher("Authorization", func(ctx context.Context) (string, error) {
+		<-unblock
+		return "", errors.New("token endpoint down")
+	})
+
+	errs := make(chan error, 3)
+	for range 3 {
+		go func() {
+			_, err := auth.renew(context.Background(), "")
+			errs <- err
+		}()
+	}
+	close(unblock)
+
+	for range 3 {
+		if err := <-errs; !errors.Is(err, ErrAuthRefresh) {
+			t.Errorf("Expected ErrAuthRefresh, got %v", err)
+		}
+	}
+	// A failed refresh doesn't stick: the next rejection refreshes again
+	if _, err := auth.renew(context.Background(), ""); !errors.Is(err, ErrAuthRefresh) {
+		t.Errorf("Expected ErrAuthRefresh, got %v", err)
+	}
+	if auth.current() != "" {
+		t.Errorf("Expected no credentials after failed refreshes, got %q", auth.current())
+	}
+}
@@ -0,0 +1,125 @@
+package httpretrier
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Backoff computes the delay before each retry, like RetryStrategy, but may
+// keep state between the attempts of a request, such as the previous delay.
+// The retry transport calls Reset at the start of every request.
+type Backoff interface {
+	// Next returns the delay after the given failed attempt, starting at 0
+	Next(attempt int) time.Duration
+
+	// Reset clears the state kept from previous attempts
+	Reset()
+}
+
+// strategyBackoff adapts a stateless RetryStrategy to Backoff
+type strategyBackoff RetryStrategy
+
+func (s strategyBackoff) Next(attempt int) time.Duration { return s(attempt) }
+
+func (strategyBackoff) Reset() {}
+
+// StrategyBackoff returns a Backoff computing its delays with strategy.
+// Its Reset method does nothing, RetryStrategy functions being stateless.
+func StrategyBackoff(strategy RetryStrategy) Backoff {
+	return strategyBackoff(strategy)
+}
+
+// BackoffStrategy returns a RetryStrategy computing its delays with b, for
+// APIs taking a RetryStrategy. The strategy never calls Reset.
+func BackoffStrategy(b Backoff) RetryStrategy {
+	return b.Next
+}
+
+// DecorrelatedJitterBackoff is a Backoff implementing "decorrelated jitter":
+// each delay is a random duration between base and three times the previous
+// delay, capped at maxDelay. Reset, or a call to Next for attempt 0, starts
+// over from base. It is safe for concurrent use, but the attempts of requests
+// sharing an instance mix their state.
+type DecorrelatedJitterBackoff struct {
+	base     time.Duration
+	maxDelay time.Duration
+	int63n   func(n int64) int64
+
+	mu   sync.Mutex
+	prev time.Duration
+}
+
+var _ Backoff = (*DecorrelatedJitterBackoff)(nil)
+
+// NewDecorrelatedJitterBackoff creates a DecorrelatedJitterBackoff
+func NewDecorrelatedJitterBackoff(base, maxDelay time.Duration) *DecorrelatedJitterBackoff {
+	return newDecorrelatedJitterBackoff(base, maxDelay, rand.Int63n)
+}
+
+// newDecorrelatedJitterBackoff is NewDecorrelatedJitterBackoff with the random number generator injected
+func newDecorrelatedJitterBackoff(base, maxDelay time.Duration, int63n func(n int64) int64) *DecorrelatedJitterBackoff {
+	return &DecorrelatedJitterBackoff{
+		base:     base,
+		maxDelay: maxDelay,
+		int63n:   int63n,
+		prev:     base,
+	}
+}
+
+// Next returns the delay after the given failed attempt
+func (b *DecorrelatedJitterBackoff) Next(attempt int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if attempt == 0 {
+		b.prev = b.base
+	}
+
+	// sleep = min(maxDelay, random_between(base, prev*3))
+	upper := b.prev * 3
+	if upper < b.prev { // overflow
+		upper = b.maxDelay
+	}
+
+	delay := b.base
+	if upper > b.base {
+		delay = b.base + time.Duration(b.int63n(int64(upper-b.base)))
+	}
+	if delay > b.maxDelay {
+		delay = b.maxDelay
+	}
+
+	b.prev = delay
+	return delay
+}
+
+// Reset starts the delays over from base
+func (b *DecorrelatedJitterBackoff) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.prev = b.base
+}
+
+// NewClientWithBackoff creates a new http.Client configured with the retry
+// transport, computing its delays with backoff. The backoff is Reset at the
+// start of every request, so a stateful one should not be shared by concurrent requests.
+func NewClientWithBackoff(maxRetries int, backoff Backoff, baseTransport http.RoundTripper) *http.Client {
+	if baseTransport == nil {
+		baseTransport = http.DefaultTransport
+	}
+	if backoff == nil {
+		// Provide a default backoff if none is given
+		backoff = StrategyBackoff(ExponentialBackoff(500*time.Millisecond, 10*time.Second))
+	}
+	return &http.Client{
+		Transport: &retryTransport{
+			Transport:     baseTransport,
+			MaxRetries:    maxRetries,
+			Backoff:       backoff,
+			AttemptHeader: DefaultAttemptHeader,
+		},
+	}
+}
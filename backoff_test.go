@@ -0,0 +1,158 @@
+package httpretrier
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStrategyBackoff(t *testing.T) {
+	backoff := StrategyBackoff(FixedDelay(time.Second))
+	backoff.Reset() // No-op
+
+	for attempt := range 3 {
+		if delay := backoff.Next(attempt); delay != time.Second {
+			t.Errorf("Attempt %d: expected 1s, got %v", attempt, delay)
+		}
+	}
+
+	strategy := BackoffStrategy(backoff)
+	if delay := strategy(0); delay != time.Second {
+		t.Errorf("Expected the adapted strategy to return 1s, got %v", delay)
+	}
+}
+
+func TestDecorrelatedJitterBackoff_Reset(t *testing.T) {
+	base, maxDelay := 100*time.Millisecond, 10*time.Second
+
+	// Always pick the upper bound so the delays are predictable
+	upper := func(n int64) int64 { return n - 1 }
+	backoff := newDecorrelatedJitterBackoff(base, maxDelay, upper)
+
+	first := []time.Duration{backoff.Next(1), backoff.Next(2), backoff.Next(3)}
+	if !(first[0] < first[1] && first[1] < first[2]) {
+		t.Fatalf("Expected growing delays, got %v", first)
+	}
+
+	// After Reset the sequence starts over from base, even without attempt 0
+	backoff.Reset()
+	for i, want := range first {
+		if got := backoff.Next(i + 1); got != want {
+			t.Errorf("Attempt %d after Reset: expected %v, got %v", i+1, want, got)
+		}
+	}
+
+	for range 20 {
+		if delay := backoff.Next(5); delay < base || delay > maxDelay {
+			t.Fatalf("Expected delay within [%v, %v], got %v", base, maxDelay, delay)
+		}
+	}
+}
+
+// recordingBackoff is a Backoff recording the calls made to it
+type recordingBackoff struct {
+	resets   int
+	attempts []int
+}
+
+func (b *recordingBackoff) Next(attempt int) time.Duration {
+	b.attempts = append(b.attempts, attempt)
+	return time.Millisecond
+}
+
+func (b *recordingBackoff) Reset() {
+	b.resets++
+	b.attempts = nil
+}
+
+func TestRetryTransport_BackoffResetPerRequest(t *testing.T) {
+	backoff := &recordingBackoff{}
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("connection reset")
+		},
+	}
+
+	client := NewClientWithBackoff(2, backoff, mockRT)
+
+	for i := range 2 {
+		req := httptest.NewRequest("GET", "http://example.com", nil)
+		req.RequestURI = ""
+		if _, err := client.Do(req); err == nil {
+			t.Fatal("Expected an error after all retries failed")
+		}
+
+		if backoff.resets != i+1 {
+			t.Errorf("Expected %d resets, got %d", i+1, backoff.resets)
+		}
+		if len(backoff.attempts) != 2 || backoff.attempts[0] != 0 || backoff.attempts[1] != 1 {
+			t.Errorf("Expected delays for attempts [0 1], got %v", backoff.attempts)
+		}
+	}
+}
+
+func TestRetryTransport_BackoffForAllocs(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	tests := []struct {
+		name      string
+		transport *retryTransport
+	}{
+		{name: "Default", transport: &retryTransport{}},
+		{name: "Strategy", transport: &retryTransport{RetryStrategy: FixedDelay(time.Second)}},
+		{name: "Built Client", transport: NewClientBuilder().WithRetryStrategy(JitterBackoffStrategy).Build().Transport.(*retryTransport)},
+	}
+
+	// Stateless strategies are reused as is by every request
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allocs := testing.AllocsPerRun(100, func() { tt.transport.backoffFor(req) }); allocs != 0 {
+				t.Errorf("Expected no allocation, got %v", allocs)
+			}
+		})
+	}
+}
+
+// BenchmarkRetryTransport_RoundTrip measures the allocations of a request
+// retried once, with the default strategy, a stateless one and a stateful one
+func BenchmarkRetryTransport_RoundTrip(b *testing.B) {
+	tests := []struct {
+		name      string
+		transport *retryTransport
+	}{
+		{name: "Default", transport: &retryTransport{MaxRetries: 1}},
+		{name: "Exponential", transport: NewClientBuilder().WithRetryStrategy(ExponentialBackoffStrategy).Build().Transport.(*retryTransport)},
+		{name: "Decorrelated", transport: NewClientBuilder().WithRetryStrategy(DecorrelatedJitterStrategy).Build().Transport.(*retryTransport)},
+	}
+
+	for _, tt := range tests {
+		var attempts int
+		rt := tt.transport
+		rt.Transport = &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				status := http.StatusServiceUnavailable
+				if attempts%2 == 0 {
+					status = http.StatusOK
+				}
+				return &http.Response{StatusCode: status, Body: http.NoBody, Header: make(http.Header)}, nil
+			},
+		}
+		rt.Clock = &fakeClock{now: time.Now()}
+		rt.Logger = slog.New(slog.DiscardHandler)
+		req := httptest.NewRequest("GET", "http://example.com", nil)
+
+		b.Run(tt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				resp, err := rt.RoundTrip(req)
+				if err != nil {
+					b.Fatal(err)
+				}
+				resp.Body.Close()
+			}
+		})
+	}
+}
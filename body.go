@@ -0,0 +1,305 @@
+package httpretrier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// BodyFactory returns a fresh request body for an attempt, along with its
+// length in bytes, or -1 if the length is unknown.
+// It lets bodies backed by files or generators be replayed without
+// buffering them in memory.
+type BodyFactory func() (io.ReadCloser, int64, error)
+
+// bodyFactoryKey is the context key for a request's BodyFactory
+type bodyFactoryKey struct{}
+
+// WithBodyFactory returns a copy of ctx carrying factory.
+// Requests sent with the returned context get their body from factory
+// on every attempt, including the first one. The factory takes precedence
+// over the request's Body and GetBody.
+func WithBodyFactory(ctx context.Context, factory BodyFactory) context.Context {
+	return context.WithValue(ctx, bodyFactoryKey{}, factory)
+}
+
+// bodyFactoryFromContext returns the BodyFactory carried by ctx, if any
+func bodyFactoryFromContext(ctx context.Context) BodyFactory {
+	factory, _ := ctx.Value(bodyFactoryKey{}).(BodyFactory)
+	return factory
+}
+
+// cancelOnCloseBody releases a per-attempt context once the response body
+// is closed, so the deadline keeps applying while the body is read
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the underlying body and cancels the attempt's context
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// requestBody provides the body for each attempt of a request without
+// modifying the caller's request
+type requestBody struct {
+	factory BodyFactory
+	getBody func() (io.ReadCloser, error)
+
+	// body is sent as is when it can't be replayed
+	body io.ReadCloser
+
+	// truncated reports that the body could not be buffered, because it
+	// exceeded the buffer limit or the buffer budget, so it can only be sent once
+	truncated bool
+
+	// shared reports that the attempts read the same body, rewound before
+	// each of them, so no two of them may be in flight at once
+	shared bool
+
+	// release returns the bytes reserved from the buffer budget, if any
+	release func()
+}
+
+// newRequestBody determines how the body of req is provided on each attempt.
+// A body factory from the request context takes precedence over GetBody.
+// Bodies lacking GetBody that implement io.Seeker, such as an *os.File, are
+// rewound before each attempt. When AutoBufferBody is set, the other bodies
+// lacking GetBody are buffered up to MaxBufferSize, within the buffer budget
+// if there is one.
+// The caller must call release once the request is over.
+func (r *retryTransport) newRequestBody(req *http.Request) (*requestBody, error) {
+	if factory := bodyFactoryFromContext(req.Context()); factory != nil {
+		return &requestBody{factory: factory, release: func() {}}, nil
+	}
+
+	b := &requestBody{getBody: req.GetBody, body: req.Body, release: func() {}}
+	if b.getBody != nil || b.body == nil || b.body == http.NoBody {
+		return b, nil
+	}
+
+	if b.rewind() || !r.AutoBufferBody {
+		return b, nil
+	}
+
+	maxSize := r.MaxBufferSize
+	var reserved int64
+	if r.bufferBudget != nil {
+		// Reserve the most the body may take up, i.e. its announced length
+		// or the buffer limit, and give back the unused part afterward
+		reserved = maxSize
+		if reserved <= 0 || (req.ContentLength > 0 && req.ContentLength < reserved) {
+			reserved = req.ContentLength
+		}
+		if reserved <= 0 {
+			reserved = r.bufferBudget.limit
+		}
+
+		ok, err := r.bufferBudget.reserve(req.Context(), reserved)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			b.truncated = true
+			return b, nil
+		}
+		maxSize = reserved
+	}
+
+	body, getBody, size, err := bufferBody(b.body, maxSize)
+	if err != nil {
+		r.releaseBuffer(reserved)
+		return nil, err
+	}
+	b.body, b.getBody = body, getBody
+	b.truncated = getBody == nil
+
+	if b.truncated {
+		r.releaseBuffer(reserved)
+	} else {
+		r.releaseBuffer(reserved - size)
+		b.release = func() { r.releaseBuffer(size) }
+	}
+
+	return b, nil
+}
+
+// rewind makes a body implementing io.Seeker replayable by seeking back to
+// its current offset before each attempt, without buffering it. It reports
+// false if the body can't seek, e.g. an *os.File reading from a pipe.
+// The transport closes the body of each attempt, so attempts get a view of
+// the body that can't close it, and the body itself is closed on release.
+// Bodies implementing io.ReaderAt give each attempt its own reader of the
+// remaining bytes, the others share theirs and can't be hedged.
+func (b *requestBody) rewind() bool {
+	seeker, ok := b.body.(io.ReadSeeker)
+	if !ok {
+		return false
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false
+	}
+
+	body := b.body
+	b.release = func() { body.Close() }
+
+	// Bodies that can be read at an offset, such as an *os.File, give each
+	// attempt its own reader, so concurrent hedged copies don't interfere
+	if readerAt, ok := b.body.(io.ReaderAt); ok {
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err == nil {
+			_, err = seeker.Seek(start, io.SeekStart)
+		}
+		if err == nil {
+			b.getBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(io.NewSectionReader(readerAt, start, end-start)), nil
+			}
+			return true
+		}
+	}
+
+	b.shared = true
+	b.getBody = func() (io.ReadCloser, error) {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		return io.NopCloser(seeker), nil
+	}
+
+	return true
+}
+
+// releaseBuffer returns n bytes to the transport's buffer budget, if any
+func (r *retryTransport) releaseBuffer(n int64) {
+	if r.bufferBudget != nil {
+		r.bufferBudget.release(n)
+	}
+}
+
+// apply sets the body of an attempt's request, a clone of the original one
+func (b *requestBody) apply(req *http.Request) error {
+	switch {
+	case b.factory != nil:
+		body, length, err := b.factory()
+		if err != nil {
+			return fmt.Errorf("failed to get request body from factory: %w", err)
+		}
+		req.Body = body
+		req.ContentLength = length
+		// Keep the transport's own replays consistent with the factory
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, _, err := b.factory()
+			return body, err
+		}
+	case b.getBody != nil && b.body != nil:
+		body, err := b.getBody()
+		if err != nil {
+			return fmt.Errorf("failed to get request body for retry: %w", err)
+		}
+		req.Body = body
+		req.GetBody = b.getBody
+	default:
+		req.Body = b.body
+	}
+
+	return nil
+}
+
+// bufferBody reads body into memory and returns a replayable copy of it
+// along with a function producing further copies and the number of bytes
+// buffered. A maxSize of 0 or less
+// means no limit. If the body is larger than maxSize, the bytes read so far
+// are put back in front of the unread remainder and no replay function is
+// returned, so the body is sent once as is but cannot be rewound.
+func bufferBody(body io.ReadCloser, maxSize int64) (io.ReadCloser, func() (io.ReadCloser, error), int64, error) {
+	reader := io.Reader(body)
+	if maxSize > 0 {
+		reader = io.LimitReader(body, maxSize+1)
+	}
+
+	buf, err := io.ReadAll(reader)
+	if err != nil {
+		body.Close()
+		return nil, nil, 0, fmt.Errorf("failed to buffer request body: %w", err)
+	}
+
+	if maxSize > 0 && int64(len(buf)) > maxSize {
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), body), body}, nil, 0, nil
+	}
+
+	if err := body.Close(); err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to close request body: %w", err)
+	}
+
+	getBody := func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
+	replay, _ := getBody()
+
+	return replay, getBody, int64(len(buf)), nil
+}
+
+// bufferPool reuses byte buffers of a fixed size
+type bufferPool struct {
+	pool sync.Pool
+}
+
+// defaultDrainBuffers is the pool of drain buffers of the transports not
+// built with one
+var defaultDrainBuffers = newBufferPool(DefaultDrainBufferSize)
+
+// newBufferPool creates a pool of buffers of the given size
+func newBufferPool(size int) *bufferPool {
+	return &bufferPool{pool: sync.Pool{New: func() any {
+		// A pointer avoids allocating when the buffer is put back
+		buf := make([]byte, size)
+		return &buf
+	}}}
+}
+
+// get returns a buffer from the pool, to be put back once done with it
+func (p *bufferPool) get() *[]byte {
+	return p.pool.Get().(*[]byte)
+}
+
+// put puts buf back in the pool
+func (p *bufferPool) put(buf *[]byte) {
+	p.pool.Put(buf)
+}
+
+// discard is io.Discard without its ReadFrom method, which io.CopyBuffer would
+// use instead of the given buffer
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// drainBody discards up to maxSize bytes of a response body, so the connection
+// can be reused, then closes it. Bodies longer than maxSize are simply closed.
+// Draining stops as soon as ctx is done, in which case the partial drain is not
+// reported as an error. The body is read with a buffer from buffers.
+func drainBody(ctx context.Context, body io.ReadCloser, maxSize int64, buffers *bufferPool) (drainErr, closeErr error) {
+	// Closing the body unblocks a read stuck on a slow or hung connection
+	stop := context.AfterFunc(ctx, func() { body.Close() })
+
+	buf := buffers.get()
+	_, drainErr = io.CopyBuffer(discard{}, io.LimitReader(body, maxSize), *buf)
+	buffers.put(buf)
+	if !stop() {
+		drainErr = nil
+	}
+
+	return drainErr, body.Close()
+}
@@ -0,0 +1,382 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport_BodyFactory(t *testing.T) {
+	const payload = "streamed body"
+	var factoryCalls, attempts int
+	var readers []io.ReadCloser
+
+	factory := func() (io.ReadCloser, int64, error) {
+		factoryCalls++
+		body := io.NopCloser(strings.NewReader(payload))
+		readers = append(readers, body)
+		return body, int64(len(payload)), nil
+	}
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if req.Body != readers[len(readers)-1] {
+				t.Errorf("Attempt %d did not get a fresh body from the factory", attempts)
+			}
+			if req.ContentLength != int64(len(payload)) {
+				t.Errorf("Expected content length %d, got %d", len(payload), req.ContentLength)
+			}
+
+			got, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Fatalf("Failed to read body: %v", err)
+			}
+			if string(got) != payload {
+				t.Errorf("Attempt %d: expected body %q, got %q", attempts, payload, got)
+			}
+
+			status := http.StatusServiceUnavailable
+			if attempts == 3 {
+				status = http.StatusOK
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+	}
+
+	ctx := WithBodyFactory(context.Background(), factory)
+	req := httptest.NewRequest("POST", "http://example.com", nil).WithContext(ctx)
+
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+	if factoryCalls != attempts {
+		t.Errorf("Expected factory to be called once per attempt (%d), got %d", attempts, factoryCalls)
+	}
+}
+
+func TestRetryTransport_BodyFactoryError(t *testing.T) {
+	factoryErr := errors.New("cannot open body")
+	factory := func() (io.ReadCloser, int64, error) {
+		return nil, 0, factoryErr
+	}
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			t.Error("Expected no request to be sent when the factory fails")
+			return nil, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    2,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+	}
+
+	ctx := WithBodyFactory(context.Background(), factory)
+	req := httptest.NewRequest("POST", "http://example.com", nil).WithContext(ctx)
+
+	_, err := retryRT.RoundTrip(req)
+	if !errors.Is(err, factoryErr) {
+		t.Errorf("Expected factory error, got %v", err)
+	}
+}
+
+func TestRetryTransport_AutoBufferBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxBufferSize int64
+		expectBodies  []string
+	}{
+		{
+			name:          "body within limit is replayed",
+			maxBufferSize: 1024,
+			expectBodies:  []string{"payload", "payload"},
+		},
+		{
+			name:          "no limit",
+			maxBufferSize: 0,
+			expectBodies:  []string{"payload", "payload"},
+		},
+		{
+			name:          "body over limit is sent once",
+			maxBufferSize: 3,
+			expectBodies:  []string{"payload"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []string
+			mockRT := &mockRoundTripper{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					got, err := io.ReadAll(req.Body)
+					if err != nil {
+						t.Fatalf("Failed to read body: %v", err)
+					}
+					bodies = append(bodies, string(got))
+					return nil, errors.New("simulated transport error")
+				},
+			}
+
+			retryRT := &retryTransport{
+				Transport:      mockRT,
+				MaxRetries:     1,
+				RetryStrategy:  FixedDelay(1 * time.Millisecond),
+				AutoBufferBody: true,
+				MaxBufferSize:  tt.maxBufferSize,
+			}
+
+			// Hide the concrete reader type so http.NewRequest can't set GetBody
+			body := struct{ io.Reader }{strings.NewReader("payload")}
+			req, err := http.NewRequest("POST", "http://example.com", body)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			if req.GetBody != nil {
+				t.Fatal("Expected request without GetBody")
+			}
+
+			_, _ = retryRT.RoundTrip(req)
+
+			if len(bodies) != len(tt.expectBodies) {
+				t.Fatalf("Expected %d attempts, got %d", len(tt.expectBodies), len(bodies))
+			}
+			for i, expected := range tt.expectBodies {
+				if bodies[i] != expected {
+					t.Errorf("Attempt %d: expected body %q, got %q", i+1, expected, bodies[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransport_AutoBufferBodyDisabled(t *testing.T) {
+	var bodies []string
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			got, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(got))
+			return nil, errors.New("simulated transport error")
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+	}
+
+	body := struct{ io.Reader }{strings.NewReader("payload")}
+	req, _ := http.NewRequest("POST", "http://example.com", body)
+	_, _ = retryRT.RoundTrip(req)
+
+	if len(bodies) != 2 || bodies[0] != "payload" || bodies[1] != "" {
+		t.Errorf("Expected the body not to be rewound without buffering, got %q", bodies)
+	}
+}
+
+func TestRetryTransport_SeekableBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upload")
+	if err := os.WriteFile(path, []byte("header:payload"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+
+	// The body starts where the file was left, not at its beginning
+	if _, err := file.Seek(int64(len("header:")), io.SeekStart); err != nil {
+		t.Fatalf("Failed to seek file: %v", err)
+	}
+
+	var bodies []string
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			got, _ := io.ReadAll(req.Body)
+			req.Body.Close() // Like http.Transport, close the body of each attempt
+			bodies = append(bodies, string(got))
+			return nil, errors.New("simulated transport error")
+		},
+	}
+
+	// Seeking doesn't need auto-buffering
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    2,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+	}
+
+	req, _ := http.NewRequest("POST", "http://example.com", file)
+	if req.GetBody != nil {
+		t.Fatal("Expected request without GetBody")
+	}
+	_, _ = retryRT.RoundTrip(req)
+
+	if len(bodies) != 3 || bodies[0] != "payload" || bodies[1] != "payload" || bodies[2] != "payload" {
+		t.Errorf("Expected the body to be rewound for every attempt, got %q", bodies)
+	}
+
+	// The file is closed once the request is over
+	if _, err := file.Read(make([]byte, 1)); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected the file to be closed, got %v", err)
+	}
+}
+
+func TestRetryTransport_UnseekableBodyIsBuffered(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	go func() {
+		pw.WriteString("payload")
+		pw.Close()
+	}()
+
+	var bodies []string
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			got, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(got))
+			return nil, errors.New("simulated transport error")
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:      mockRT,
+		MaxRetries:     1,
+		RetryStrategy:  FixedDelay(1 * time.Millisecond),
+		AutoBufferBody: true,
+	}
+
+	// A pipe is an *os.File that can't seek, so it falls back to buffering
+	req, _ := http.NewRequest("POST", "http://example.com", pr)
+	_, _ = retryRT.RoundTrip(req)
+
+	if len(bodies) != 2 || bodies[0] != "payload" || bodies[1] != "payload" {
+		t.Errorf("Expected the buffered body to be replayed, got %q", bodies)
+	}
+}
+
+func TestRetryTransport_OnBufferTruncated(t *testing.T) {
+	var attempts int
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			got, _ := io.ReadAll(req.Body)
+			if string(got) != "oversized payload" {
+				t.Errorf("Expected the full body to be sent, got %q", got)
+			}
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	var hookCalls int
+	var hookSize int64
+	retryRT := &retryTransport{
+		Transport:      mockRT,
+		MaxRetries:     3,
+		RetryStrategy:  FixedDelay(1 * time.Millisecond),
+		AutoBufferBody: true,
+		MaxBufferSize:  4,
+		OnBufferTruncated: func(size int64) {
+			hookCalls++
+			hookSize = size
+		},
+	}
+
+	body := struct{ io.Reader }{strings.NewReader("oversized payload")}
+	req, _ := http.NewRequest("POST", "http://example.com", body)
+	req.ContentLength = int64(len("oversized payload"))
+
+	_, err := retryRT.RoundTrip(req)
+	if err == nil {
+		t.Error("Expected the failed attempt to be reported")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected a single attempt, got %d", attempts)
+	}
+	if hookCalls != 1 {
+		t.Errorf("Expected the hook to fire once, got %d", hookCalls)
+	}
+	if hookSize != int64(len("oversized payload")) {
+		t.Errorf("Expected the hook to receive the body size, got %d", hookSize)
+	}
+}
+
+func TestDrainBody(t *testing.T) {
+	buffers := newBufferPool(4)
+	body := &slowReaderCloser{content: []byte(strings.Repeat("x", 100)), closed: make(chan struct{})}
+
+	drainErr, closeErr := drainBody(context.Background(), body, 10, buffers)
+	if drainErr != nil || closeErr != nil {
+		t.Fatalf("Expected no error, got %v, %v", drainErr, closeErr)
+	}
+	if body.read != 10 {
+		t.Errorf("Expected 10 bytes drained with a smaller buffer, got %d", body.read)
+	}
+	select {
+	case <-body.closed:
+	default:
+		t.Error("Expected the body to be closed")
+	}
+}
+
+// BenchmarkDrainBody compares the allocations of draining a failed response's
+// body with a pooled buffer to those of io.Copy, which drainBody used before
+func BenchmarkDrainBody(b *testing.B) {
+	content := strings.Repeat("x", 2<<10)
+	ctx := context.Background()
+	// A reader without WriteTo, like a connection's
+	newBody := func() io.ReadCloser {
+		return io.NopCloser(struct{ io.Reader }{strings.NewReader(content)})
+	}
+
+	b.Run("Pooled", func(b *testing.B) {
+		buffers := newBufferPool(DefaultDrainBufferSize)
+		b.ReportAllocs()
+		for b.Loop() {
+			drainBody(ctx, newBody(), DefaultMaxDrainSize, buffers)
+		}
+	})
+
+	b.Run("Copy", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			body := newBody()
+			stop := context.AfterFunc(ctx, func() { body.Close() })
+			io.Copy(io.Discard, io.LimitReader(body, DefaultMaxDrainSize))
+			stop()
+			body.Close()
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package httpretrier
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// bufferBudget caps the total size of request bodies buffered at once across
+// all in-flight requests of a client
+type bufferBudget struct {
+	limit int64
+
+	// wait makes reservations block until enough bytes are released,
+	// instead of failing right away
+	wait bool
+
+	used atomic.Int64
+
+	mu sync.Mutex
+	// released is closed and replaced whenever bytes are released
+	released chan struct{}
+}
+
+// newBufferBudget creates a bufferBudget allowing limit bytes to be buffered at once
+func newBufferBudget(limit int64, wait bool) *bufferBudget {
+	return &bufferBudget{
+		limit:    limit,
+		wait:     wait,
+		released: make(chan struct{}),
+	}
+}
+
+// reserve claims n bytes of the budget and reports whether it succeeded.
+// When the budget is exhausted, it waits for releases if the budget is set
+// to wait, until ctx is done, or fails right away otherwise. A reservation
+// larger than the whole budget always fails.
+func (b *bufferBudget) reserve(ctx context.Context, n int64) (bool, error) {
+	if n > b.limit {
+		return false, nil
+	}
+
+	for {
+		// Grab the channel before checking usage, so a release in between
+		// isn't missed
+		b.mu.Lock()
+		released := b.released
+		b.mu.Unlock()
+
+		used := b.used.Load()
+		if used+n <= b.limit {
+			if b.used.CompareAndSwap(used, used+n) {
+				return true, nil
+			}
+			continue
+		}
+
+		if !b.wait {
+			return false, nil
+		}
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+// release returns n bytes to the budget, waking up waiting reservations
+func (b *bufferBudget) release(n int64) {
+	if n <= 0 {
+		return
+	}
+
+	b.used.Add(-n)
+
+	b.mu.Lock()
+	close(b.released)
+	b.released = make(chan struct{})
+	b.mu.Unlock()
+}
+
+// BufferedBytes returns the number of request body bytes currently buffered
+// for retries by a client built with WithMaxTotalBufferBytes, its transport
+// possibly wrapped, see StopSignalHandling.
+// It returns 0 for clients without a buffer budget.
+func BufferedBytes(client *http.Client) int64 {
+	if client == nil {
+		return 0
+	}
+
+	rt, ok := retryTransportOf(client.Transport)
+	if !ok || rt.bufferBudget == nil {
+		return 0
+	}
+
+	return rt.bufferBudget.used.Load()
+}
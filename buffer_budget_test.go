@@ -0,0 +1,180 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// opaqueBody hides the concrete reader type so http.NewRequest can't set GetBody
+func opaqueBody(s string) io.Reader {
+	return struct{ io.Reader }{strings.NewReader(s)}
+}
+
+func TestRetryTransport_MaxTotalBufferBytes(t *testing.T) {
+	const (
+		limit    = 64
+		bodySize = 16
+	)
+	payload := strings.Repeat("x", bodySize)
+	budget := newBufferBudget(limit, true)
+
+	var maxUsed, attempts int64
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			used := budget.used.Load()
+			for {
+				observed := atomic.LoadInt64(&maxUsed)
+				if used <= observed || atomic.CompareAndSwapInt64(&maxUsed, observed, used) {
+					break
+				}
+			}
+
+			got, _ := io.ReadAll(req.Body)
+			if string(got) != payload {
+				t.Errorf("Expected the full body on every attempt, got %q", got)
+			}
+			time.Sleep(time.Millisecond)
+
+			// Fail the first attempt of every request so bodies are replayed
+			if atomic.AddInt64(&attempts, 1)%2 == 1 {
+				return nil, errors.New("simulated transport error")
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:      mockRT,
+		MaxRetries:     3,
+		RetryStrategy:  FixedDelay(1 * time.Millisecond),
+		AutoBufferBody: true,
+		MaxBufferSize:  1024,
+		bufferBudget:   budget,
+	}
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("POST", "http://example.com", opaqueBody(payload))
+			req.ContentLength = bodySize
+			resp, err := retryRT.RoundTrip(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt64(&maxUsed); got > limit {
+		t.Errorf("Expected at most %d buffered bytes, observed %d", limit, got)
+	}
+	if got := budget.used.Load(); got != 0 {
+		t.Errorf("Expected the budget to be fully released, %d bytes still reserved", got)
+	}
+}
+
+func TestRetryTransport_MaxTotalBufferBytesSkip(t *testing.T) {
+	budget := newBufferBudget(8, false)
+	if ok, _ := budget.reserve(context.Background(), 8); !ok {
+		t.Fatal("Expected to reserve the whole budget")
+	}
+
+	var attempts int
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			return nil, errors.New("simulated transport error")
+		},
+	}
+
+	var truncated bool
+	retryRT := &retryTransport{
+		Transport:         mockRT,
+		MaxRetries:        3,
+		RetryStrategy:     FixedDelay(1 * time.Millisecond),
+		AutoBufferBody:    true,
+		MaxBufferSize:     1024,
+		OnBufferTruncated: func(size int64) { truncated = true },
+		bufferBudget:      budget,
+	}
+
+	req, _ := http.NewRequest("POST", "http://example.com", opaqueBody("payload"))
+	_, _ = retryRT.RoundTrip(req)
+
+	// Without room in the budget the body isn't buffered, so it's sent once
+	if attempts != 1 {
+		t.Errorf("Expected a single attempt, got %d", attempts)
+	}
+	if !truncated {
+		t.Error("Expected the truncation hook to fire")
+	}
+}
+
+func TestRetryTransport_MaxTotalBufferBytesWaitContext(t *testing.T) {
+	budget := newBufferBudget(8, true)
+	if ok, _ := budget.reserve(context.Background(), 8); !ok {
+		t.Fatal("Expected to reserve the whole budget")
+	}
+
+	retryRT := &retryTransport{
+		Transport: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				t.Error("Expected no attempt while waiting for the budget")
+				return nil, nil
+			},
+		},
+		MaxRetries:     1,
+		RetryStrategy:  FixedDelay(1 * time.Millisecond),
+		AutoBufferBody: true,
+		MaxBufferSize:  4,
+		bufferBudget:   budget,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "POST", "http://example.com", opaqueBody("data"))
+
+	_, err := retryRT.RoundTrip(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to end with the request context, got %v", err)
+	}
+
+	// Releasing the budget lets waiting requests proceed
+	budget.release(8)
+	if ok, _ := budget.reserve(context.Background(), 4); !ok {
+		t.Error("Expected to reserve after release")
+	}
+}
+
+func TestClientBuilder_WithMaxTotalBufferBytes(t *testing.T) {
+	httpClient := NewClientBuilder().WithMaxTotalBufferBytes(1 << 20).WithWaitForBufferBudget(true).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	if rt.bufferBudget == nil || rt.bufferBudget.limit != 1<<20 || !rt.bufferBudget.wait {
+		t.Errorf("Expected a waiting 1 MiB buffer budget, got %+v", rt.bufferBudget)
+	}
+	if BufferedBytes(httpClient) != 0 {
+		t.Errorf("Expected no buffered bytes, got %d", BufferedBytes(httpClient))
+	}
+
+	httpClient = NewClientBuilder().WithMaxTotalBufferBytes(-1).Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	if rt.bufferBudget != nil {
+		t.Error("Expected no buffer budget for an invalid value")
+	}
+	if BufferedBytes(http.DefaultClient) != 0 {
+		t.Error("Expected no buffered bytes for a non-retry client")
+	}
+}
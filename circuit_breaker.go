@@ -0,0 +1,154 @@
+package httpretrier
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when a request is not sent because the circuit
+// breaker of its host is open after too many consecutive failures
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// circuitMaxHosts bounds the number of hosts whose breaker state is remembered
+const circuitMaxHosts = 1024
+
+// circuitState is the state of a host's circuit breaker
+type circuitState int
+
+const (
+	circuitClosed   circuitState = iota // Requests flow, failures are counted
+	circuitOpen                         // Requests fail fast until the open duration is over
+	circuitHalfOpen                     // A single probe is let through to test the host
+)
+
+// hostCircuit is the circuit breaker state of a single host
+type hostCircuit struct {
+	state    circuitState
+	failures int       // Consecutive failures while closed
+	openedAt time.Time // When the breaker last opened
+	probing  bool      // Whether the half-open probe is in flight
+}
+
+// circuitBreaker tracks consecutive failures per host and stops sending
+// requests to a host once failureThreshold is reached, for openDuration.
+// After that, a single probe is let through: if it succeeds the breaker
+// closes, otherwise it opens again. Only hosts with failures are tracked: a
+// host is forgotten once its breaker closes on a success, and at most
+// circuitMaxHosts hosts are tracked.
+type circuitBreaker struct {
+	failureThreshold int
+	openDuration     time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostCircuit
+}
+
+// newCircuitBreaker creates a circuitBreaker with all hosts closed
+func newCircuitBreaker(failureThreshold int, openDuration time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		hosts:            make(map[string]*hostCircuit),
+	}
+}
+
+// allow reports whether an attempt may be sent to the host at time now,
+// returning ErrCircuitOpen if not. Once the open duration is over, the first
+// caller becomes the probe, as reported by probe, and the others keep failing
+// until it is recorded.
+func (cb *circuitBreaker) allow(host string, now time.Time) (probe bool, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.hosts[host]
+	if !ok {
+		return false, nil
+	}
+
+	switch c.state {
+	case circuitOpen:
+		if now.Sub(c.openedAt) < cb.openDuration {
+			return false, ErrCircuitOpen
+		}
+		c.state = circuitHalfOpen
+		c.probing = true
+		return true, nil
+	case circuitHalfOpen:
+		if c.probing {
+			return false, ErrCircuitOpen
+		}
+		c.probing = true
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// record updates the host's breaker with the outcome of an attempt sent at
+// time now, probe telling whether the attempt was the half-open probe. While
+// the breaker isn't closed, only the probe's outcome counts: the others come
+// from attempts sent before it opened and say nothing about the host's
+// recovery.
+func (cb *circuitBreaker) record(host string, probe, failed bool, now time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.hosts[host]
+	if !ok {
+		// Healthy hosts aren't tracked
+		if !failed {
+			return
+		}
+
+		// Make room by forgetting a closed host, or any host if none is
+		if len(cb.hosts) >= circuitMaxHosts {
+			evict := ""
+			for other, oc := range cb.hosts {
+				evict = other
+				if oc.state == circuitClosed {
+					break
+				}
+			}
+			delete(cb.hosts, evict)
+		}
+		c = &hostCircuit{}
+		cb.hosts[host] = c
+	}
+
+	if c.state != circuitClosed && !probe {
+		return
+	}
+
+	if !failed {
+		delete(cb.hosts, host)
+		return
+	}
+
+	switch c.state {
+	case circuitHalfOpen:
+		// The probe failed, the host still isn't healthy
+		c.state = circuitOpen
+		c.openedAt = now
+		c.probing = false
+	case circuitClosed:
+		c.failures++
+		if c.failures >= cb.failureThreshold {
+			c.state = circuitOpen
+			c.openedAt = now
+			c.failures = 0
+		}
+	}
+}
+
+// abandon releases an attempt whose outcome says nothing about the host's
+// health, such as one canceled by the caller. If it was the probe, another
+// probe is let through.
+func (cb *circuitBreaker) abandon(host string, probe bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if c, ok := cb.hosts[host]; ok && probe && c.state == circuitHalfOpen {
+		c.probing = false
+	}
+}
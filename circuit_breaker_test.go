@@ -0,0 +1,329 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_States(t *testing.T) {
+	now := time.Now()
+	cb := newCircuitBreaker(2, time.Minute)
+
+	// Failures below the threshold keep the breaker closed
+	cb.record("a", false, true, now)
+	if _, err := cb.allow("a", now); err != nil {
+		t.Fatalf("Expected closed breaker, got %v", err)
+	}
+
+	// A success resets the consecutive failures
+	cb.record("a", false, false, now)
+	cb.record("a", false, true, now)
+	if _, err := cb.allow("a", now); err != nil {
+		t.Fatalf("Expected closed breaker after a success, got %v", err)
+	}
+
+	cb.record("a", false, true, now)
+	if _, err := cb.allow("a", now); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected open breaker, got %v", err)
+	}
+
+	// Other hosts are not affected
+	if _, err := cb.allow("b", now); err != nil {
+		t.Errorf("Expected closed breaker for another host, got %v", err)
+	}
+
+	// Once the open duration is over a single probe goes through
+	now = now.Add(time.Minute)
+	if _, err := cb.allow("a", now); err != nil {
+		t.Fatalf("Expected a probe to be allowed, got %v", err)
+	}
+	if _, err := cb.allow("a", now); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected other requests to fail during the probe, got %v", err)
+	}
+
+	// A failed probe opens the breaker again
+	cb.record("a", true, true, now)
+	if _, err := cb.allow("a", now.Add(time.Second)); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected open breaker after a failed probe, got %v", err)
+	}
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	if _, err := cb.allow("a", now); err != nil {
+		t.Fatalf("Expected a probe to be allowed, got %v", err)
+	}
+	cb.record("a", true, false, now)
+	for range 3 {
+		if _, err := cb.allow("a", now); err != nil {
+			t.Fatalf("Expected closed breaker after a successful probe, got %v", err)
+		}
+	}
+}
+
+func TestCircuitBreaker_AbandonedProbe(t *testing.T) {
+	now := time.Now()
+	cb := newCircuitBreaker(1, time.Second)
+	cb.record("a", false, true, now)
+
+	now = now.Add(time.Second)
+	if _, err := cb.allow("a", now); err != nil {
+		t.Fatalf("Expected a probe to be allowed, got %v", err)
+	}
+
+	// A canceled probe lets the next request probe instead
+	cb.abandon("a", true)
+	if _, err := cb.allow("a", now); err != nil {
+		t.Errorf("Expected a new probe after an abandoned one, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_LateOutcomesWhileOpen(t *testing.T) {
+	now := time.Now()
+	cb := newCircuitBreaker(1, time.Minute)
+	cb.record("a", false, true, now)
+
+	// A success from an attempt sent before the breaker opened doesn't close it
+	cb.record("a", false, false, now)
+	if _, err := cb.allow("a", now); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the breaker to stay open, got %v", err)
+	}
+
+	// Nor does it during the probe, whose outcome alone counts
+	now = now.Add(time.Minute)
+	probe, err := cb.allow("a", now)
+	if err != nil || !probe {
+		t.Fatalf("Expected a probe to be allowed, got probe %v and %v", probe, err)
+	}
+	cb.record("a", false, false, now)
+	cb.abandon("a", false)
+	if _, err := cb.allow("a", now); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected other requests to fail during the probe, got %v", err)
+	}
+
+	cb.record("a", true, false, now)
+	if _, err := cb.allow("a", now); err != nil {
+		t.Errorf("Expected closed breaker after a successful probe, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_EvictsHosts(t *testing.T) {
+	now := time.Now()
+	cb := newCircuitBreaker(2, time.Minute)
+
+	// Healthy hosts aren't tracked
+	for _, host := range []string{"a", "b", "c"} {
+		if _, err := cb.allow(host, now); err != nil {
+			t.Fatalf("Expected closed breaker for %s, got %v", host, err)
+		}
+		cb.record(host, false, false, now)
+	}
+	if n := len(cb.hosts); n != 0 {
+		t.Errorf("Expected no hosts tracked, got %d", n)
+	}
+
+	// A host is forgotten once a success closes its breaker
+	cb.record("a", false, true, now)
+	cb.record("a", false, false, now)
+	if n := len(cb.hosts); n != 0 {
+		t.Errorf("Expected the recovered host to be forgotten, got %d hosts", n)
+	}
+
+	// The number of hosts tracked is bounded, closed hosts going first
+	cb.record("open", false, true, now)
+	cb.record("open", false, true, now)
+	for i := range circuitMaxHosts + 10 {
+		cb.record(fmt.Sprintf("host-%d", i), false, true, now)
+	}
+	if n := len(cb.hosts); n > circuitMaxHosts {
+		t.Errorf("Expected at most %d hosts tracked, got %d", circuitMaxHosts, n)
+	}
+	if _, err := cb.allow("open", now); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected the open host to be kept, got %v", err)
+	}
+}
+
+func TestRetryTransport_CircuitBreaker(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	var attempts int
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       io.NopCloser(strings.NewReader("Unavailable")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:      mockRT,
+		MaxRetries:     5,
+		RetryStrategy:  FixedDelay(time.Second),
+		Clock:          clock,
+		circuitBreaker: newCircuitBreaker(3, time.Minute),
+	}
+
+	// The breaker opens after the third failed attempt, stopping the retries
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	_, err := retryRT.RoundTrip(req)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts before the breaker opened, got %d", attempts)
+	}
+
+	// Further requests fail fast without reaching the host
+	_, err = retryRT.RoundTrip(httptest.NewRequest("GET", "http://example.com/other", nil))
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected no attempt while the breaker is open, got %d", attempts)
+	}
+}
+
+func TestRetryTransport_CircuitBreakerIgnoresCanceledRequests(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return nil, context.Canceled
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:      mockRT,
+		MaxRetries:     1,
+		RetryStrategy:  FixedDelay(time.Millisecond),
+		circuitBreaker: newCircuitBreaker(1, time.Minute),
+	}
+
+	for range 3 {
+		_, err := retryRT.RoundTrip(httptest.NewRequest("GET", "http://example.com", nil))
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+	}
+}
+
+func TestClientBuilder_WithCircuitBreaker(t *testing.T) {
+	rt, _ := NewClientBuilder().WithCircuitBreaker(5, time.Second).Build().Transport.(*retryTransport)
+	if rt.circuitBreaker == nil {
+		t.Fatal("Expected a circuit breaker to be installed")
+	}
+
+	for _, tt := range []struct {
+		threshold    int
+		openDuration time.Duration
+	}{
+		{threshold: 0, openDuration: time.Second},
+		{threshold: 5, openDuration: 0},
+	} {
+		builder := NewClientBuilder().WithCircuitBreaker(tt.threshold, tt.openDuration)
+		rt, _ = builder.Build().Transport.(*retryTransport)
+		if rt.circuitBreaker != nil {
+			t.Errorf("Expected no circuit breaker for %+v", tt)
+		}
+		if len(builder.Warnings()) != 1 {
+			t.Errorf("Expected 1 warning for %+v, got %v", tt, builder.Warnings())
+		}
+	}
+}
+
+func TestRetryTransport_CircuitBreakerLimiterTimeoutDuringProbe(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	var attempts int
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: make(http.Header)}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:      mockRT,
+		MaxRetries:     1,
+		RetryStrategy:  FixedDelay(time.Millisecond),
+		Clock:          clock,
+		circuitBreaker: newCircuitBreaker(1, time.Minute),
+		hostLimiter:    newHostLimiter(1),
+	}
+
+	// Open the breaker, then let its open duration pass so the next attempt is the probe
+	retryRT.circuitBreaker.record("example.com", false, true, clock.Now())
+	clock.Sleep(context.Background(), time.Minute)
+
+	// The probe times out waiting for the host's only slot
+	if err := retryRT.hostLimiter.acquire(context.Background(), "example.com"); err != nil {
+		t.Fatalf("Expected to acquire the slot, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := retryRT.RoundTrip(httptest.NewRequest("GET", "http://example.com", nil).WithContext(ctx))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the limiter wait to time out, got %v", err)
+	}
+	retryRT.hostLimiter.release("example.com")
+
+	// The abandoned probe doesn't wedge the breaker: the next request probes the host
+	resp, err := retryRT.RoundTrip(httptest.NewRequest("GET", "http://example.com", nil))
+	if err != nil {
+		t.Fatalf("Expected the next request to probe the host, got %v", err)
+	}
+	resp.Body.Close()
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestRetryTransport_CircuitBreakerOpenClosesNoBody(t *testing.T) {
+	var opened, closed int
+	factory := func() (io.ReadCloser, int64, error) {
+		opened++
+		return &closeRecorder{Reader: strings.NewReader("payload"), closed: &closed}, 7, nil
+	}
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			t.Fatal("Expected no attempt while the breaker is open")
+			return nil, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:      mockRT,
+		MaxRetries:     1,
+		RetryStrategy:  FixedDelay(time.Millisecond),
+		circuitBreaker: newCircuitBreaker(1, time.Minute),
+	}
+	retryRT.circuitBreaker.record("example.com", false, true, time.Now())
+
+	ctx := WithBodyFactory(context.Background(), factory)
+	req := httptest.NewRequest("POST", "http://example.com", nil).WithContext(ctx)
+	if _, err := retryRT.RoundTrip(req); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+
+	if opened != closed {
+		t.Errorf("Expected every body opened to be closed, opened %d and closed %d", opened, closed)
+	}
+}
+
+// closeRecorder counts the times it is closed
+type closeRecorder struct {
+	io.Reader
+	closed *int
+}
+
+func (c *closeRecorder) Close() error {
+	*c.closed++
+	return nil
+}
@@ -0,0 +1,39 @@
+package httpretrier
+
+import (
+	"context"
+	"time"
+)
+
+// Clock provides the current time and sleeping for the retry transport.
+// Replacing it lets tests drive backoff delays deterministically, without
+// real sleeps. See httpretriertest.ManualClock for an implementation.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// Sleep pauses for the given duration or until ctx is done, whichever
+	// comes first. It returns ctx.Err() if ctx ended the sleep.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// realClock is the Clock backed by the time package
+type realClock struct{}
+
+// Now returns the current time
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Sleep pauses for d or until ctx is done
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,44 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRealClock_Sleep(t *testing.T) {
+	clock := realClock{}
+
+	start := clock.Now()
+	if err := clock.Sleep(context.Background(), 10*time.Millisecond); err != nil {
+		t.Errorf("Expected sleep to complete, got %v", err)
+	}
+	if elapsed := clock.Now().Sub(start); elapsed < 10*time.Millisecond {
+		t.Errorf("Expected to sleep at least 10ms, slept %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := clock.Sleep(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestClientBuilder_WithClock(t *testing.T) {
+	clock := realClock{}
+	httpClient := NewClientBuilder().WithClock(clock).Build()
+
+	rt, ok := httpClient.Transport.(*retryTransport)
+	if !ok {
+		t.Fatalf("Client transport is not of type *retryTransport, got %T", httpClient.Transport)
+	}
+	if rt.Clock != clock {
+		t.Errorf("Expected the configured clock, got %v", rt.Clock)
+	}
+
+	// Without a clock the transport falls back to the real one
+	if _, ok := (&retryTransport{}).clock().(realClock); !ok {
+		t.Error("Expected the real clock by default")
+	}
+}
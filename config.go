@@ -0,0 +1,391 @@
+package httpretrier
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryConfig describes the retry behavior of a client
+type RetryConfig struct {
+	MaxRetries int
+	Strategy   Strategy // Empty when the client was created with a custom RetryStrategy
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration // NoMaxDelay (0) means the delays have no cap
+	Timeout    time.Duration // The http.Client timeout, 0 means no timeout
+}
+
+// String returns a one-line description of the configuration, e.g.
+// "retries=3 strategy=exponential base=500ms max=10s timeout=5s"
+func (c RetryConfig) String() string {
+	strategy := c.Strategy.String()
+	if strategy == "" {
+		strategy = "custom"
+	}
+
+	return fmt.Sprintf("retries=%d strategy=%s base=%s max=%s timeout=%s",
+		c.MaxRetries, strategy, c.BaseDelay, c.MaxDelay, c.Timeout)
+}
+
+// DescribeClient returns the retry configuration of a client created by this
+// package, formatted with RetryConfig.String.
+// It returns an empty string if the client doesn't use the retry transport.
+func DescribeClient(client *http.Client) string {
+	if client == nil {
+		return ""
+	}
+
+	rt, ok := client.Transport.(*retryTransport)
+	if !ok {
+		return ""
+	}
+
+	config := rt.config
+	config.MaxRetries = rt.MaxRetries
+	config.Timeout = client.Timeout
+
+	return config.String()
+}
+
+// String returns a one-line description of the client's settings, starting
+// like RetryConfig.String, e.g.
+// "retries=3 strategy=exponential base=500ms max=10s timeout=5s min=0s ..."
+// The keys are always listed in the same order, so the line can be grep'd
+func (c *Client) String() string {
+	strategy := c.retryStrategyType.String()
+	if strategy == "" {
+		strategy = "custom"
+	}
+
+	fields := []string{
+		fmt.Sprintf("retries=%d", c.maxRetries),
+		"strategy=" + strategy,
+		fmt.Sprintf("base=%s", c.retryBaseDelay),
+		fmt.Sprintf("max=%s", c.retryMaxDelay),
+		fmt.Sprintf("timeout=%s", c.timeout),
+		fmt.Sprintf("min=%s", c.minDelay),
+		fmt.Sprintf("multiplier=%g", c.retryMultiplier),
+		fmt.Sprintf("fixedJitter=%g", c.fixedJitter),
+		fmt.Sprintf("maxElapsed=%s", c.maxElapsedTime),
+		fmt.Sprintf("perAttemptTimeout=%s", c.perAttemptTimeout),
+		fmt.Sprintf("minAttemptBudget=%s", c.minAttemptBudget),
+		fmt.Sprintf("rateLimit=%g", c.rateLimit),
+		fmt.Sprintf("rateLimitBurst=%d", c.rateLimitBurst),
+		fmt.Sprintf("maxConcurrent=%d", c.maxConcurrent),
+		fmt.Sprintf("maxConcurrentPerHost=%d", c.maxConcurrentPerHost),
+		fmt.Sprintf("breakerThreshold=%d", c.breakerThreshold),
+		fmt.Sprintf("breakerOpen=%s", c.breakerOpenDuration),
+		fmt.Sprintf("maxIdleConns=%d", c.maxIdleConns),
+		fmt.Sprintf("maxIdleConnsPerHost=%d", c.maxIdleConnsPerHost),
+		fmt.Sprintf("maxConnsPerHost=%d", c.maxConnsPerHost),
+		fmt.Sprintf("writeBufferSize=%d", c.writeBufferSize),
+		fmt.Sprintf("readBufferSize=%d", c.readBufferSize),
+		fmt.Sprintf("maxResponseHeaderBytes=%d", c.maxResponseHeaderBytes),
+		fmt.Sprintf("idleConnTimeout=%s", c.idleConnTimeout),
+		fmt.Sprintf("tlsHandshakeTimeout=%s", c.tlsHandshakeTimeout),
+		fmt.Sprintf("expectContinueTimeout=%s", c.expectContinueTimeout),
+		fmt.Sprintf("responseHeaderTimeout=%s", c.responseHeaderTimeout),
+		fmt.Sprintf("dialTimeout=%s", c.dialTimeout),
+		fmt.Sprintf("connMaxLifetime=%s", c.connMaxLifetime),
+		"minTLSVersion=" + tls.VersionName(c.minTLSVersion),
+		fmt.Sprintf("disableKeepAlives=%t", c.disableKeepAlives),
+		fmt.Sprintf("maxBufferSize=%d", c.maxBufferSize),
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// Duration is a time.Duration read from and written to configuration files
+// as a string such as "500ms" or "1m30s"
+type Duration time.Duration
+
+// MarshalText formats the duration like time.Duration.String
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText parses the duration with time.ParseDuration
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", text, err)
+	}
+	*d = Duration(duration)
+
+	return nil
+}
+
+// TLSVersion is a TLS version, such as tls.VersionTLS12, read from and written
+// to configuration files as a string such as "1.2". Other values are written
+// in hexadecimal, e.g. "0x0200", and read back as is, for validation to
+// report them.
+type TLSVersion uint16
+
+// tlsVersions are the names of the TLS versions
+var tlsVersions = map[TLSVersion]string{
+	tls.VersionTLS10: "1.0",
+	tls.VersionTLS11: "1.1",
+	tls.VersionTLS12: "1.2",
+	tls.VersionTLS13: "1.3",
+}
+
+// MarshalText formats the version as its name, e.g. "1.2"
+func (v TLSVersion) MarshalText() ([]byte, error) {
+	if name, ok := tlsVersions[v]; ok {
+		return []byte(name), nil
+	}
+
+	return fmt.Appendf(nil, "%#04x", uint16(v)), nil
+}
+
+// UnmarshalText parses a version name, e.g. "1.2", or a hexadecimal value
+func (v *TLSVersion) UnmarshalText(text []byte) error {
+	for version, name := range tlsVersions {
+		if string(text) == name {
+			*v = version
+			return nil
+		}
+	}
+
+	if hex, ok := strings.CutPrefix(string(text), "0x"); ok {
+		if version, err := strconv.ParseUint(hex, 16, 16); err == nil {
+			*v = TLSVersion(version)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid TLS version %q, expected one of 1.0, 1.1, 1.2 or 1.3", text)
+}
+
+// Config holds the settings of a client in a form that can be loaded from
+// JSON or YAML configuration files, e.g.
+//
+//	{"maxRetries": 5, "strategy": "jitter", "baseDelay": "500ms", "timeout": "10s"}
+//
+// Each field mirrors the ClientBuilder method of the same name. Fields left
+// to their zero value keep the builder's default, which is why the settings
+// whose zero value is meaningful are pointers. Settings that take code, such
+// as callbacks, middlewares, a logger or a TLS configuration, can only be set
+// with the builder.
+type Config struct {
+	// Transport
+	MaxIdleConns           int        `json:"maxIdleConns,omitempty" yaml:"maxIdleConns,omitempty"`
+	MaxIdleConnsPerHost    int        `json:"maxIdleConnsPerHost,omitempty" yaml:"maxIdleConnsPerHost,omitempty"`
+	MaxConnsPerHost        int        `json:"maxConnsPerHost,omitempty" yaml:"maxConnsPerHost,omitempty"`
+	WriteBufferSize        int        `json:"writeBufferSize,omitempty" yaml:"writeBufferSize,omitempty"`
+	ReadBufferSize         int        `json:"readBufferSize,omitempty" yaml:"readBufferSize,omitempty"`
+	MaxResponseHeaderBytes int64      `json:"maxResponseHeaderBytes,omitempty" yaml:"maxResponseHeaderBytes,omitempty"`
+	IdleConnTimeout        Duration   `json:"idleConnTimeout,omitempty" yaml:"idleConnTimeout,omitempty"`
+	TLSHandshakeTimeout    Duration   `json:"tlsHandshakeTimeout,omitempty" yaml:"tlsHandshakeTimeout,omitempty"`
+	ExpectContinueTimeout  Duration   `json:"expectContinueTimeout,omitempty" yaml:"expectContinueTimeout,omitempty"`
+	ResponseHeaderTimeout  Duration   `json:"responseHeaderTimeout,omitempty" yaml:"responseHeaderTimeout,omitempty"`
+	DialTimeout            Duration   `json:"dialTimeout,omitempty" yaml:"dialTimeout,omitempty"`
+	DialKeepAlive          Duration   `json:"dialKeepAlive,omitempty" yaml:"dialKeepAlive,omitempty"`
+	ConnMaxLifetime        Duration   `json:"connMaxLifetime,omitempty" yaml:"connMaxLifetime,omitempty"`
+	ForceAttemptHTTP2      *bool      `json:"forceAttemptHTTP2,omitempty" yaml:"forceAttemptHTTP2,omitempty"`
+	DisableHTTP2           bool       `json:"disableHTTP2,omitempty" yaml:"disableHTTP2,omitempty"`
+	DisableKeepAlives      bool       `json:"disableKeepAlives,omitempty" yaml:"disableKeepAlives,omitempty"`
+	FreshConnOnError       *bool      `json:"freshConnOnError,omitempty" yaml:"freshConnOnError,omitempty"`
+	ProxyURL               string     `json:"proxyURL,omitempty" yaml:"proxyURL,omitempty"`
+	UnixSocket             string     `json:"unixSocket,omitempty" yaml:"unixSocket,omitempty"`
+	ClientCertFile         string     `json:"clientCertFile,omitempty" yaml:"clientCertFile,omitempty"`
+	ClientKeyFile          string     `json:"clientKeyFile,omitempty" yaml:"clientKeyFile,omitempty"`
+	RootCAFile             string     `json:"rootCAFile,omitempty" yaml:"rootCAFile,omitempty"`
+	MinTLSVersion          TLSVersion `json:"minTLSVersion,omitempty" yaml:"minTLSVersion,omitempty"`
+
+	// Client
+	Timeout          Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	DefaultCookieJar bool     `json:"defaultCookieJar,omitempty" yaml:"defaultCookieJar,omitempty"`
+	MaxRedirects     *int     `json:"maxRedirects,omitempty" yaml:"maxRedirects,omitempty"`
+
+	// Retries
+	MaxRetries           *int             `json:"maxRetries,omitempty" yaml:"maxRetries,omitempty"`
+	MaxAttempts          int              `json:"maxAttempts,omitempty" yaml:"maxAttempts,omitempty"`
+	Strategy             Strategy         `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	BaseDelay            Duration         `json:"baseDelay,omitempty" yaml:"baseDelay,omitempty"`
+	MaxDelay             *Duration        `json:"maxDelay,omitempty" yaml:"maxDelay,omitempty"`
+	MinDelay             Duration         `json:"minDelay,omitempty" yaml:"minDelay,omitempty"`
+	Multiplier           float64          `json:"multiplier,omitempty" yaml:"multiplier,omitempty"`
+	FixedJitter          float64          `json:"fixedJitter,omitempty" yaml:"fixedJitter,omitempty"`
+	StrictMaxDelay       bool             `json:"strictMaxDelay,omitempty" yaml:"strictMaxDelay,omitempty"`
+	MaxJitter            Duration         `json:"maxJitter,omitempty" yaml:"maxJitter,omitempty"`
+	RandomizedMaxDelay   Duration         `json:"randomizedMaxDelay,omitempty" yaml:"randomizedMaxDelay,omitempty"`
+	JitterSeed           *int64           `json:"jitterSeed,omitempty" yaml:"jitterSeed,omitempty"`
+	MaxElapsedTime       Duration         `json:"maxElapsedTime,omitempty" yaml:"maxElapsedTime,omitempty"`
+	MinAttemptBudget     Duration         `json:"minAttemptBudget,omitempty" yaml:"minAttemptBudget,omitempty"`
+	PerAttemptTimeout    Duration         `json:"perAttemptTimeout,omitempty" yaml:"perAttemptTimeout,omitempty"`
+	ReturnLastResponse   bool             `json:"returnLastResponse,omitempty" yaml:"returnLastResponse,omitempty"`
+	RetryableStatuses    []StatusRange    `json:"retryableStatuses,omitempty" yaml:"retryableStatuses,omitempty"`
+	NoRetryOnStatus      []int            `json:"noRetryOnStatus,omitempty" yaml:"noRetryOnStatus,omitempty"`
+	StatusDelays         map[int]Duration `json:"statusDelays,omitempty" yaml:"statusDelays,omitempty"`
+	RetryIfMissingHeader string           `json:"retryIfMissingHeader,omitempty" yaml:"retryIfMissingHeader,omitempty"`
+	ExpectedContentType  string           `json:"expectedContentType,omitempty" yaml:"expectedContentType,omitempty"`
+	RespectRetryAfter    bool             `json:"respectRetryAfter,omitempty" yaml:"respectRetryAfter,omitempty"`
+	FallbackHosts        []string         `json:"fallbackHosts,omitempty" yaml:"fallbackHosts,omitempty"`
+	DryRun               bool             `json:"dryRun,omitempty" yaml:"dryRun,omitempty"`
+
+	// Request headers
+	DefaultHeaders       http.Header `json:"defaultHeaders,omitempty" yaml:"defaultHeaders,omitempty"`
+	UserAgent            string      `json:"userAgent,omitempty" yaml:"userAgent,omitempty"`
+	AttemptHeader        string      `json:"attemptHeader,omitempty" yaml:"attemptHeader,omitempty"`
+	IdempotencyKeyHeader string      `json:"idempotencyKeyHeader,omitempty" yaml:"idempotencyKeyHeader,omitempty"`
+
+	// Bodies
+	AutoBufferBody      bool  `json:"autoBufferBody,omitempty" yaml:"autoBufferBody,omitempty"`
+	MaxBufferSize       int64 `json:"maxBufferSize,omitempty" yaml:"maxBufferSize,omitempty"`
+	MaxTotalBufferBytes int64 `json:"maxTotalBufferBytes,omitempty" yaml:"maxTotalBufferBytes,omitempty"`
+	WaitForBufferBudget bool  `json:"waitForBufferBudget,omitempty" yaml:"waitForBufferBudget,omitempty"`
+	MaxDrainSize        int64 `json:"maxDrainSize,omitempty" yaml:"maxDrainSize,omitempty"`
+	DrainBufferSize     int   `json:"drainBufferSize,omitempty" yaml:"drainBufferSize,omitempty"`
+
+	// Load control, the rate limit burst defaults to 1
+	RateLimit                  float64  `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty"`
+	RateLimitBurst             int      `json:"rateLimitBurst,omitempty" yaml:"rateLimitBurst,omitempty"`
+	RetryBudgetRatio           float64  `json:"retryBudgetRatio,omitempty" yaml:"retryBudgetRatio,omitempty"`
+	RetryBudgetMinPerSec       float64  `json:"retryBudgetMinPerSec,omitempty" yaml:"retryBudgetMinPerSec,omitempty"`
+	HedgeDelay                 Duration `json:"hedgeDelay,omitempty" yaml:"hedgeDelay,omitempty"`
+	MaxHedges                  int      `json:"maxHedges,omitempty" yaml:"maxHedges,omitempty"`
+	CircuitBreakerThreshold    int      `json:"circuitBreakerThreshold,omitempty" yaml:"circuitBreakerThreshold,omitempty"`
+	CircuitBreakerOpenDuration Duration `json:"circuitBreakerOpenDuration,omitempty" yaml:"circuitBreakerOpenDuration,omitempty"`
+	AdaptiveBackoff            bool     `json:"adaptiveBackoff,omitempty" yaml:"adaptiveBackoff,omitempty"`
+	MaxConcurrent              int      `json:"maxConcurrent,omitempty" yaml:"maxConcurrent,omitempty"`
+	MaxConcurrentPerHost       int      `json:"maxConcurrentPerHost,omitempty" yaml:"maxConcurrentPerHost,omitempty"`
+}
+
+// NewFromConfig creates a new http.Client with the retry transport from cfg.
+// Unlike Build, it doesn't fall back to default values: like BuildStrict, it
+// returns a *ClientError listing every invalid setting.
+func NewFromConfig(cfg Config) (*http.Client, error) {
+	return cfg.builder().BuildStrict()
+}
+
+// builder returns a ClientBuilder with the settings of c
+func (c Config) builder() *ClientBuilder {
+	b := NewClientBuilder()
+
+	set(c.MaxIdleConns, b.WithMaxIdleConns)
+	set(c.MaxIdleConnsPerHost, b.WithMaxIdleConnsPerHost)
+	set(c.MaxConnsPerHost, b.WithMaxConnsPerHost)
+	set(c.WriteBufferSize, b.WithWriteBufferSize)
+	set(c.ReadBufferSize, b.WithReadBufferSize)
+	set(c.MaxResponseHeaderBytes, b.WithMaxResponseHeaderBytes)
+	set(time.Duration(c.IdleConnTimeout), b.WithIdleConnTimeout)
+	set(time.Duration(c.TLSHandshakeTimeout), b.WithTLSHandshakeTimeout)
+	set(time.Duration(c.ExpectContinueTimeout), b.WithExpectContinueTimeout)
+	set(time.Duration(c.ResponseHeaderTimeout), b.WithResponseHeaderTimeout)
+	set(time.Duration(c.DialTimeout), b.WithDialTimeout)
+	set(time.Duration(c.DialKeepAlive), b.WithDialKeepAlive)
+	set(time.Duration(c.ConnMaxLifetime), b.WithConnMaxLifetime)
+	setPtr(c.ForceAttemptHTTP2, b.WithForceAttemptHTTP2)
+	set(c.DisableHTTP2, b.WithDisableHTTP2)
+	set(c.DisableKeepAlives, b.WithDisableKeepAlives)
+	setPtr(c.FreshConnOnError, b.WithFreshConnOnError)
+	set(c.ProxyURL, b.WithProxyURL)
+	set(c.UnixSocket, b.WithUnixSocket)
+	if c.ClientCertFile != "" || c.ClientKeyFile != "" {
+		b.WithClientCertificate(c.ClientCertFile, c.ClientKeyFile)
+	}
+	set(c.RootCAFile, b.WithRootCAFile)
+	set(uint16(c.MinTLSVersion), b.WithMinTLSVersion)
+
+	set(time.Duration(c.Timeout), b.WithTimeout)
+	if c.DefaultCookieJar {
+		b.WithDefaultCookieJar()
+	}
+	setPtr(c.MaxRedirects, b.WithMaxRedirects)
+
+	setPtr(c.MaxRetries, b.WithMaxRetries)
+	set(c.MaxAttempts, b.WithMaxAttempts)
+	set(c.Strategy, b.WithRetryStrategy)
+	set(time.Duration(c.BaseDelay), b.WithRetryBaseDelay)
+	if c.MaxDelay != nil {
+		b.WithRetryMaxDelay(time.Duration(*c.MaxDelay))
+	}
+	set(time.Duration(c.MinDelay), b.WithMinDelay)
+	set(c.Multiplier, b.WithRetryMultiplier)
+	set(c.FixedJitter, b.WithFixedJitter)
+	set(c.StrictMaxDelay, b.WithStrictMaxDelay)
+	set(time.Duration(c.MaxJitter), b.WithMaxJitter)
+	set(time.Duration(c.RandomizedMaxDelay), b.WithRandomizedMaxDelay)
+	setPtr(c.JitterSeed, b.WithJitterSeed)
+	set(time.Duration(c.MaxElapsedTime), b.WithMaxElapsedTime)
+	set(time.Duration(c.MinAttemptBudget), b.WithMinAttemptBudget)
+	set(time.Duration(c.PerAttemptTimeout), b.WithPerAttemptTimeout)
+	set(c.ReturnLastResponse, b.WithReturnLastResponse)
+	for _, s := range c.RetryableStatuses {
+		b.WithRetryableStatusRange(s.Min, s.Max)
+	}
+	if len(c.NoRetryOnStatus) > 0 {
+		b.WithNoRetryOnStatus(c.NoRetryOnStatus...)
+	}
+	if len(c.StatusDelays) > 0 {
+		delays := make(map[int]time.Duration, len(c.StatusDelays))
+		for status, delay := range c.StatusDelays {
+			delays[status] = time.Duration(delay)
+		}
+		b.WithStatusDelay(delays)
+	}
+	set(c.RetryIfMissingHeader, b.WithRetryIfMissingHeader)
+	set(c.ExpectedContentType, b.WithExpectedContentType)
+	set(c.RespectRetryAfter, b.WithRespectRetryAfter)
+	set(c.DryRun, b.WithDryRun)
+	if len(c.FallbackHosts) > 0 {
+		b.WithFallbackHosts(c.FallbackHosts)
+	}
+
+	if len(c.DefaultHeaders) > 0 {
+		b.WithDefaultHeaders(c.DefaultHeaders)
+	}
+	set(c.UserAgent, b.WithUserAgent)
+	set(c.AttemptHeader, b.WithAttemptHeader)
+	set(c.IdempotencyKeyHeader, b.WithIdempotencyKey)
+
+	set(c.AutoBufferBody, b.WithAutoBufferBody)
+	set(c.MaxBufferSize, b.WithMaxBufferSize)
+	set(c.MaxTotalBufferBytes, b.WithMaxTotalBufferBytes)
+	set(c.WaitForBufferBudget, b.WithWaitForBufferBudget)
+	set(c.MaxDrainSize, b.WithMaxDrainSize)
+	set(c.DrainBufferSize, b.WithDrainBufferSize)
+
+	if c.RateLimit != 0 || c.RateLimitBurst != 0 {
+		burst := c.RateLimitBurst
+		if burst == 0 {
+			burst = 1
+		}
+		b.WithRateLimit(c.RateLimit, burst)
+	}
+	if c.RetryBudgetRatio != 0 || c.RetryBudgetMinPerSec != 0 {
+		b.WithRetryBudget(c.RetryBudgetRatio, c.RetryBudgetMinPerSec)
+	}
+	if c.HedgeDelay != 0 || c.MaxHedges != 0 {
+		b.WithHedging(time.Duration(c.HedgeDelay), c.MaxHedges)
+	}
+	if c.CircuitBreakerThreshold != 0 || c.CircuitBreakerOpenDuration != 0 {
+		b.WithCircuitBreaker(c.CircuitBreakerThreshold, time.Duration(c.CircuitBreakerOpenDuration))
+	}
+	if c.AdaptiveBackoff {
+		b.WithAdaptiveBackoff()
+	}
+	set(c.MaxConcurrent, b.WithMaxConcurrent)
+	set(c.MaxConcurrentPerHost, b.WithMaxConcurrentPerHost)
+
+	return b
+}
+
+// set calls with with value, unless it is the zero value
+func set[T comparable](value T, with func(T) *ClientBuilder) {
+	var zero T
+	if value != zero {
+		with(value)
+	}
+}
+
+// setPtr calls with with the value pointed to, unless the pointer is nil
+func setPtr[T any](value *T, with func(T) *ClientBuilder) {
+	if value != nil {
+		with(*value)
+	}
+}
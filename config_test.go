@@ -0,0 +1,233 @@
+package httpretrier
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryConfig_String(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   RetryConfig
+		expected string
+	}{
+		{
+			name: "Exponential",
+			config: RetryConfig{
+				MaxRetries: 3,
+				Strategy:   ExponentialBackoffStrategy,
+				BaseDelay:  500 * time.Millisecond,
+				MaxDelay:   10 * time.Second,
+				Timeout:    5 * time.Second,
+			},
+			expected: "retries=3 strategy=exponential base=500ms max=10s timeout=5s",
+		},
+		{
+			name: "Fixed",
+			config: RetryConfig{
+				MaxRetries: 1,
+				Strategy:   FixedDelayStrategy,
+				BaseDelay:  2 * time.Second,
+				MaxDelay:   2 * time.Second,
+				Timeout:    1500 * time.Millisecond,
+			},
+			expected: "retries=1 strategy=fixed base=2s max=2s timeout=1.5s",
+		},
+		{
+			name:     "Custom",
+			config:   RetryConfig{MaxRetries: 2},
+			expected: "retries=2 strategy=custom base=0s max=0s timeout=0s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.config.String())
+		})
+	}
+}
+
+func TestDescribeClient(t *testing.T) {
+	httpClient := NewClientBuilder().
+		WithMaxRetries(4).
+		WithRetryStrategy(JitterBackoffStrategy).
+		WithRetryBaseDelay(1 * time.Second).
+		WithRetryMaxDelay(30 * time.Second).
+		WithTimeout(10 * time.Second).
+		Build()
+	assert.Equal(t, "retries=4 strategy=jitter base=1s max=30s timeout=10s", DescribeClient(httpClient))
+
+	// Defaults are reported after validation
+	assert.Equal(t, "retries=3 strategy=exponential base=500ms max=10s timeout=5s", DescribeClient(NewClientBuilder().Build()))
+
+	// Clients created with NewClient use a custom strategy function
+	assert.Equal(t, "retries=2 strategy=custom base=0s max=0s timeout=0s", DescribeClient(NewClient(2, FixedDelay(time.Second), nil)))
+
+	assert.Equal(t, "", DescribeClient(http.DefaultClient))
+	assert.Equal(t, "", DescribeClient(nil))
+}
+
+func TestClientBuilder_String(t *testing.T) {
+	builder := NewClientBuilder().
+		WithMaxRetries(4).
+		WithRetryStrategy(JitterBackoffStrategy).
+		WithRetryBaseDelay(1 * time.Second).
+		WithRetryMaxDelay(30 * time.Second).
+		WithTimeout(10 * time.Second).
+		WithMaxConnsPerHost(50)
+
+	description := builder.String()
+	assert.True(t, strings.HasPrefix(description, "retries=4 strategy=jitter base=1s max=30s timeout=10s min=0s "), description)
+	assert.Contains(t, description, " maxConnsPerHost=50 ")
+	assert.NotContains(t, description, "\n")
+
+	// It describes the client Build creates
+	httpClient := builder.Build()
+	assert.True(t, strings.HasPrefix(description, DescribeClient(httpClient)+" "))
+
+	// Invalid settings are shown with their defaults, leaving the builder unchanged
+	builder = NewClientBuilder().WithMaxRetries(50)
+	assert.True(t, strings.HasPrefix(builder.String(), "retries=3 "), builder.String())
+	assert.Equal(t, 50, builder.MaxRetries())
+	assert.Empty(t, builder.Warnings())
+}
+
+func TestConfig_UnmarshalJSON(t *testing.T) {
+	data := `{
+		"maxRetries": 5,
+		"strategy": "jitter",
+		"baseDelay": "400ms",
+		"maxDelay": "1m30s",
+		"timeout": "10s",
+		"freshConnOnError": false,
+		"maxRedirects": 0,
+		"fallbackHosts": ["backup.example.com"]
+	}`
+
+	var cfg Config
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if assert.NotNil(t, cfg.MaxRetries) {
+		assert.Equal(t, 5, *cfg.MaxRetries)
+	}
+	assert.Equal(t, JitterBackoffStrategy, cfg.Strategy)
+	assert.Equal(t, Duration(400*time.Millisecond), cfg.BaseDelay)
+	if assert.NotNil(t, cfg.MaxDelay) {
+		assert.Equal(t, Duration(90*time.Second), *cfg.MaxDelay)
+	}
+	assert.Equal(t, Duration(10*time.Second), cfg.Timeout)
+	if assert.NotNil(t, cfg.FreshConnOnError) {
+		assert.False(t, *cfg.FreshConnOnError)
+	}
+	if assert.NotNil(t, cfg.MaxRedirects) {
+		assert.Zero(t, *cfg.MaxRedirects)
+	}
+	assert.Equal(t, []string{"backup.example.com"}, cfg.FallbackHosts)
+
+	// Durations round-trip as strings
+	out, err := json.Marshal(Config{BaseDelay: Duration(500 * time.Millisecond)})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"baseDelay": "500ms"}`, string(out))
+
+	err = json.Unmarshal([]byte(`{"timeout": "soon"}`), &cfg)
+	assert.ErrorContains(t, err, `invalid duration "soon"`)
+}
+
+func TestNewFromConfig(t *testing.T) {
+	freshConn, retries := false, 5
+	client, err := NewFromConfig(Config{
+		MaxRetries:       &retries,
+		Strategy:         FixedDelayStrategy,
+		BaseDelay:        Duration(400 * time.Millisecond),
+		Timeout:          Duration(10 * time.Second),
+		FreshConnOnError: &freshConn,
+		RateLimit:        10,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	rt, ok := client.Transport.(*retryTransport)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, 10*time.Second, client.Timeout)
+	assert.Equal(t, 5, rt.MaxRetries)
+	assert.Equal(t, FixedDelayStrategy, rt.config.Strategy)
+	assert.Equal(t, 400*time.Millisecond, rt.RetryStrategy(3))
+	assert.False(t, rt.FreshConnOnError)
+	assert.NotNil(t, rt.rateLimiter)
+
+	// Omitted settings keep their defaults
+	client, err = NewFromConfig(Config{})
+	if assert.NoError(t, err) {
+		rt = client.Transport.(*retryTransport)
+		assert.Equal(t, DefaultTimeout, client.Timeout)
+		assert.Equal(t, DefaultMaxRetries, rt.MaxRetries)
+		assert.Equal(t, DefaultMaxDelay, rt.config.MaxDelay)
+		assert.Equal(t, DefaultFreshConnOnError, rt.FreshConnOnError)
+	}
+}
+
+func TestNewFromConfig_InvalidSettings(t *testing.T) {
+	retries := 50
+	client, err := NewFromConfig(Config{
+		MaxRetries: &retries,
+		Strategy:   "unknown",
+		BaseDelay:  Duration(1 * time.Millisecond),
+	})
+	assert.Nil(t, client)
+
+	var clientErr *ClientError
+	if assert.True(t, errors.As(err, &clientErr)) {
+		assert.Contains(t, clientErr.Message, "max retries")
+		assert.Contains(t, clientErr.Message, "retry strategy")
+		assert.Contains(t, clientErr.Message, "base delay")
+	}
+}
+
+func TestConfig_MinTLSVersion(t *testing.T) {
+	// The version round-trips by name
+	out, err := json.Marshal(Config{MinTLSVersion: tls.VersionTLS13})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"minTLSVersion": "1.3"}`, string(out))
+
+	var cfg Config
+	if assert.NoError(t, json.Unmarshal(out, &cfg)) {
+		assert.Equal(t, TLSVersion(tls.VersionTLS13), cfg.MinTLSVersion)
+	}
+
+	client, err := NewFromConfig(cfg)
+	if assert.NoError(t, err) {
+		transport := client.Transport.(*retryTransport).Transport.(*http.Transport)
+		assert.Equal(t, uint16(tls.VersionTLS13), transport.TLSClientConfig.MinVersion)
+	}
+
+	// Omitted, the builder's default applies
+	client, err = NewFromConfig(Config{})
+	if assert.NoError(t, err) {
+		transport := client.Transport.(*retryTransport).Transport.(*http.Transport)
+		assert.Equal(t, uint16(DefaultMinTLSVersion), transport.TLSClientConfig.MinVersion)
+	}
+
+	// Unknown versions are rejected like with the builder
+	out, err = json.Marshal(Config{MinTLSVersion: 0x0200})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"minTLSVersion": "0x0200"}`, string(out))
+	if assert.NoError(t, json.Unmarshal(out, &cfg)) {
+		_, err = NewFromConfig(cfg)
+		assert.ErrorContains(t, err, "min TLS version")
+	}
+
+	err = json.Unmarshal([]byte(`{"minTLSVersion": "1.4"}`), &cfg)
+	assert.ErrorContains(t, err, `invalid TLS version "1.4"`)
+}
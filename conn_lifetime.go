@@ -0,0 +1,50 @@
+package httpretrier
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// errConnMaxLifetime is returned by the connections that outlived the
+// WithConnMaxLifetime limit. It wraps net.ErrClosed, making it a connection
+// error that is retried on a new connection.
+var errConnMaxLifetime = fmt.Errorf("connection max lifetime reached: %w", net.ErrClosed)
+
+// dialFunc is the signature of http.Transport.DialContext
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// withConnMaxLifetime wraps dial so that its connections close themselves once
+// they are older than maxLifetime, as measured by clock
+func withConnMaxLifetime(dial dialFunc, maxLifetime time.Duration, clock Clock) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return &lifetimeConn{Conn: conn, expiry: clock.Now().Add(maxLifetime), clock: clock}, nil
+	}
+}
+
+// lifetimeConn is a connection that closes itself when written to past its
+// expiry. The lifetime is only checked as a request is written, so a
+// connection is replaced on its first use after expiring rather than right
+// when it expires, and responses being read are never cut short.
+type lifetimeConn struct {
+	net.Conn
+	expiry time.Time
+	clock  Clock
+}
+
+// Write writes to the connection, or closes it and fails with
+// errConnMaxLifetime if it expired
+func (c *lifetimeConn) Write(b []byte) (int, error) {
+	if !c.clock.Now().Before(c.expiry) {
+		c.Conn.Close()
+		return 0, errConnMaxLifetime
+	}
+
+	return c.Conn.Write(b)
+}
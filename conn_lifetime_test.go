@@ -0,0 +1,98 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLifetimeConn(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	client, server := net.Pipe()
+	defer server.Close()
+	go io.Copy(io.Discard, server)
+
+	dial := withConnMaxLifetime(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return client, nil
+	}, time.Minute, clock)
+	conn, err := dial(context.Background(), "tcp", "example.com:80")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := conn.Write([]byte("before")); err != nil {
+		t.Errorf("Expected the write to succeed before the expiry, got %v", err)
+	}
+
+	clock.Sleep(context.Background(), time.Minute)
+	_, err = conn.Write([]byte("after"))
+	if !errors.Is(err, errConnMaxLifetime) {
+		t.Errorf("Expected errConnMaxLifetime after the expiry, got %v", err)
+	}
+	if !isConnectionError(err) {
+		t.Error("Expected the expiry to be a connection error")
+	}
+
+	// The underlying connection is closed
+	if _, err := client.Write([]byte("closed")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Expected the connection to be closed, got %v", err)
+	}
+}
+
+func TestClientBuilder_WithConnMaxLifetime(t *testing.T) {
+	var mu sync.Mutex
+	conns := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		conns[r.RemoteAddr] = true
+		mu.Unlock()
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	clock := &fakeClock{now: time.Now()}
+	httpClient := NewClientBuilder().
+		WithConnMaxLifetime(time.Minute).
+		WithClock(clock).
+		Build()
+
+	send := func() {
+		t.Helper()
+		// A POST isn't replayed by net/http itself, only by the retries
+		resp, err := httpClient.Post(server.URL, "text/plain", strings.NewReader("payload"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	send()
+	send()
+	if len(conns) != 1 {
+		t.Errorf("Expected the connection to be reused before it expires, got %d connections", len(conns))
+	}
+
+	clock.Sleep(context.Background(), time.Minute)
+	send()
+	if len(conns) != 2 {
+		t.Errorf("Expected a new connection once the first one expired, got %d connections", len(conns))
+	}
+
+	// Invalid values fall back to the default
+	builder := NewClientBuilder().WithConnMaxLifetime(-time.Second)
+	builder.Build()
+	if builder.ConnMaxLifetime() != DefaultConnMaxLifetime {
+		t.Errorf("Expected the default max lifetime, got %v", builder.ConnMaxLifetime())
+	}
+	if len(builder.Warnings()) != 1 {
+		t.Errorf("Expected 1 warning, got %v", builder.Warnings())
+	}
+}
@@ -0,0 +1,49 @@
+package httpretrier
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync/atomic"
+)
+
+// serverClosedIdleMessage is the message of the error net/http returns when
+// the server closed a keep-alive connection just as it was reused
+const serverClosedIdleMessage = "http: server closed idle connection"
+
+// connTrace records, through httptrace, how an attempt used its connection
+type connTrace struct {
+	reused atomic.Bool // The connection came from the idle pool
+	wrote  atomic.Bool // The whole request was written to it
+}
+
+// traceConn returns req with a context recording into the returned connTrace.
+// Traces already in the request's context keep being called.
+func traceConn(req *http.Request) (*http.Request, *connTrace) {
+	ct := &connTrace{}
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			ct.reused.Store(info.Reused)
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				ct.wrote.Store(true)
+			}
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), ct
+}
+
+// idleConnRace reports whether err means the server closed a reused
+// keep-alive connection before the request was written to it. The request
+// never reached the server, so retrying it is safe whatever its method.
+// Errors after the request was written don't count: the server may have
+// processed it.
+func (ct *connTrace) idleConnRace(err error) bool {
+	if err == nil || isContextError(err) || !ct.reused.Load() || ct.wrote.Load() {
+		return false
+	}
+
+	return strings.Contains(err.Error(), serverClosedIdleMessage) || isConnectionError(err)
+}
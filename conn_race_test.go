@@ -0,0 +1,119 @@
+package httpretrier
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+	"time"
+)
+
+// idleCloseRoundTripper simulates a server closing its keep-alive connections
+// while idle: the first attempt gets a reused connection that fails, either
+// before or after the request is written to it, and the next ones succeed
+type idleCloseRoundTripper struct {
+	idleConnsRoundTripper
+	attempts     int
+	afterWritten bool
+	err          error
+}
+
+func (rt *idleCloseRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.attempts++
+	if rt.attempts > 1 {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("OK")),
+			Header:     make(http.Header),
+		}, nil
+	}
+
+	trace := httptrace.ContextClientTrace(req.Context())
+	trace.GotConn(httptrace.GotConnInfo{Reused: true, WasIdle: true})
+	if rt.afterWritten {
+		trace.WroteRequest(httptrace.WroteRequestInfo{})
+	}
+
+	return nil, rt.err
+}
+
+func TestRetryTransport_IdleConnRace(t *testing.T) {
+	tests := []struct {
+		name             string
+		afterWritten     bool
+		err              error
+		expectedAttempts int
+		expectedClosed   int
+	}{
+		{name: "Server Closed Idle", err: errors.New("http: server closed idle connection"), expectedAttempts: 2, expectedClosed: 1},
+		{name: "Unexpected EOF", err: io.ErrUnexpectedEOF, expectedAttempts: 2, expectedClosed: 1},
+		{name: "After Request Written", afterWritten: true, err: io.ErrUnexpectedEOF, expectedAttempts: 1},
+		{name: "Other Error", err: errors.New("boom"), expectedAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRT := &idleCloseRoundTripper{afterWritten: tt.afterWritten, err: tt.err}
+
+			// The retry condition refuses every retry, as for a non-idempotent request
+			retryRT := &retryTransport{
+				Transport:      mockRT,
+				MaxRetries:     2,
+				RetryStrategy:  FixedDelay(1 * time.Millisecond),
+				RetryCondition: func(resp *http.Response, err error) bool { return false },
+			}
+
+			req := httptest.NewRequest("POST", "http://example.com", strings.NewReader("payload"))
+			resp, err := retryRT.RoundTrip(req)
+			if tt.expectedAttempts > 1 {
+				if err != nil {
+					t.Fatalf("Expected the race to be retried, got %v", err)
+				}
+				resp.Body.Close()
+			} else if err == nil {
+				t.Fatal("Expected the error to be returned without retrying")
+			}
+
+			if mockRT.attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, mockRT.attempts)
+			}
+			if mockRT.closed != tt.expectedClosed {
+				t.Errorf("Expected idle connections closed %d times, got %d", tt.expectedClosed, mockRT.closed)
+			}
+		})
+	}
+}
+
+func TestConnTrace_IdleConnRace(t *testing.T) {
+	req, ct := traceConn(httptest.NewRequest("GET", "http://example.com", nil))
+	trace := httptrace.ContextClientTrace(req.Context())
+	closedIdle := errors.New("http: server closed idle connection")
+
+	// A new connection failing isn't a race
+	trace.GotConn(httptrace.GotConnInfo{})
+	if ct.idleConnRace(closedIdle) {
+		t.Error("Expected no race on a new connection")
+	}
+
+	trace.GotConn(httptrace.GotConnInfo{Reused: true})
+	if !ct.idleConnRace(closedIdle) {
+		t.Error("Expected a race on a reused connection")
+	}
+	if ct.idleConnRace(nil) {
+		t.Error("Expected no race without an error")
+	}
+
+	// A failed write leaves the request unsent
+	trace.WroteRequest(httptrace.WroteRequestInfo{Err: io.ErrUnexpectedEOF})
+	if !ct.idleConnRace(io.ErrUnexpectedEOF) {
+		t.Error("Expected a race when writing the request failed")
+	}
+
+	trace.WroteRequest(httptrace.WroteRequestInfo{})
+	if ct.idleConnRace(closedIdle) {
+		t.Error("Expected no race once the request was written")
+	}
+}
@@ -0,0 +1,22 @@
+package httpretrier
+
+import (
+	"mime"
+	"strings"
+)
+
+// contentTypeMatches reports whether the media type of contentType, a
+// Content-Type header value, is expected. Parameters such as the charset are
+// ignored, and an expected subtype of "*" matches any subtype of the type.
+func contentTypeMatches(contentType, expected string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	if typ, ok := strings.CutSuffix(expected, "/*"); ok {
+		return strings.HasPrefix(mediaType, typ+"/")
+	}
+
+	return mediaType == expected
+}
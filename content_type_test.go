@@ -0,0 +1,108 @@
+package httpretrier
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContentTypeMatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		expected    string
+		matches     bool
+	}{
+		{name: "Exact", contentType: "application/json", expected: "application/json", matches: true},
+		{name: "With Charset", contentType: "application/json; charset=utf-8", expected: "application/json", matches: true},
+		{name: "Case Insensitive", contentType: "Application/JSON", expected: "application/json", matches: true},
+		{name: "Other Type", contentType: "text/html; charset=utf-8", expected: "application/json", matches: false},
+		{name: "Missing", contentType: "", expected: "application/json", matches: false},
+		{name: "Wildcard", contentType: "application/problem+json", expected: "application/*", matches: true},
+		{name: "Wildcard Other Type", contentType: "text/html", expected: "application/*", matches: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := contentTypeMatches(tt.contentType, tt.expected); actual != tt.matches {
+				t.Errorf("Expected %v, got %v", tt.matches, actual)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_ExpectedContentType(t *testing.T) {
+	var attempts int
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			contentType, body := "text/html", "<html>gateway error</html>"
+			if attempts == 2 {
+				contentType, body = "application/json; charset=utf-8", `{"ok":true}`
+			}
+			header := make(http.Header)
+			header.Set("Content-Type", contentType)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     header,
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:           mockRT,
+		MaxRetries:          2,
+		RetryStrategy:       FixedDelay(1 * time.Millisecond),
+		ExpectedContentType: "application/json",
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if attempts != 2 {
+		t.Errorf("Expected the HTML response to be retried, got %d attempts", attempts)
+	}
+
+	// The matching response's body is delivered unread
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || string(data) != `{"ok":true}` {
+		t.Errorf("Expected the JSON body, got %q (%v)", data, err)
+	}
+
+	// Other statuses are handled as usual
+	resp = &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{"Content-Type": {"text/html"}}}
+	if retryRT.shouldRetry(resp, nil) {
+		t.Error("Expected a 404 not to be retried whatever its content type")
+	}
+}
+
+func TestClientBuilder_WithExpectedContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		expected    string
+	}{
+		{name: "Default", contentType: "", expected: ""},
+		{name: "Media Type", contentType: "Application/JSON", expected: "application/json"},
+		{name: "Wildcard", contentType: "application/*", expected: "application/*"},
+		{name: "Parameters", contentType: "application/json; charset=utf-8", expected: ""},
+		{name: "No Subtype", contentType: "json", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := NewClientBuilder().WithExpectedContentType(tt.contentType).Build().Transport.(*retryTransport)
+			if rt.ExpectedContentType != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, rt.ExpectedContentType)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package httpretrier
+
+import (
+	"net/http"
+)
+
+// storeCookies saves the cookies set by the response of a failed attempt.
+// http.Client only sees the response returned by RoundTrip, so without this
+// the cookies of attempts that are retried would be lost.
+func (r *retryTransport) storeCookies(req *http.Request, resp *http.Response) {
+	if r.Jar == nil {
+		return
+	}
+
+	if cookies := resp.Cookies(); len(cookies) > 0 {
+		r.Jar.SetCookies(req.URL, cookies)
+	}
+}
+
+// applyCookies updates the Cookie header of a retry with the cookies in the jar,
+// replacing those with the same name and keeping the ones set by the caller
+func (r *retryTransport) applyCookies(req *http.Request) {
+	if r.Jar == nil {
+		return
+	}
+
+	jarCookies := r.Jar.Cookies(req.URL)
+	if len(jarCookies) == 0 {
+		return
+	}
+
+	fromJar := make(map[string]bool, len(jarCookies))
+	for _, c := range jarCookies {
+		fromJar[c.Name] = true
+	}
+
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	existing := req.Cookies()
+	req.Header.Del("Cookie")
+	for _, c := range existing {
+		if !fromJar[c.Name] {
+			req.AddCookie(c)
+		}
+	}
+	for _, c := range jarCookies {
+		req.AddCookie(c)
+	}
+}
@@ -0,0 +1,98 @@
+package httpretrier
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport_CookiesOfFailedAttempts(t *testing.T) {
+	jar, _ := cookiejar.New(nil)
+	var cookieHeaders []string
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			cookieHeaders = append(cookieHeaders, req.Header.Get("Cookie"))
+
+			header := make(http.Header)
+			status := http.StatusOK
+			if len(cookieHeaders) == 1 {
+				header.Add("Set-Cookie", "session=new")
+				status = http.StatusServiceUnavailable
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader("body")),
+				Header:     header,
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		Jar:           jar,
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "old"})
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if len(cookieHeaders) != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", len(cookieHeaders))
+	}
+	if cookieHeaders[0] != "session=old; theme=dark" {
+		t.Errorf("Expected the caller's cookies on the first attempt, got %q", cookieHeaders[0])
+	}
+	// The cookie set by the failed attempt replaces the stale one
+	if cookieHeaders[1] != "theme=dark; session=new" {
+		t.Errorf("Expected the jar's cookie on the retry, got %q", cookieHeaders[1])
+	}
+	if got := req.Header.Get("Cookie"); got != "session=old; theme=dark" {
+		t.Errorf("Expected the original request to be unchanged, got %q", got)
+	}
+}
+
+func TestRetryTransport_NoJarLeavesCookiesUnchanged(t *testing.T) {
+	var cookieHeaders []string
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			cookieHeaders = append(cookieHeaders, req.Header.Get("Cookie"))
+			header := make(http.Header)
+			header.Add("Set-Cookie", "session=new")
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       io.NopCloser(strings.NewReader("body")),
+				Header:     header,
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "old"})
+	retryRT.RoundTrip(req)
+
+	for i, header := range cookieHeaders {
+		if header != "session=old" {
+			t.Errorf("Attempt %d: expected the caller's cookie only, got %q", i, header)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package httpretrier
+
+import (
+	"net/http"
+	"time"
+)
+
+// RetryEvent describes a failed attempt that is about to be retried
+type RetryEvent struct {
+	Attempt int           // Number of the failed attempt, starting at 1
+	Method  string        // HTTP method of the request
+	URL     string        // URL of the request
+	Host    string        // Host the failed attempt was sent to, including the port if any
+	Status  int           // Status code of the failed attempt, or 0 if no response was received
+	Err     error         // Transport error of the failed attempt, if any
+	Delay   time.Duration // Delay before the next attempt
+}
+
+// newRetryEvent builds the RetryEvent for a failed attempt
+func newRetryEvent(req *http.Request, attempt int, resp *http.Response, err error, delay time.Duration) RetryEvent {
+	event := RetryEvent{
+		Attempt: attempt,
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Host:    req.URL.Host,
+		Err:     err,
+		Delay:   delay,
+	}
+	if resp != nil {
+		event.Status = resp.StatusCode
+	}
+
+	return event
+}
+
+// sendRetryEvent delivers event to ch without blocking.
+// The event is dropped if the channel is full, so a slow consumer never
+// holds up requests.
+func sendRetryEvent(ch chan<- RetryEvent, event RetryEvent) {
+	select {
+	case ch <- event:
+	default:
+	}
+}
+
+// chainCallbacks returns a callback calling each of callbacks in order,
+// or nil if there are none
+func chainCallbacks[T any](callbacks []func(T)) func(T) {
+	switch len(callbacks) {
+	case 0:
+		return nil
+	case 1:
+		return callbacks[0]
+	}
+
+	return func(v T) {
+		for _, callback := range callbacks {
+			callback(v)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package httpretrier
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport_RetryEventChannel(t *testing.T) {
+	var attempts int
+	simulatedError := errors.New("simulated transport error")
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			switch attempts {
+			case 1:
+				return nil, simulatedError
+			case 2:
+				return &http.Response{
+					StatusCode: http.StatusBadGateway,
+					Body:       io.NopCloser(strings.NewReader("Bad Gateway")),
+					Header:     make(http.Header),
+				}, nil
+			default:
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("OK")),
+					Header:     make(http.Header),
+				}, nil
+			}
+		},
+	}
+
+	events := make(chan RetryEvent, 10)
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		RetryEvents:   events,
+	}
+
+	req := httptest.NewRequest("POST", "http://example.com/path", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	close(events)
+
+	var got []RetryEvent
+	for event := range events {
+		got = append(got, event)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 retry events, got %d", len(got))
+	}
+
+	first, second := got[0], got[1]
+	if first.Attempt != 1 || first.Method != "POST" || first.URL != "http://example.com/path" || first.Host != "example.com" {
+		t.Errorf("Unexpected first event: %+v", first)
+	}
+	if first.Status != 0 || !errors.Is(first.Err, simulatedError) {
+		t.Errorf("Expected first event to carry the transport error and no status, got %+v", first)
+	}
+	if first.Delay != 1*time.Millisecond {
+		t.Errorf("Expected first event delay 1ms, got %v", first.Delay)
+	}
+	if second.Attempt != 2 || second.Status != http.StatusBadGateway || second.Err != nil {
+		t.Errorf("Unexpected second event: %+v", second)
+	}
+}
+
+func TestRetryTransport_RetryEventChannelFull(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("simulated transport error")
+		},
+	}
+
+	// Nobody drains the channel: the second and third events must be dropped
+	// instead of blocking the request
+	events := make(chan RetryEvent, 1)
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		RetryEvents:   events,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		req := httptest.NewRequest("GET", "http://example.com", nil)
+		_, err := retryRT.RoundTrip(req)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected an error after all retries failed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RoundTrip blocked on a full event channel")
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 buffered event, got %d", len(events))
+	}
+	if event := <-events; event.Attempt != 1 {
+		t.Errorf("Expected the first retry to be kept, got attempt %d", event.Attempt)
+	}
+}
+
+func TestRetryTransport_OnRetry(t *testing.T) {
+	var attempts int
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			status := http.StatusOK
+			if attempts < 3 {
+				status = http.StatusServiceUnavailable
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader("body")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	var got []RetryEvent
+	events := make(chan RetryEvent, 10)
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		RetryEvents:   events,
+		OnRetry:       func(event RetryEvent) { got = append(got, event) },
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if len(got) != 2 || len(events) != 2 {
+		t.Fatalf("Expected 2 events for the callback and the channel, got %d and %d", len(got), len(events))
+	}
+	for i, event := range got {
+		if event.Attempt != i+1 || event.Status != http.StatusServiceUnavailable {
+			t.Errorf("Unexpected event %d: %+v", i, event)
+		}
+		if sent := <-events; sent != event {
+			t.Errorf("Expected the channel to get the same event %+v, got %+v", event, sent)
+		}
+	}
+}
+
+func TestChainCallbacks(t *testing.T) {
+	if chainCallbacks[int](nil) != nil {
+		t.Error("Expected nil callback without callbacks")
+	}
+
+	var calls []string
+	chained := chainCallbacks([]func(int){
+		func(v int) { calls = append(calls, fmt.Sprintf("a%d", v)) },
+		func(v int) { calls = append(calls, fmt.Sprintf("b%d", v)) },
+	})
+	chained(1)
+
+	if strings.Join(calls, ",") != "a1,b1" {
+		t.Errorf("Expected callbacks called in order, got %v", calls)
+	}
+}
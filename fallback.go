@@ -0,0 +1,79 @@
+package httpretrier
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// FallbackError is returned when the retries failed against the request's
+// host and every fallback host. It unwraps to the error of each host, so
+// errors.Is(err, ErrAllRetriesFailed) still holds.
+type FallbackError struct {
+	// Hosts lists the hosts tried, starting with the request's own
+	Hosts []string
+
+	// Errs holds the error of each host, in the same order
+	Errs []error
+}
+
+func (e *FallbackError) Error() string {
+	failures := make([]string, len(e.Hosts))
+	for i, host := range e.Hosts {
+		failures[i] = fmt.Sprintf("%s: %v", host, e.Errs[i])
+	}
+
+	return "all hosts failed: " + strings.Join(failures, "; ")
+}
+
+// Unwrap returns the error of each host
+func (e *FallbackError) Unwrap() []error {
+	return e.Errs
+}
+
+// retryFallbackHosts retries req against each fallback host in turn, once the
+// retries against its own host are used up, and returns the result of the
+// first host that doesn't exhaust its retries. resp and err are the result of
+// the request's own host.
+func (r *retryTransport) retryFallbackHosts(transport http.RoundTripper, req *http.Request, body *requestBody, maxRetries int, resp *http.Response, err error) (*http.Response, error) {
+	hosts := []string{req.URL.Host}
+	errs := []error{hostError(resp, err)}
+
+	for _, host := range r.FallbackHosts {
+		// Only the last host's response is returned with ReturnLastResponse
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		r.logger().Info("Retries exhausted, trying fallback host", "host", host, "method", req.Method, "url", req.URL.String())
+
+		hostReq := req.Clone(req.Context())
+		hostReq.URL.Host = host
+		hostReq.Host = ""
+
+		var exhausted bool
+		resp, exhausted, err = r.retryHost(transport, hostReq, body, maxRetries)
+		if !exhausted {
+			return resp, err
+		}
+
+		hosts = append(hosts, host)
+		errs = append(errs, hostError(resp, err))
+	}
+
+	if resp != nil {
+		return resp, nil
+	}
+
+	return nil, &FallbackError{Hosts: hosts, Errs: errs}
+}
+
+// hostError returns the error to report for an exhausted host, which has no
+// error of its own when ReturnLastResponse kept its last response
+func hostError(resp *http.Response, err error) error {
+	if err == nil && resp != nil {
+		return fmt.Errorf("%w: last status %d", ErrAllRetriesFailed, resp.StatusCode)
+	}
+
+	return err
+}
@@ -0,0 +1,230 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hostStatusRoundTripper answers each request with the status set for its host
+// and records the hosts and bodies it receives
+type hostStatusRoundTripper struct {
+	statuses map[string]int
+	hosts    []string
+	bodies   []string
+}
+
+func (h *hostStatusRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.hosts = append(h.hosts, req.URL.Host)
+	if req.Body != nil {
+		data, _ := io.ReadAll(req.Body)
+		h.bodies = append(h.bodies, string(data))
+	}
+
+	status, ok := h.statuses[req.URL.Host]
+	if !ok {
+		return nil, errors.New("unknown host")
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Body:       io.NopCloser(strings.NewReader("body")),
+		Header:     make(http.Header),
+	}, nil
+}
+
+func TestRetryTransport_FallbackHostSucceeds(t *testing.T) {
+	rt := &hostStatusRoundTripper{statuses: map[string]int{
+		"primary.example.com": http.StatusServiceUnavailable,
+		"backup1.example.com": http.StatusServiceUnavailable,
+		"backup2.example.com": http.StatusOK,
+	}}
+
+	retryRT := &retryTransport{
+		Transport:     rt,
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		FallbackHosts: []string{"backup1.example.com", "backup2.example.com"},
+	}
+
+	req, err := http.NewRequest("POST", "http://primary.example.com/path?q=1", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected success from a fallback host, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	wantHosts := []string{
+		"primary.example.com", "primary.example.com",
+		"backup1.example.com", "backup1.example.com",
+		"backup2.example.com",
+	}
+	if strings.Join(rt.hosts, ",") != strings.Join(wantHosts, ",") {
+		t.Errorf("Expected hosts %v, got %v", wantHosts, rt.hosts)
+	}
+
+	// The body is replayed to every host
+	for i, body := range rt.bodies {
+		if body != "payload" {
+			t.Errorf("Expected body %q on attempt %d, got %q", "payload", i, body)
+		}
+	}
+	if resp.Request.URL.Path != "/path" || resp.Request.URL.RawQuery != "q=1" {
+		t.Errorf("Expected path and query to be kept, got %s", resp.Request.URL)
+	}
+}
+
+func TestRetryTransport_FallbackHostsAllFail(t *testing.T) {
+	rt := &hostStatusRoundTripper{statuses: map[string]int{
+		"primary.example.com": http.StatusServiceUnavailable,
+		"backup.example.com":  http.StatusBadGateway,
+	}}
+
+	retryRT := &retryTransport{
+		Transport:     rt,
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		FallbackHosts: []string{"backup.example.com"},
+	}
+
+	req := httptest.NewRequest("GET", "http://primary.example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if resp != nil {
+		resp.Body.Close()
+		t.Errorf("Expected nil response, got status %d", resp.StatusCode)
+	}
+
+	var fallbackErr *FallbackError
+	if !errors.As(err, &fallbackErr) {
+		t.Fatalf("Expected *FallbackError, got %T: %v", err, err)
+	}
+	if !errors.Is(err, ErrAllRetriesFailed) {
+		t.Errorf("Expected error to match ErrAllRetriesFailed, got %v", err)
+	}
+
+	wantHosts := []string{"primary.example.com", "backup.example.com"}
+	if strings.Join(fallbackErr.Hosts, ",") != strings.Join(wantHosts, ",") {
+		t.Errorf("Expected hosts %v, got %v", wantHosts, fallbackErr.Hosts)
+	}
+
+	var retryErr *RetryError
+	if !errors.As(fallbackErr.Errs[1], &retryErr) || retryErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected the backup host's RetryError with status 502, got %v", fallbackErr.Errs[1])
+	}
+	if !strings.Contains(err.Error(), "backup.example.com") {
+		t.Errorf("Expected error message to list the backup host, got %q", err.Error())
+	}
+}
+
+func TestRetryTransport_FallbackHostsReturnLastResponse(t *testing.T) {
+	rt := &hostStatusRoundTripper{statuses: map[string]int{
+		"primary.example.com": http.StatusServiceUnavailable,
+		"backup.example.com":  http.StatusBadGateway,
+	}}
+
+	retryRT := &retryTransport{
+		Transport:          rt,
+		MaxRetries:         1,
+		RetryStrategy:      FixedDelay(1 * time.Millisecond),
+		ReturnLastResponse: true,
+		FallbackHosts:      []string{"backup.example.com"},
+	}
+
+	req := httptest.NewRequest("GET", "http://primary.example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected the last response, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected the last host's status 502, got %d", resp.StatusCode)
+	}
+}
+
+func TestRetryTransport_FallbackHostsNotUsed(t *testing.T) {
+	t.Run("permanent error", func(t *testing.T) {
+		rt := &hostStatusRoundTripper{statuses: map[string]int{
+			"primary.example.com": http.StatusNotFound,
+			"backup.example.com":  http.StatusOK,
+		}}
+
+		retryRT := &retryTransport{
+			Transport:     rt,
+			MaxRetries:    2,
+			RetryStrategy: FixedDelay(1 * time.Millisecond),
+			FallbackHosts: []string{"backup.example.com"},
+		}
+
+		req := httptest.NewRequest("GET", "http://primary.example.com", nil)
+		resp, err := retryRT.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Expected the 404 response, got %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusNotFound || len(rt.hosts) != 1 {
+			t.Errorf("Expected a single 404 from the primary host, got %d after %v", resp.StatusCode, rt.hosts)
+		}
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		mockRT := &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				if req.URL.Host != "primary.example.com" {
+					t.Errorf("Unexpected request to %s", req.URL.Host)
+				}
+				cancel()
+				return nil, ctx.Err()
+			},
+		}
+
+		retryRT := &retryTransport{
+			Transport:     mockRT,
+			MaxRetries:    2,
+			RetryStrategy: FixedDelay(1 * time.Millisecond),
+			FallbackHosts: []string{"backup.example.com"},
+		}
+
+		req := httptest.NewRequest("GET", "http://primary.example.com", nil).WithContext(ctx)
+		_, err := retryRT.RoundTrip(req)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestClientBuilder_WithFallbackHosts(t *testing.T) {
+	hosts := []string{"backup.example.com:8443", "", "http://bad.example.com", "bad.example.com/path"}
+	builder := NewClientBuilder().WithFallbackHosts(hosts)
+
+	// The builder keeps its own copy
+	hosts[0] = "changed.example.com"
+
+	client := builder.Build()
+	rt, ok := client.Transport.(*retryTransport)
+	if !ok {
+		t.Fatalf("Client transport is not of type *retryTransport, got %T", client.Transport)
+	}
+
+	if len(rt.FallbackHosts) != 1 || rt.FallbackHosts[0] != "backup.example.com:8443" {
+		t.Errorf("Expected only the valid fallback host, got %v", rt.FallbackHosts)
+	}
+
+	if rt := NewClientBuilder().Build().Transport.(*retryTransport); rt.FallbackHosts != nil {
+		t.Errorf("Expected no fallback hosts by default, got %v", rt.FallbackHosts)
+	}
+}
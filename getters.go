@@ -0,0 +1,150 @@
+package httpretrier
+
+import (
+	"net/http"
+	"time"
+)
+
+// The getters below return the settings the builder holds, as they were set.
+// They are not validated until Build, which replaces the invalid ones with
+// their default values, so a getter called after Build returns the value used.
+
+// Timeout returns the timeout for HTTP requests, see WithTimeout
+func (b *ClientBuilder) Timeout() time.Duration {
+	return b.client.timeout
+}
+
+// MaxRetries returns the maximum number of retries, see WithMaxRetries
+func (b *ClientBuilder) MaxRetries() int {
+	return b.client.maxRetries
+}
+
+// MaxAttempts returns the maximum number of attempts, or 0 if it isn't set, see WithMaxAttempts
+func (b *ClientBuilder) MaxAttempts() int {
+	return b.client.maxAttempts
+}
+
+// RetryStrategyType returns the retry strategy, see WithRetryStrategy
+func (b *ClientBuilder) RetryStrategyType() Strategy {
+	return b.client.retryStrategyType
+}
+
+// BaseDelay returns the base delay of the retry strategy, see WithRetryBaseDelay
+func (b *ClientBuilder) BaseDelay() time.Duration {
+	return b.client.retryBaseDelay
+}
+
+// MaxDelay returns the maximum delay of the retry strategy, see WithRetryMaxDelay
+func (b *ClientBuilder) MaxDelay() time.Duration {
+	return b.client.retryMaxDelay
+}
+
+// MinDelay returns the minimum delay between attempts, see WithMinDelay
+func (b *ClientBuilder) MinDelay() time.Duration {
+	return b.client.minDelay
+}
+
+// RetryMultiplier returns the growth factor of the backoff strategies, see WithRetryMultiplier
+func (b *ClientBuilder) RetryMultiplier() float64 {
+	return b.client.retryMultiplier
+}
+
+// FixedJitter returns the jitter fraction of the fixed delay strategy, see WithFixedJitter
+func (b *ClientBuilder) FixedJitter() float64 {
+	return b.client.fixedJitter
+}
+
+// MaxElapsedTime returns the time budget across all attempts, see WithMaxElapsedTime
+func (b *ClientBuilder) MaxElapsedTime() time.Duration {
+	return b.client.maxElapsedTime
+}
+
+// PerAttemptTimeout returns the timeout of each attempt, see WithPerAttemptTimeout
+func (b *ClientBuilder) PerAttemptTimeout() time.Duration {
+	return b.client.perAttemptTimeout
+}
+
+// MaxIdleConns returns the maximum number of idle connections, see WithMaxIdleConns
+func (b *ClientBuilder) MaxIdleConns() int {
+	return b.client.maxIdleConns
+}
+
+// MaxIdleConnsPerHost returns the maximum number of idle connections per host, see WithMaxIdleConnsPerHost
+func (b *ClientBuilder) MaxIdleConnsPerHost() int {
+	return b.client.maxIdleConnsPerHost
+}
+
+// MaxConnsPerHost returns the maximum number of connections per host, see WithMaxConnsPerHost
+func (b *ClientBuilder) MaxConnsPerHost() int {
+	return b.client.maxConnsPerHost
+}
+
+// WriteBufferSize returns the size of the transport's write buffer, see WithWriteBufferSize
+func (b *ClientBuilder) WriteBufferSize() int {
+	return b.client.writeBufferSize
+}
+
+// ReadBufferSize returns the size of the transport's read buffer, see WithReadBufferSize
+func (b *ClientBuilder) ReadBufferSize() int {
+	return b.client.readBufferSize
+}
+
+// MaxResponseHeaderBytes returns the limit on the size of the response headers, see WithMaxResponseHeaderBytes
+func (b *ClientBuilder) MaxResponseHeaderBytes() int64 {
+	return b.client.maxResponseHeaderBytes
+}
+
+// IdleConnTimeout returns the idle connection timeout, see WithIdleConnTimeout
+func (b *ClientBuilder) IdleConnTimeout() time.Duration {
+	return b.client.idleConnTimeout
+}
+
+// TLSHandshakeTimeout returns the TLS handshake timeout, see WithTLSHandshakeTimeout
+func (b *ClientBuilder) TLSHandshakeTimeout() time.Duration {
+	return b.client.tlsHandshakeTimeout
+}
+
+// ExpectContinueTimeout returns the expect continue timeout, see WithExpectContinueTimeout
+func (b *ClientBuilder) ExpectContinueTimeout() time.Duration {
+	return b.client.expectContinueTimeout
+}
+
+// ResponseHeaderTimeout returns the time to wait for the response headers, see WithResponseHeaderTimeout
+func (b *ClientBuilder) ResponseHeaderTimeout() time.Duration {
+	return b.client.responseHeaderTimeout
+}
+
+// MinTLSVersion returns the minimum TLS version accepted by the transport, see WithMinTLSVersion
+func (b *ClientBuilder) MinTLSVersion() uint16 {
+	return b.client.minTLSVersion
+}
+
+// UnixSocket returns the path of the Unix domain socket dialed for every request, see WithUnixSocket
+func (b *ClientBuilder) UnixSocket() string {
+	return b.client.unixSocket
+}
+
+// ConnMaxLifetime returns the maximum age of a connection, see WithConnMaxLifetime
+func (b *ClientBuilder) ConnMaxLifetime() time.Duration {
+	return b.client.connMaxLifetime
+}
+
+// DialTimeout returns the timeout for establishing connections, see WithDialTimeout
+func (b *ClientBuilder) DialTimeout() time.Duration {
+	return b.client.dialTimeout
+}
+
+// MaxBufferSize returns the largest request body buffered for retries, see WithMaxBufferSize
+func (b *ClientBuilder) MaxBufferSize() int64 {
+	return b.client.maxBufferSize
+}
+
+// UserAgent returns the User-Agent sent with every request, see WithUserAgent
+func (b *ClientBuilder) UserAgent() string {
+	return b.client.userAgent
+}
+
+// DefaultHeaders returns a copy of the headers added to every request, see WithDefaultHeaders
+func (b *ClientBuilder) DefaultHeaders() http.Header {
+	return b.client.defaultHeaders.Clone()
+}
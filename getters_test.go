@@ -0,0 +1,68 @@
+package httpretrier
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClientBuilder_Getters(t *testing.T) {
+	builder := NewClientBuilder().
+		WithTimeout(10 * time.Second).
+		WithMaxRetries(5).
+		WithRetryStrategy(FixedDelayStrategy).
+		WithRetryBaseDelay(2 * time.Second).
+		WithRetryMaxDelay(20 * time.Second).
+		WithUserAgent("my-service/1.0").
+		WithDefaultHeaders(http.Header{"x-api-version": {"2"}})
+
+	if got := builder.Timeout(); got != 10*time.Second {
+		t.Errorf("Expected timeout 10s, got %v", got)
+	}
+	if got := builder.MaxRetries(); got != 5 {
+		t.Errorf("Expected 5 max retries, got %d", got)
+	}
+	if got := builder.RetryStrategyType(); got != FixedDelayStrategy {
+		t.Errorf("Expected strategy %v, got %v", FixedDelayStrategy, got)
+	}
+	if got := builder.BaseDelay(); got != 2*time.Second {
+		t.Errorf("Expected base delay 2s, got %v", got)
+	}
+	if got := builder.MaxDelay(); got != 20*time.Second {
+		t.Errorf("Expected max delay 20s, got %v", got)
+	}
+	if got := builder.UserAgent(); got != "my-service/1.0" {
+		t.Errorf("Expected user agent %q, got %q", "my-service/1.0", got)
+	}
+
+	// The headers are a copy, changing them doesn't affect the builder
+	headers := builder.DefaultHeaders()
+	if got := headers.Get("X-Api-Version"); got != "2" {
+		t.Errorf("Expected default header X-Api-Version 2, got %q", got)
+	}
+	headers.Set("X-Api-Version", "3")
+	if got := builder.DefaultHeaders().Get("X-Api-Version"); got != "2" {
+		t.Errorf("Expected the builder's headers to be unchanged, got %q", got)
+	}
+}
+
+func TestClientBuilder_GettersBeforeAndAfterBuild(t *testing.T) {
+	builder := NewClientBuilder().WithMaxRetries(50).WithTimeout(-1 * time.Second)
+
+	// Invalid values are returned as set until Build validates them
+	if got := builder.MaxRetries(); got != 50 {
+		t.Errorf("Expected the raw max retries 50, got %d", got)
+	}
+	if got := builder.Timeout(); got != -1*time.Second {
+		t.Errorf("Expected the raw timeout -1s, got %v", got)
+	}
+
+	builder.Build()
+
+	if got := builder.MaxRetries(); got != DefaultMaxRetries {
+		t.Errorf("Expected max retries %d after Build, got %d", DefaultMaxRetries, got)
+	}
+	if got := builder.Timeout(); got != DefaultTimeout {
+		t.Errorf("Expected timeout %v after Build, got %v", DefaultTimeout, got)
+	}
+}
@@ -0,0 +1,61 @@
+package httpretrier
+
+import (
+	"net/http"
+	"slices"
+)
+
+// canonicalHeaders returns a copy of headers with canonical keys, so that
+// their values can be looked up like those of a request's header
+func canonicalHeaders(headers http.Header) http.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	canonical := make(http.Header, len(headers))
+	for key, values := range headers {
+		for _, value := range values {
+			canonical.Add(key, value)
+		}
+	}
+
+	return canonical
+}
+
+// applyDefaultHeaders adds the DefaultHeaders to an attempt's request. The
+// values the caller already set come first, and only the default values they
+// don't include are appended after them.
+func (r *retryTransport) applyDefaultHeaders(req *http.Request) {
+	if len(r.DefaultHeaders) == 0 {
+		return
+	}
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	for key, values := range r.DefaultHeaders {
+		set := req.Header[key]
+		for _, value := range values {
+			if !slices.Contains(set, value) {
+				req.Header.Add(key, value)
+			}
+		}
+	}
+}
+
+// applyUserAgent sets the UserAgent on an attempt's request, unless the caller
+// or the DefaultHeaders set one. An empty User-Agent set by the caller, which
+// keeps net/http from sending its own, is kept too.
+func (r *retryTransport) applyUserAgent(req *http.Request) {
+	if r.UserAgent == "" {
+		return
+	}
+	if _, ok := req.Header["User-Agent"]; ok {
+		return
+	}
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	req.Header.Set("User-Agent", r.UserAgent)
+}
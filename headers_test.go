@@ -0,0 +1,147 @@
+package httpretrier
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport_DefaultHeaders(t *testing.T) {
+	var received []http.Header
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			received = append(received, req.Header.Clone())
+			status := http.StatusOK
+			if len(received) == 1 {
+				status = http.StatusServiceUnavailable
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader("body")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    2,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		DefaultHeaders: canonicalHeaders(http.Header{
+			"api-version": {"2"},
+			"Accept":      {"application/json", "text/plain"},
+			"X-Client-Id": {"billing"},
+		}),
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set("Api-Version", "3")
+	req.Header.Add("Accept", "text/plain")
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", len(received))
+	}
+	for i, header := range received {
+		// Every attempt gets the headers once, the caller's values first
+		if got := header.Values("Api-Version"); !slices.Equal(got, []string{"3", "2"}) {
+			t.Errorf("Attempt %d: Expected Api-Version [3 2], got %v", i+1, got)
+		}
+		if got := header.Values("Accept"); !slices.Equal(got, []string{"text/plain", "application/json"}) {
+			t.Errorf("Attempt %d: Expected Accept [text/plain application/json], got %v", i+1, got)
+		}
+		if got := header.Values("X-Client-Id"); !slices.Equal(got, []string{"billing"}) {
+			t.Errorf("Attempt %d: Expected X-Client-Id [billing], got %v", i+1, got)
+		}
+	}
+
+	// The caller's request is left untouched
+	if got := req.Header.Values("Accept"); !slices.Equal(got, []string{"text/plain"}) {
+		t.Errorf("Expected the caller's Accept header unchanged, got %v", got)
+	}
+	if req.Header.Get("X-Client-Id") != "" {
+		t.Error("Expected no default header on the caller's request")
+	}
+}
+
+func TestClientBuilder_WithDefaultHeaders(t *testing.T) {
+	headers := http.Header{"x-client-id": {"billing"}}
+	rt := NewClientBuilder().WithDefaultHeaders(headers).Build().Transport.(*retryTransport)
+
+	if got := rt.DefaultHeaders.Get("X-Client-Id"); got != "billing" {
+		t.Errorf("Expected the canonical X-Client-Id header, got %v", rt.DefaultHeaders)
+	}
+
+	// Later changes to the caller's headers don't leak into the client
+	headers["x-client-id"][0] = "changed"
+	if got := rt.DefaultHeaders.Get("X-Client-Id"); got != "billing" {
+		t.Errorf("Expected the client to keep its copy, got %q", got)
+	}
+}
+
+func TestClientBuilder_WithUserAgent(t *testing.T) {
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClientBuilder().WithUserAgent("billing/1.2").Build()
+
+	send := func(userAgent string, set bool) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		if set {
+			req.Header.Set("User-Agent", userAgent)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	send("", false)
+	send("caller/2.0", true)
+	send("", true) // An explicitly empty User-Agent sends none
+
+	want := []string{"billing/1.2", "caller/2.0", ""}
+	if !slices.Equal(userAgents, want) {
+		t.Errorf("Expected User-Agents %q, got %q", want, userAgents)
+	}
+}
+
+func TestRetryTransport_UserAgentFromDefaultHeaders(t *testing.T) {
+	var userAgent []string
+	retryRT := &retryTransport{
+		Transport: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				userAgent = req.Header.Values("User-Agent")
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: make(http.Header)}, nil
+			},
+		},
+		MaxRetries:     1,
+		RetryStrategy:  FixedDelay(1 * time.Millisecond),
+		DefaultHeaders: http.Header{"User-Agent": {"default/1.0"}},
+		UserAgent:      "option/1.0",
+	}
+
+	resp, err := retryRT.RoundTrip(httptest.NewRequest("GET", "http://example.com", nil))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if !slices.Equal(userAgent, []string{"default/1.0"}) {
+		t.Errorf("Expected the default headers' User-Agent only, got %q", userAgent)
+	}
+}
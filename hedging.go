@@ -0,0 +1,127 @@
+package httpretrier
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// hedgeResult is the outcome of one copy of a hedged attempt
+type hedgeResult struct {
+	index  int // Position of the copy, 0 for the original request
+	resp   *http.Response
+	err    error
+	cancel context.CancelFunc
+}
+
+// discard releases the resources of a copy that won't be returned
+func (h hedgeResult) discard() {
+	if h.resp != nil {
+		h.resp.Body.Close()
+	}
+	h.cancel()
+}
+
+// isHedgeable reports whether req can be sent several times concurrently:
+// its method must be idempotent and its body, if any, replayable
+func isHedgeable(req *http.Request) bool {
+	if !isIdempotentMethod(req.Method) {
+		return false
+	}
+
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// hedgedRoundTrip sends req and, as long as no response worth keeping has
+// arrived, up to MaxHedges speculative copies of it, each HedgeDelay after the
+// previous one. The first response that doesn't warrant a retry is returned
+// and the other copies are canceled. If every copy fails, the last failure is
+// returned, to be retried like any failed attempt.
+func (r *retryTransport) hedgedRoundTrip(transport http.RoundTripper, req *http.Request) (*http.Response, error) {
+	results := make(chan hedgeResult, r.MaxHedges+1)
+	var cancels []context.CancelFunc
+
+	send := func(copyReq *http.Request, cancel context.CancelFunc) {
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := transport.RoundTrip(copyReq)
+			results <- hedgeResult{index: index, resp: resp, err: err, cancel: cancel}
+		}()
+	}
+
+	// The first copy uses the attempt's body, the hedges get their own
+	ctx, cancel := context.WithCancel(req.Context())
+	send(req.WithContext(ctx), cancel)
+	sent, pending := 1, 1
+
+	timer := time.NewTimer(r.HedgeDelay)
+	defer timer.Stop()
+
+	var last hedgeResult
+	for pending > 0 {
+		var hedge <-chan time.Time
+		if sent <= r.MaxHedges {
+			hedge = timer.C
+		}
+
+		select {
+		case res := <-results:
+			pending--
+			if res.err == nil && !r.shouldRetry(res.resp, nil) {
+				// Cancel the losers and release them as they finish
+				for i, cancel := range cancels {
+					if i != res.index {
+						cancel()
+					}
+				}
+				go func(pending int) {
+					for range pending {
+						(<-results).discard()
+					}
+				}(pending)
+				if last.cancel != nil {
+					last.discard()
+				}
+
+				// The winner's context must outlive RoundTrip until its body is consumed
+				res.resp.Body = &cancelOnCloseBody{ReadCloser: res.resp.Body, cancel: res.cancel}
+				return res.resp, nil
+			}
+
+			if last.cancel != nil {
+				last.discard()
+			}
+			last = res
+		case <-hedge:
+			ctx, cancel := context.WithCancel(req.Context())
+			copyReq := req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					// Stop hedging, the copies in flight may still succeed
+					cancel()
+					sent = r.MaxHedges + 1
+					continue
+				}
+				copyReq.Body = body
+			}
+
+			r.logger().Debug("Sending hedged request", "hedge", sent, "method", req.Method, "url", req.URL.String())
+			send(copyReq, cancel)
+			sent++
+			pending++
+			timer.Reset(r.HedgeDelay)
+		}
+	}
+
+	// Every copy failed: hand back the last failure, keeping its context
+	// alive until its body is closed
+	if last.resp != nil {
+		last.resp.Body = &cancelOnCloseBody{ReadCloser: last.resp.Body, cancel: last.cancel}
+	} else {
+		last.cancel()
+	}
+
+	return last.resp, last.err
+}
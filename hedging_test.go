@@ -0,0 +1,327 @@
+package httpretrier
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport_HedgingFirstGoodResponseWins(t *testing.T) {
+	var calls int32
+	var mu sync.Mutex
+	var canceled []int32
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			n := atomic.AddInt32(&calls, 1)
+			if n == 1 {
+				// The original request hangs until it is canceled
+				<-req.Context().Done()
+				mu.Lock()
+				canceled = append(canceled, n)
+				mu.Unlock()
+				return nil, req.Context().Err()
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("hedge")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(time.Millisecond),
+		MaxHedges:     2,
+		HedgeDelay:    10 * time.Millisecond,
+	}
+
+	resp, err := retryRT.RoundTrip(httptest.NewRequest("GET", "http://example.com", nil))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "hedge" {
+		t.Errorf("Expected the hedge's body, got %q (%v)", body, err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("Expected 2 copies sent, got %d", got)
+	}
+
+	// The losing copy gets canceled
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(canceled)
+		mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the original request to be canceled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRetryTransport_HedgingFastResponseSendsNoHedge(t *testing.T) {
+	var calls int32
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("OK")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:  mockRT,
+		MaxHedges:  3,
+		HedgeDelay: time.Second,
+	}
+
+	resp, err := retryRT.RoundTrip(httptest.NewRequest("GET", "http://example.com", nil))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected a single request, got %d", got)
+	}
+}
+
+func TestRetryTransport_HedgingAllCopiesFail(t *testing.T) {
+	var calls int32
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(5 * time.Millisecond)
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       io.NopCloser(strings.NewReader("Unavailable")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(time.Millisecond),
+		MaxHedges:     1,
+		HedgeDelay:    time.Millisecond,
+	}
+
+	_, err := retryRT.RoundTrip(httptest.NewRequest("GET", "http://example.com", nil))
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("Expected a RetryError, got %v", err)
+	}
+	if retryErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected the last status to be 503, got %d", retryErr.StatusCode)
+	}
+	// Each of the 2 attempts sent its original and its hedge
+	if got := atomic.LoadInt32(&calls); got != 4 {
+		t.Errorf("Expected 4 requests, got %d", got)
+	}
+}
+
+func TestRetryTransport_HedgingSkipsNonIdempotentRequests(t *testing.T) {
+	var calls int32
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(20 * time.Millisecond)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("OK")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:  mockRT,
+		MaxHedges:  2,
+		HedgeDelay: time.Millisecond,
+	}
+
+	resp, err := retryRT.RoundTrip(httptest.NewRequest("POST", "http://example.com", strings.NewReader("payload")))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected POST not to be hedged, got %d requests", got)
+	}
+}
+
+func TestIsHedgeable(t *testing.T) {
+	withGetBody, _ := http.NewRequest("PUT", "http://example.com", strings.NewReader("payload"))
+	withoutGetBody := httptest.NewRequest("PUT", "http://example.com", io.NopCloser(strings.NewReader("payload")))
+	withoutGetBody.GetBody = nil
+
+	tests := []struct {
+		name     string
+		req      *http.Request
+		expected bool
+	}{
+		{name: "GET", req: httptest.NewRequest("GET", "http://example.com", nil), expected: true},
+		{name: "HEAD", req: httptest.NewRequest("HEAD", "http://example.com", nil), expected: true},
+		{name: "POST", req: httptest.NewRequest("POST", "http://example.com", nil), expected: false},
+		{name: "PATCH", req: httptest.NewRequest("PATCH", "http://example.com", nil), expected: false},
+		{name: "PUT With Replayable Body", req: withGetBody, expected: true},
+		{name: "PUT Without Replayable Body", req: withoutGetBody, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isHedgeable(tt.req); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestClientBuilder_WithHedging(t *testing.T) {
+	rt, _ := NewClientBuilder().WithHedging(50*time.Millisecond, 2).Build().Transport.(*retryTransport)
+	if rt.MaxHedges != 2 || rt.HedgeDelay != 50*time.Millisecond {
+		t.Errorf("Expected 2 hedges 50ms apart, got %d and %v", rt.MaxHedges, rt.HedgeDelay)
+	}
+
+	for _, tt := range []struct {
+		delay     time.Duration
+		maxHedges int
+	}{
+		{delay: 0, maxHedges: 2},
+		{delay: time.Second, maxHedges: 0},
+		{delay: -time.Second, maxHedges: -1},
+	} {
+		builder := NewClientBuilder().WithHedging(tt.delay, tt.maxHedges)
+		rt, _ = builder.Build().Transport.(*retryTransport)
+		if rt.MaxHedges != 0 {
+			t.Errorf("Expected hedging to be disabled for %+v", tt)
+		}
+		if len(builder.Warnings()) != 1 {
+			t.Errorf("Expected 1 warning for %+v, got %v", tt, builder.Warnings())
+		}
+	}
+}
+
+func TestRetryTransport_HedgingFileBody(t *testing.T) {
+	const content = "0123456789abcdefghij"
+	path := filepath.Join(t.TempDir(), "upload")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+
+	var calls int32
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var mu sync.Mutex
+	var bodies []string
+	hedgeRead, originalRead := make(chan struct{}), make(chan struct{})
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			defer wg.Done()
+			var got []byte
+			status := http.StatusOK
+			if atomic.AddInt32(&calls, 1) == 1 {
+				// The original copy is half read when the hedge reads the body
+				got = make([]byte, 5)
+				io.ReadFull(req.Body, got)
+				select {
+				case <-hedgeRead:
+				case <-time.After(time.Second):
+				}
+				rest, _ := io.ReadAll(req.Body)
+				got = append(got, rest...)
+				close(originalRead)
+				status = http.StatusServiceUnavailable
+			} else {
+				got, _ = io.ReadAll(req.Body)
+				close(hedgeRead)
+				// Win only once the original copy is done reading, as the
+				// file is closed when the request is over
+				<-originalRead
+			}
+			req.Body.Close()
+
+			mu.Lock()
+			bodies = append(bodies, string(got))
+			mu.Unlock()
+			return &http.Response{StatusCode: status, Body: http.NoBody, Header: make(http.Header)}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(time.Millisecond),
+		MaxHedges:     1,
+		HedgeDelay:    5 * time.Millisecond,
+	}
+
+	req, _ := http.NewRequest("PUT", "http://example.com", file)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	wg.Wait()
+
+	// Each copy reads the whole file, whatever the other one does
+	if len(bodies) != 2 || bodies[0] != content || bodies[1] != content {
+		t.Errorf("Expected both copies to send %q, got %q", content, bodies)
+	}
+}
+
+func TestRetryTransport_HedgingSkipsSharedBodies(t *testing.T) {
+	var calls int32
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			io.ReadAll(req.Body)
+			time.Sleep(20 * time.Millisecond)
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: make(http.Header)}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:  mockRT,
+		MaxHedges:  2,
+		HedgeDelay: time.Millisecond,
+	}
+
+	// A body that can seek but not be read at an offset is shared by the attempts
+	body := struct {
+		io.ReadSeeker
+		io.Closer
+	}{strings.NewReader("payload"), io.NopCloser(nil)}
+	resp, err := retryRT.RoundTrip(httptest.NewRequest("PUT", "http://example.com", body))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected a shared body not to be hedged, got %d requests", got)
+	}
+}
@@ -0,0 +1,98 @@
+package httpretrier
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// hostLimiter caps the number of requests proceeding concurrently to each host.
+// Unlike the transport's connection limits, it throttles request initiation,
+// so it also applies to requests that would reuse an idle connection.
+type hostLimiter struct {
+	limit int
+
+	mu    sync.Mutex
+	hosts map[string]*hostSlots
+}
+
+// hostSlots is the semaphore of a host, along with the number of requests
+// holding or waiting for one of its slots. A host is forgotten once that
+// number drops to zero, so the limiter doesn't grow with every host ever seen.
+type hostSlots struct {
+	sem   chan struct{}
+	users int
+}
+
+// newHostLimiter creates a hostLimiter allowing limit concurrent requests per host
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{
+		limit: limit,
+		hosts: make(map[string]*hostSlots),
+	}
+}
+
+// join returns the slots of the given host, creating them if needed, and
+// counts the caller as one of their users
+func (l *hostLimiter) join(host string) *hostSlots {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slots, ok := l.hosts[host]
+	if !ok {
+		slots = &hostSlots{sem: make(chan struct{}, l.limit)}
+		l.hosts[host] = slots
+	}
+	slots.users++
+
+	return slots
+}
+
+// leave stops counting the caller as a user of the host's slots, forgetting
+// the host when nobody else uses them
+func (l *hostLimiter) leave(host string, slots *hostSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slots.users--
+	if slots.users == 0 {
+		delete(l.hosts, host)
+	}
+}
+
+// acquire blocks until a slot for the host is available or ctx is done
+func (l *hostLimiter) acquire(ctx context.Context, host string) error {
+	slots := l.join(host)
+	select {
+	case slots.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		l.leave(host, slots)
+		return ctx.Err()
+	}
+}
+
+// release frees a slot previously acquired for the host
+func (l *hostLimiter) release(host string) {
+	l.mu.Lock()
+	slots := l.hosts[host]
+	l.mu.Unlock()
+
+	<-slots.sem
+	l.leave(host, slots)
+}
+
+// releaseOnCloseBody holds a host slot until the response body is closed,
+// so a response being streamed still counts against the host's limit
+type releaseOnCloseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Close closes the underlying body and releases the host slot, once
+func (b *releaseOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,207 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport_MaxConcurrentPerHost(t *testing.T) {
+	const limit = 2
+	var inFlight, maxInFlight, calls int32
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			current := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+
+			for {
+				observed := atomic.LoadInt32(&maxInFlight)
+				if current <= observed || atomic.CompareAndSwapInt32(&maxInFlight, observed, current) {
+					break
+				}
+			}
+
+			time.Sleep(5 * time.Millisecond)
+
+			// Fail every other call so retries go through the limiter too
+			status := http.StatusOK
+			if atomic.AddInt32(&calls, 1)%2 == 0 {
+				status = http.StatusInternalServerError
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader("body")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    2,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		hostLimiter:   newHostLimiter(limit),
+	}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			resp, err := retryRT.RoundTrip(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&maxInFlight); got > limit {
+		t.Errorf("Expected at most %d concurrent requests to the host, got %d", limit, got)
+	}
+}
+
+func TestRetryTransport_MaxConcurrentPerHostIndependentHosts(t *testing.T) {
+	limiter := newHostLimiter(1)
+	ctx := context.Background()
+
+	if err := limiter.acquire(ctx, "a.example.com"); err != nil {
+		t.Fatalf("Expected to acquire slot for first host, got %v", err)
+	}
+	defer limiter.release("a.example.com")
+
+	// A different host has its own slots
+	ctxTimeout, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := limiter.acquire(ctxTimeout, "b.example.com"); err != nil {
+		t.Fatalf("Expected to acquire slot for second host, got %v", err)
+	}
+	limiter.release("b.example.com")
+}
+
+func TestRetryTransport_MaxConcurrentPerHostContextCancelled(t *testing.T) {
+	limiter := newHostLimiter(1)
+	if err := limiter.acquire(context.Background(), "example.com"); err != nil {
+		t.Fatalf("Expected to acquire slot, got %v", err)
+	}
+	defer limiter.release("example.com")
+
+	var calls int32
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, errors.New("should not be reached")
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		hostLimiter:   limiter,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest("GET", "http://example.com", nil).WithContext(ctx)
+
+	_, err := retryRT.RoundTrip(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context deadline error while waiting for a slot, got %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 0 {
+		t.Errorf("Expected no calls to the underlying transport, got %d", atomic.LoadInt32(&calls))
+	}
+}
+
+func TestRetryTransport_MaxConcurrentPerHostHeldUntilBodyClosed(t *testing.T) {
+	limiter := newHostLimiter(1)
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("body")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		hostLimiter:   limiter,
+	}
+
+	first, err := retryRT.RoundTrip(httptest.NewRequest("GET", "http://example.com", nil))
+	if err != nil {
+		t.Fatalf("Expected first request to succeed, got %v", err)
+	}
+
+	// The first response is still being read, so its slot is taken
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = retryRT.RoundTrip(httptest.NewRequest("GET", "http://example.com", nil).WithContext(ctx))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected second request to wait for the slot, got %v", err)
+	}
+
+	if _, err := io.ReadAll(first.Body); err != nil {
+		t.Fatalf("Failed to read first body: %v", err)
+	}
+	first.Body.Close()
+	// Closing twice must not release the slot twice
+	first.Body.Close()
+
+	second, err := retryRT.RoundTrip(httptest.NewRequest("GET", "http://example.com", nil))
+	if err != nil {
+		t.Fatalf("Expected second request to succeed once the first body is closed, got %v", err)
+	}
+	second.Body.Close()
+
+	if n := len(limiter.hosts); n != 0 {
+		t.Errorf("Expected no hosts left once every body is closed, got %d", n)
+	}
+}
+
+func TestRetryTransport_MaxConcurrentPerHostEvictsIdleHosts(t *testing.T) {
+	limiter := newHostLimiter(1)
+	ctx := context.Background()
+
+	for _, host := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		if err := limiter.acquire(ctx, host); err != nil {
+			t.Fatalf("Expected to acquire slot for %s, got %v", host, err)
+		}
+		limiter.release(host)
+	}
+	if n := len(limiter.hosts); n != 0 {
+		t.Errorf("Expected released hosts to be evicted, got %d left", n)
+	}
+
+	// A host stays known while someone holds its slot or waits for one
+	if err := limiter.acquire(ctx, "a.example.com"); err != nil {
+		t.Fatalf("Expected to acquire slot, got %v", err)
+	}
+	ctxTimeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.acquire(ctxTimeout, "a.example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected waiting for a taken slot to time out, got %v", err)
+	}
+	if n := len(limiter.hosts); n != 1 {
+		t.Errorf("Expected the held host to be kept, got %d hosts", n)
+	}
+
+	limiter.release("a.example.com")
+	if n := len(limiter.hosts); n != 0 {
+		t.Errorf("Expected the host to be evicted once released, got %d left", n)
+	}
+}
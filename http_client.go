@@ -1,8 +1,20 @@
 package httpretrier
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"log/slog"
+	"maps"
+	"math"
+	"math/rand"
+	"net"
 	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"slices"
+	"syscall"
 	"time"
 )
 
@@ -11,20 +23,42 @@ const (
 	ValidMinIdleConns             = 1
 	ValidMaxIdleConnsPerHost      = 200
 	ValidMinIdleConnsPerHost      = 1
+	ValidMaxMaxConnsPerHost       = 1000
+	ValidMinMaxConnsPerHost       = 1
+	ValidMaxWriteBufferSize       = 1 << 20
+	ValidMinWriteBufferSize       = 1 << 10
+	ValidMaxReadBufferSize        = 1 << 20
+	ValidMinReadBufferSize        = 1 << 10
+	ValidMaxResponseHeaderBytes   = 10 << 20
+	ValidMinResponseHeaderBytes   = 1 << 10
 	ValidMaxIdleConnTimeout       = 120 * time.Second
 	ValidMinIdleConnTimeout       = 1 * time.Second
 	ValidMaxTLSHandshakeTimeout   = 15 * time.Second
 	ValidMinTLSHandshakeTimeout   = 1 * time.Second
 	ValidMaxExpectContinueTimeout = 5 * time.Second
 	ValidMinExpectContinueTimeout = 1 * time.Second
+	ValidMaxResponseHeaderTimeout = 60 * time.Second
+	ValidMinResponseHeaderTimeout = 100 * time.Millisecond
+	ValidMaxDialTimeout           = 60 * time.Second
+	ValidMinDialTimeout           = 100 * time.Millisecond
+	ValidMaxDialKeepAlive         = 300 * time.Second
+	ValidMinDialKeepAlive         = 1 * time.Second
 	ValidMaxTimeout               = 30 * time.Second
 	ValidMinTimeout               = 1 * time.Second
 	ValidMaxRetries               = 10
-	ValidMinRetries               = 1
+	ValidMinRetries               = 0
+	ValidMaxAttempts              = ValidMaxRetries + 1
+	ValidMinAttempts              = 1
 	ValidMaxBaseDelay             = 5 * time.Second
 	ValidMinBaseDelay             = 300 * time.Millisecond
 	ValidMaxMaxDelay              = 120 * time.Second
 	ValidMinMaxDelay              = 300 * time.Millisecond
+	ValidMaxRetryMultiplier       = 10.0
+	ValidMinRetryMultiplier       = 1.0
+	ValidMaxFixedJitter           = 1.0
+	ValidMinFixedJitter           = 0.0
+	ValidMaxDrainBufferSize       = 64 << 10
+	ValidMinDrainBufferSize       = 512
 
 	// DefaultMaxRetries is the default number of retry attempts
 	DefaultMaxRetries = 3
@@ -35,6 +69,15 @@ const (
 	// DefaultMaxDelay is the default maximum delay for backoff strategies
 	DefaultMaxDelay = 10 * time.Second
 
+	// NoMaxDelay, passed to WithRetryMaxDelay, lets the backoff delays grow without a cap
+	NoMaxDelay time.Duration = 0
+
+	// DefaultRetryMultiplier is the default growth factor for exponential backoff strategies
+	DefaultRetryMultiplier = 2.0
+
+	// DefaultFixedJitter is the default jitter fraction of the fixed delay strategy (0 means no jitter)
+	DefaultFixedJitter = 0.0
+
 	// DefaultMaxIdleConns is the default maximum number of idle connections
 	DefaultMaxIdleConns = 100
 
@@ -47,14 +90,92 @@ const (
 	// DefaultExpectContinueTimeout is the default expect continue timeout
 	DefaultExpectContinueTimeout = 1 * time.Second
 
+	// DefaultResponseHeaderTimeout is the default time to wait for the response headers (0 means no timeout)
+	DefaultResponseHeaderTimeout time.Duration = 0
+
+	// DefaultDialTimeout is the default timeout for establishing a TCP connection
+	DefaultDialTimeout = 30 * time.Second
+
+	// DefaultDialKeepAlive is the default interval between TCP keep-alive probes
+	DefaultDialKeepAlive = 30 * time.Second
+
+	// DefaultForceAttemptHTTP2 is the default setting for attempting HTTP/2 with a custom dialer or TLS config
+	DefaultForceAttemptHTTP2 = true
+
+	// DefaultDisableHTTP2 is the default disable HTTP/2 setting
+	DefaultDisableHTTP2 = false
+
+	// DefaultFreshConnOnError is the default setting for dropping idle connections after a connection error
+	DefaultFreshConnOnError = true
+
 	// DefaultDisableKeepAlives is the default disable keep-alives setting
 	DefaultDisableKeepAlives = false
 
 	// DefaultMaxIdleConnsPerHost is the default maximum number of idle connections per host
 	DefaultMaxIdleConnsPerHost = 100
 
+	// DefaultMaxConnsPerHost is the default maximum number of connections per host (0 means no limit)
+	DefaultMaxConnsPerHost = 0
+
+	// DefaultWriteBufferSize is the default size of the transport's write buffer (0 means net/http's default, 4 KiB)
+	DefaultWriteBufferSize = 0
+
+	// DefaultReadBufferSize is the default size of the transport's read buffer (0 means net/http's default, 4 KiB)
+	DefaultReadBufferSize = 0
+
+	// DefaultMaxResponseHeaderBytes is the default limit on the size of the response headers (0 means net/http's default, 10 MiB)
+	DefaultMaxResponseHeaderBytes int64 = 0
+
 	// DefaultTimeout is the default timeout for HTTP requests
 	DefaultTimeout = 5 * time.Second
+
+	// DefaultMaxElapsedTime is the default time budget across all retries (0 means no budget)
+	DefaultMaxElapsedTime = 0 * time.Second
+
+	// DefaultMinAttemptBudget is the default time an attempt needs before the deadline (0 means only the delay must fit)
+	DefaultMinAttemptBudget = 0 * time.Second
+
+	// DefaultMaxJitter is the default bound on the jitter of the jitter strategies (0 means half the delay)
+	DefaultMaxJitter = 0 * time.Second
+
+	// DefaultPerAttemptTimeout is the default timeout for each individual attempt (0 means no per-attempt timeout)
+	DefaultPerAttemptTimeout = 0 * time.Second
+
+	// DefaultMinTLSVersion is the default minimum TLS version accepted by the transport
+	DefaultMinTLSVersion = tls.VersionTLS12
+
+	// DefaultConnMaxLifetime is the default maximum age of a connection (0 means connections are reused for as long as they stay open)
+	DefaultConnMaxLifetime = 0 * time.Second
+
+	// DefaultMaxBufferSize is the default cap on request bodies buffered for retries (10 MiB)
+	DefaultMaxBufferSize = 10 << 20
+
+	// DefaultMaxTotalBufferBytes is the default budget for bodies buffered across concurrent requests (0 means unlimited)
+	DefaultMaxTotalBufferBytes = 0
+
+	// DefaultMaxValidateBodySize is the default maximum size of the response bodies passed to the response validator (1 MiB)
+	DefaultMaxValidateBodySize = 1 << 20
+
+	// DefaultAuthHeader is the default header carrying the credentials set by WithAuthRefresh
+	DefaultAuthHeader = "Authorization"
+
+	// DefaultIdempotencyKeyHeader is the header carrying idempotency keys when WithIdempotencyKey is given no name
+	DefaultIdempotencyKeyHeader = "Idempotency-Key"
+
+	// DefaultAttemptHeader is the default header carrying the attempt number on outgoing requests
+	DefaultAttemptHeader = "X-Retry-Attempt"
+
+	// DefaultMaxDrainSize is the default cap on bytes read from a failed attempt's response body before closing it (4 KiB)
+	DefaultMaxDrainSize = 4 << 10
+
+	// DefaultDrainBufferSize is the default size of the pooled buffers used to drain failed attempts' response bodies (4 KiB)
+	DefaultDrainBufferSize = 4 << 10
+
+	// DefaultMaxConcurrentPerHost is the default cap on concurrent requests per host (0 means unlimited)
+	DefaultMaxConcurrentPerHost = 0
+
+	// DefaultMaxConcurrent is the default cap on requests in flight per client (0 means unlimited)
+	DefaultMaxConcurrent = 0
 )
 
 // ClientError represents an error that occurs during HTTP client operations
@@ -75,15 +196,43 @@ const (
 	FixedDelayStrategy         Strategy = "fixed"
 	JitterBackoffStrategy      Strategy = "jitter"
 	ExponentialBackoffStrategy Strategy = "exponential"
+	FullJitterStrategy         Strategy = "full-jitter"
+	EqualJitterStrategy        Strategy = "equal-jitter"
+
+	// DecorrelatedJitterStrategy is stateful: every request gets its own
+	// instance so the previous delay is tracked per request
+	DecorrelatedJitterStrategy Strategy = "decorrelated"
 )
 
 func (s Strategy) String() string {
 	return string(s)
 }
 
+// IsValid reports whether s is a built-in strategy or was registered with RegisterStrategy
 func (s Strategy) IsValid() bool {
+	if s.isBuiltin() {
+		return true
+	}
+
+	_, ok := registeredStrategy(s)
+	return ok
+}
+
+// isBuiltin reports whether s is one of the strategies provided by this package
+func (s Strategy) isBuiltin() bool {
+	switch s {
+	case FixedDelayStrategy, JitterBackoffStrategy, ExponentialBackoffStrategy,
+		FullJitterStrategy, EqualJitterStrategy, DecorrelatedJitterStrategy:
+		return true
+	default:
+		return false
+	}
+}
+
+// usesJitter reports whether the strategy draws random numbers
+func (s Strategy) usesJitter() bool {
 	switch s {
-	case FixedDelayStrategy, JitterBackoffStrategy, ExponentialBackoffStrategy:
+	case JitterBackoffStrategy, FullJitterStrategy, EqualJitterStrategy, DecorrelatedJitterStrategy:
 		return true
 	default:
 		return false
@@ -93,22 +242,197 @@ func (s Strategy) IsValid() bool {
 // Client is a custom HTTP client with configurable settings
 // and retry strategies
 type Client struct {
-	maxIdleConns          int
-	idleConnTimeout       time.Duration
-	tlsHandshakeTimeout   time.Duration
-	expectContinueTimeout time.Duration
-	disableKeepAlives     bool
-	maxIdleConnsPerHost   int
-	timeout               time.Duration
-	maxRetries            int
-	retryStrategyType     Strategy // Store the type, not the function
-	retryBaseDelay        time.Duration
-	retryMaxDelay         time.Duration
+	maxIdleConns           int
+	idleConnTimeout        time.Duration
+	tlsHandshakeTimeout    time.Duration
+	expectContinueTimeout  time.Duration
+	responseHeaderTimeout  time.Duration
+	disableKeepAlives      bool
+	maxIdleConnsPerHost    int
+	timeout                time.Duration
+	maxRetries             int
+	maxRetriesSet          bool
+	maxAttempts            int
+	maxAttemptsSet         bool
+	retryStrategyType      Strategy // Store the type, not the function
+	retryBaseDelay         time.Duration
+	retryMaxDelay          time.Duration
+	strictMaxDelay         bool
+	maxJitter              time.Duration
+	minDelay               time.Duration
+	ranges                 ValidationRanges // Zero ranges mean the default ones
+	requestSeed            func(req *http.Request) int64
+	shutdownSignals        []os.Signal
+	jitterSeed             int64
+	jitterSeedSet          bool
+	maxConcurrentPerHost   int
+	randomMaxDelayMean     time.Duration
+	maxDelayDistribution   DelayDistribution
+	retryableStatuses      []StatusRange
+	noRetryStatuses        []int
+	statusDelays           map[int]time.Duration
+	requiredHeader         string
+	expectedContentType    string
+	respectRetryAfter      bool
+	dryRun                 bool
+	responseValidator      func(resp *http.Response, body []byte) bool
+	maxValidateBodySize    int64
+	retryMultiplier        float64
+	fixedJitter            float64
+	rateLimit              float64
+	rateLimitBurst         int
+	rateLimitSet           bool
+	retryBudgetRatio       float64
+	retryBudgetMinPerSec   float64
+	retryBudgetSet         bool
+	maxConcurrent          int
+	breakerThreshold       int
+	breakerOpenDuration    time.Duration
+	breakerSet             bool
+	adaptiveBackoff        bool
+	hedgeDelay             time.Duration
+	maxHedges              int
+	fallbackHosts          []string
+	connectTimeoutBackoff  RetryStrategy
+	maxElapsedTime         time.Duration
+	minAttemptBudget       time.Duration
+	perAttemptTimeout      time.Duration
+	retryEvents            chan<- RetryEvent
+	onRetry                []func(RetryEvent)
+	onRequestDone          []func(Stats)
+	attemptMiddlewares     []Middleware
+	requestMiddlewares     []Middleware
+	autoBufferBody         bool
+	maxBufferSize          int64
+	clock                  Clock
+	logger                 *slog.Logger
+	maxDrainSize           int64
+	drainBufferSize        int
+	onBufferTruncated      func(size int64)
+	retryCondition         RetryCondition
+	maxTotalBufferBytes    int64
+	waitForBufferBudget    bool
+	returnLastResponse     bool
+	attemptHeader          string
+	defaultHeaders         http.Header
+	userAgent              string
+	idempotencyKeyHeader   string
+	authRefresh            func(ctx context.Context) (string, error)
+	authHeader             string
+	baseTransport          http.RoundTripper
+	tlsConfig              *tls.Config
+	minTLSVersion          uint16
+	clientCertFile         string
+	clientKeyFile          string
+	clientCert             *tls.Certificate // Loaded from the files by validation
+	rootCAFile             string
+	rootCAs                *x509.CertPool // Loaded from the file by validation
+	proxy                  func(*http.Request) (*url.URL, error)
+	proxyURL               string
+	unixSocket             string
+	dialTimeout            time.Duration
+	dialKeepAlive          time.Duration
+	connMaxLifetime        time.Duration
+	forceAttemptHTTP2      bool
+	freshConnOnError       bool
+	disableHTTP2           bool
+	maxConnsPerHost        int
+	writeBufferSize        int
+	readBufferSize         int
+	maxResponseHeaderBytes int64
+	cookieJar              http.CookieJar
+	checkRedirect          func(req *http.Request, via []*http.Request) error
+	maxRedirects           int
+	maxRedirectsSet        bool
+	transportSettingsSet   bool
+}
+
+// newRetryStrategy creates the strategy function for the given type using the
+// client's delays, drawing any jitter from int63n. With a strict max delay,
+// the delays are clamped to it, jitter included
+func (c *Client) newRetryStrategy(strategyType Strategy, int63n func(n int64) int64) RetryStrategy {
+	strategy := c.newBaseStrategy(strategyType, int63n)
+	if c.strictMaxDelay {
+		return capDelay(strategy, c.maxDelayCap())
+	}
+
+	return strategy
+}
+
+// newBaseStrategy creates the strategy function for the given type, whose
+// jitter may take the delays above the max delay
+func (c *Client) newBaseStrategy(strategyType Strategy, int63n func(n int64) int64) RetryStrategy {
+	switch strategyType {
+	case FixedDelayStrategy:
+		return fixedDelayWithJitter(c.retryBaseDelay, c.fixedJitter, int63n)
+	case JitterBackoffStrategy:
+		return jitterBackoff(c.exponentialBackoff(), int63n, c.maxJitter)
+	case FullJitterStrategy:
+		return fullJitterBackoff(c.exponentialBackoff(), int63n)
+	case EqualJitterStrategy:
+		return equalJitterBackoff(c.exponentialBackoff(), int63n, c.maxJitter)
+	case DecorrelatedJitterStrategy:
+		return decorrelatedJitter(c.retryBaseDelay, c.maxDelayCap(), int63n)
+	case ExponentialBackoffStrategy:
+		return c.exponentialBackoff()
+	default:
+		if factory, ok := registeredStrategy(strategyType); ok {
+			return factory(c.retryBaseDelay, c.maxDelayCap())
+		}
+		return c.exponentialBackoff() // Anything unexpected
+	}
+}
+
+// usesFixedJitter reports whether the client uses the fixed delay strategy with jitter
+func (c *Client) usesFixedJitter() bool {
+	return c.retryStrategyType == FixedDelayStrategy && c.fixedJitter > 0
+}
+
+// exponentialBackoff creates the exponential strategy the other backoff
+// strategies build on, using the configured growth factor
+func (c *Client) exponentialBackoff() RetryStrategy {
+	if c.retryMultiplier == DefaultRetryMultiplier {
+		return ExponentialBackoff(c.retryBaseDelay, c.maxDelayCap())
+	}
+
+	return ExponentialBackoffWithFactor(c.retryBaseDelay, c.maxDelayCap(), c.retryMultiplier)
+}
+
+// maxDelayCap returns the max delay passed to the strategies, the largest
+// time.Duration when there is no cap
+func (c *Client) maxDelayCap() time.Duration {
+	if c.retryMaxDelay == NoMaxDelay {
+		return math.MaxInt64
+	}
+
+	return c.retryMaxDelay
+}
+
+// newRequestStrategy creates the strategy function for a single request.
+// When a randomized max delay is configured, the request gets its own
+// max delay drawn around the configured mean, never below the base delay.
+func (c *Client) newRequestStrategy(strategyType Strategy, int63n func(n int64) int64, uniform func() float64) RetryStrategy {
+	if c.randomMaxDelayMean <= 0 {
+		return c.newRetryStrategy(strategyType, int63n)
+	}
+
+	distribution := c.maxDelayDistribution
+	if distribution == nil {
+		distribution = ExponentialDistribution
+	}
+
+	requestConfig := *c
+	requestConfig.retryMaxDelay = min(max(distribution(c.randomMaxDelayMean, uniform()), c.retryBaseDelay), c.ranges.MaxDelay.Max)
+
+	return requestConfig.newRetryStrategy(strategyType, int63n)
 }
 
 // ClientBuilder is a builder for creating a custom HTTP client
 type ClientBuilder struct {
 	client *Client
+
+	// warnings describes the settings adjusted by the last Build call
+	warnings []string
 }
 
 // NewClientBuilder creates a new ClientBuilder with default settings
@@ -117,27 +441,133 @@ func NewClientBuilder() *ClientBuilder {
 	cb := &ClientBuilder{
 		client: &Client{
 			// Initialize with defaults
-			maxIdleConns:          DefaultMaxIdleConns,
-			idleConnTimeout:       DefaultIdleConnTimeout,
-			tlsHandshakeTimeout:   DefaultTLSHandshakeTimeout,
-			expectContinueTimeout: DefaultExpectContinueTimeout,
-			disableKeepAlives:     DefaultDisableKeepAlives,
-			maxIdleConnsPerHost:   DefaultMaxIdleConnsPerHost,
-			timeout:               DefaultTimeout,
-			maxRetries:            DefaultMaxRetries,
-			retryStrategyType:     ExponentialBackoffStrategy, // Default strategy type
-			retryBaseDelay:        DefaultBaseDelay,
-			retryMaxDelay:         DefaultMaxDelay,
+			maxIdleConns:           DefaultMaxIdleConns,
+			idleConnTimeout:        DefaultIdleConnTimeout,
+			tlsHandshakeTimeout:    DefaultTLSHandshakeTimeout,
+			expectContinueTimeout:  DefaultExpectContinueTimeout,
+			responseHeaderTimeout:  DefaultResponseHeaderTimeout,
+			dialTimeout:            DefaultDialTimeout,
+			dialKeepAlive:          DefaultDialKeepAlive,
+			connMaxLifetime:        DefaultConnMaxLifetime,
+			minTLSVersion:          DefaultMinTLSVersion,
+			forceAttemptHTTP2:      DefaultForceAttemptHTTP2,
+			disableHTTP2:           DefaultDisableHTTP2,
+			freshConnOnError:       DefaultFreshConnOnError,
+			disableKeepAlives:      DefaultDisableKeepAlives,
+			maxIdleConnsPerHost:    DefaultMaxIdleConnsPerHost,
+			maxConnsPerHost:        DefaultMaxConnsPerHost,
+			writeBufferSize:        DefaultWriteBufferSize,
+			readBufferSize:         DefaultReadBufferSize,
+			maxResponseHeaderBytes: DefaultMaxResponseHeaderBytes,
+			timeout:                DefaultTimeout,
+			maxRetries:             DefaultMaxRetries,
+			retryStrategyType:      ExponentialBackoffStrategy, // Default strategy type
+			retryBaseDelay:         DefaultBaseDelay,
+			retryMaxDelay:          DefaultMaxDelay,
+			maxConcurrentPerHost:   DefaultMaxConcurrentPerHost,
+			maxConcurrent:          DefaultMaxConcurrent,
+			retryMultiplier:        DefaultRetryMultiplier,
+			fixedJitter:            DefaultFixedJitter,
+			maxElapsedTime:         DefaultMaxElapsedTime,
+			minAttemptBudget:       DefaultMinAttemptBudget,
+			maxJitter:              DefaultMaxJitter,
+			perAttemptTimeout:      DefaultPerAttemptTimeout,
+			maxBufferSize:          DefaultMaxBufferSize,
+			maxDrainSize:           DefaultMaxDrainSize,
+			drainBufferSize:        DefaultDrainBufferSize,
+			attemptHeader:          DefaultAttemptHeader,
+			authHeader:             DefaultAuthHeader,
+			maxValidateBodySize:    DefaultMaxValidateBodySize,
 		},
 	}
 	return cb
 }
 
+// NewClientBuilderFrom creates a new ClientBuilder adding retries to an
+// existing client. Build wraps the client's transport, http.DefaultTransport
+// if it has none, in the retry logic as is, like WithBaseTransport. The
+// client's cookie jar and redirect policy are kept, and so is its timeout
+// unless it is 0 (no timeout), which falls back to DefaultTimeout.
+// When the transport is an *http.Transport, its idle connection settings
+// (MaxIdleConns, MaxIdleConnsPerHost, MaxConnsPerHost and IdleConnTimeout),
+// TLS handshake, expect continue and response header timeouts, buffer sizes,
+// response header limit, keep-alives and HTTP/2 setting
+// are copied too, so they are reported accurately, zero values keeping the
+// builder's defaults. They describe the wrapped transport, which Build doesn't
+// change. All the retry settings start from their defaults.
+// A client built by this package gets its retry logic replaced rather than
+// wrapped, keeping its underlying transport. The client itself is left as is:
+// a signal handler installed by its WithSignalAwareShutdown stays in place
+// until Shutdown is called on it, which callers done with it should do.
+func NewClientBuilderFrom(client *http.Client) *ClientBuilder {
+	b := NewClientBuilder()
+	if client == nil {
+		return b
+	}
+
+	if client.Timeout > 0 {
+		b.client.timeout = client.Timeout
+	}
+	b.client.cookieJar = client.Jar
+	b.client.checkRedirect = client.CheckRedirect
+
+	transport := client.Transport
+	if rt, ok := transport.(*retryTransport); ok {
+		transport = rt.Transport
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	b.client.baseTransport = transport
+
+	// Set the fields directly: going through the With methods would warn
+	// that the transport settings are ignored with a base transport
+	if t, ok := transport.(*http.Transport); ok {
+		copyNonZero(&b.client.maxIdleConns, t.MaxIdleConns)
+		copyNonZero(&b.client.maxIdleConnsPerHost, t.MaxIdleConnsPerHost)
+		copyNonZero(&b.client.maxConnsPerHost, t.MaxConnsPerHost)
+		copyNonZero(&b.client.writeBufferSize, t.WriteBufferSize)
+		copyNonZero(&b.client.readBufferSize, t.ReadBufferSize)
+		copyNonZero(&b.client.maxResponseHeaderBytes, t.MaxResponseHeaderBytes)
+		copyNonZero(&b.client.idleConnTimeout, t.IdleConnTimeout)
+		copyNonZero(&b.client.tlsHandshakeTimeout, t.TLSHandshakeTimeout)
+		copyNonZero(&b.client.expectContinueTimeout, t.ExpectContinueTimeout)
+		b.client.responseHeaderTimeout = t.ResponseHeaderTimeout
+		b.client.disableKeepAlives = t.DisableKeepAlives
+		b.client.forceAttemptHTTP2 = t.ForceAttemptHTTP2
+	}
+
+	return b
+}
+
+// copyNonZero sets *dst to value, unless value is the zero value
+func copyNonZero[T comparable](dst *T, value T) {
+	var zero T
+	if value != zero {
+		*dst = value
+	}
+}
+
+// WithValidationRanges sets the ranges of values Build accepts for the
+// settings, in place of the ValidMin* and ValidMax* constants
+// and returns the ClientBuilder for method chaining
+// This allows values such as a 60s timeout that the default ranges reject.
+// Zero ranges keep their default, and the max attempts range follows the max
+// retries one unless set too. A value outside its range is still replaced by
+// the default value, even if the default is outside the range itself
+// If a range has its minimum above its maximum, a warning is logged and the default range is used
+func (b *ClientBuilder) WithValidationRanges(ranges ValidationRanges) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.ranges = ranges
+	return b
+}
+
 // WithMaxIdleConns sets the maximum number of idle connections
 // and returns the ClientBuilder for method chaining
 func (b *ClientBuilder) WithMaxIdleConns(maxIdleConns int) *ClientBuilder {
 	// Just set the value, Build will validate/default
 	b.client.maxIdleConns = maxIdleConns
+	b.client.transportSettingsSet = true
 	return b
 }
 
@@ -155,6 +585,7 @@ func (b *ClientBuilder) WithMaxIdleConns(maxIdleConns int) *ClientBuilder {
 func (b *ClientBuilder) WithIdleConnTimeout(idleConnTimeout time.Duration) *ClientBuilder {
 	// Just set the value, Build will validate/default
 	b.client.idleConnTimeout = idleConnTimeout
+	b.client.transportSettingsSet = true
 	return b
 }
 
@@ -170,6 +601,7 @@ func (b *ClientBuilder) WithIdleConnTimeout(idleConnTimeout time.Duration) *Clie
 func (b *ClientBuilder) WithTLSHandshakeTimeout(tlsHandshakeTimeout time.Duration) *ClientBuilder {
 	// Just set the value, Build will validate/default
 	b.client.tlsHandshakeTimeout = tlsHandshakeTimeout
+	b.client.transportSettingsSet = true
 	return b
 }
 
@@ -182,6 +614,99 @@ func (b *ClientBuilder) WithTLSHandshakeTimeout(tlsHandshakeTimeout time.Duratio
 func (b *ClientBuilder) WithExpectContinueTimeout(expectContinueTimeout time.Duration) *ClientBuilder {
 	// Just set the value, Build will validate/default
 	b.client.expectContinueTimeout = expectContinueTimeout
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithResponseHeaderTimeout sets the time to wait for the response headers
+// once the request is written, including its body
+// and returns the ClientBuilder for method chaining
+// It bounds a server that accepts the request but stalls before answering,
+// separately from the overall timeout. An attempt running out of it fails
+// with a timeout, which is retried
+// The value must be between ValidMinResponseHeaderTimeout and ValidMaxResponseHeaderTimeout,
+// or DefaultResponseHeaderTimeout (0) for no timeout
+// If the value is invalid, a warning is logged and the default value is used
+func (b *ClientBuilder) WithResponseHeaderTimeout(responseHeaderTimeout time.Duration) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.responseHeaderTimeout = responseHeaderTimeout
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithDialTimeout sets the maximum time to wait for a TCP connection to be established
+// and returns the ClientBuilder for method chaining
+// Valid range: 100 milliseconds to 60 seconds
+// If the value is invalid, a warning is logged and the default value is used
+// This setting is useful to fail fast when a host is down instead of
+// waiting for the operating system's connect timeout
+func (b *ClientBuilder) WithDialTimeout(dialTimeout time.Duration) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.dialTimeout = dialTimeout
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithDialKeepAlive sets the interval between TCP keep-alive probes on open connections
+// and returns the ClientBuilder for method chaining
+// Valid range: 1 second to 300 seconds
+// If the value is invalid, a warning is logged and the default value is used
+func (b *ClientBuilder) WithDialKeepAlive(dialKeepAlive time.Duration) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.dialKeepAlive = dialKeepAlive
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithConnMaxLifetime sets the maximum age of a connection, after which it is
+// closed and a new one is dialed
+// and returns the ClientBuilder for method chaining
+// Long-lived keep-alive connections stay pinned to the same backend behind an
+// L4 load balancer, reconnecting periodically lets the load be rebalanced.
+// The age is checked when a request is about to be written, so a connection
+// is only replaced on its first use after expiring. The request then fails
+// with a connection error before being sent, and is retried on a new connection
+// The value must not be negative, 0 (the default) reuses connections for as
+// long as they stay open
+// If the value is invalid, a warning is logged and the default value is used
+func (b *ClientBuilder) WithConnMaxLifetime(connMaxLifetime time.Duration) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.connMaxLifetime = connMaxLifetime
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithForceAttemptHTTP2 sets whether HTTP/2 is attempted
+// and returns the ClientBuilder for method chaining
+// net/http only enables HTTP/2 automatically for transports without a custom
+// dialer or TLS config, so this is needed for HTTP/2 to be used by the builder's transport
+// Default: true, matching http.DefaultTransport
+func (b *ClientBuilder) WithForceAttemptHTTP2(forceAttemptHTTP2 bool) *ClientBuilder {
+	b.client.forceAttemptHTTP2 = forceAttemptHTTP2
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithFreshConnOnError sets whether idle connections are dropped after a connection error
+// and returns the ClientBuilder for method chaining
+// When an attempt fails because its connection broke, e.g. reset by the peer or
+// closed by the server while idle, the idle connections of the underlying
+// transport are closed before retrying, so the retry dials a fresh connection
+// instead of picking another dead one from the pool. Retries caused by a status
+// code keep the pool as is
+// Default: true
+func (b *ClientBuilder) WithFreshConnOnError(freshConnOnError bool) *ClientBuilder {
+	b.client.freshConnOnError = freshConnOnError
+	return b
+}
+
+// WithDisableHTTP2 sets whether HTTP/2 is disabled
+// and returns the ClientBuilder for method chaining
+// When true, only HTTP/1.1 is used, regardless of WithForceAttemptHTTP2
+// This setting is useful for servers that misbehave under HTTP/2
+func (b *ClientBuilder) WithDisableHTTP2(disableHTTP2 bool) *ClientBuilder {
+	b.client.disableHTTP2 = disableHTTP2
+	b.client.transportSettingsSet = true
 	return b
 }
 
@@ -191,6 +716,7 @@ func (b *ClientBuilder) WithExpectContinueTimeout(expectContinueTimeout time.Dur
 // after a request is completed
 func (b *ClientBuilder) WithDisableKeepAlives(disableKeepAlives bool) *ClientBuilder {
 	b.client.disableKeepAlives = disableKeepAlives
+	b.client.transportSettingsSet = true
 	return b
 }
 
@@ -202,12 +728,170 @@ func (b *ClientBuilder) WithDisableKeepAlives(disableKeepAlives bool) *ClientBui
 func (b *ClientBuilder) WithMaxIdleConnsPerHost(maxIdleConnsPerHost int) *ClientBuilder {
 	// Just set the value, Build will validate/default
 	b.client.maxIdleConnsPerHost = maxIdleConnsPerHost
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithMaxConnsPerHost sets the maximum number of connections per host, including
+// connections in the dialing, active, and idle states
+// and returns the ClientBuilder for method chaining
+// Unlike WithMaxIdleConnsPerHost, this limits the total number of concurrent
+// connections, so requests beyond the limit wait for a connection to be available
+// The value must be 0 (no limit) or between ValidMinMaxConnsPerHost and ValidMaxMaxConnsPerHost
+// If the value is invalid, a warning is logged and the default value is used
+func (b *ClientBuilder) WithMaxConnsPerHost(maxConnsPerHost int) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.maxConnsPerHost = maxConnsPerHost
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithWriteBufferSize sets the size of the buffer used when writing to connections
+// and returns the ClientBuilder for method chaining
+// Larger buffers save syscalls when sending large payloads, at the cost of
+// memory for each connection
+// The value must be 0 (net/http's default, 4 KiB) or between ValidMinWriteBufferSize and ValidMaxWriteBufferSize
+// If the value is invalid, a warning is logged and the default value is used
+func (b *ClientBuilder) WithWriteBufferSize(writeBufferSize int) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.writeBufferSize = writeBufferSize
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithReadBufferSize sets the size of the buffer used when reading from connections
+// and returns the ClientBuilder for method chaining
+// Larger buffers save syscalls when receiving large payloads, at the cost of
+// memory for each connection
+// The value must be 0 (net/http's default, 4 KiB) or between ValidMinReadBufferSize and ValidMaxReadBufferSize
+// If the value is invalid, a warning is logged and the default value is used
+func (b *ClientBuilder) WithReadBufferSize(readBufferSize int) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.readBufferSize = readBufferSize
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithMaxResponseHeaderBytes sets the limit on the size of the response headers
+// and returns the ClientBuilder for method chaining
+// It protects the client against broken or malicious servers sending huge
+// headers. A response exceeding it fails with an error that is not retried,
+// the server being at fault
+// The value must be 0 (net/http's default, 10 MiB) or between ValidMinResponseHeaderBytes and ValidMaxResponseHeaderBytes
+// If the value is invalid, a warning is logged and the default value is used
+func (b *ClientBuilder) WithMaxResponseHeaderBytes(maxResponseHeaderBytes int64) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.maxResponseHeaderBytes = maxResponseHeaderBytes
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithTLSConfig sets the TLS configuration used by the transport
+// and returns the ClientBuilder for method chaining
+// This allows setting the minimum TLS version, client certificates for mTLS,
+// custom root CAs or skipping certificate verification
+// If nil, the default TLS configuration of net/http is used
+func (b *ClientBuilder) WithTLSConfig(tlsConfig *tls.Config) *ClientBuilder {
+	b.client.tlsConfig = tlsConfig
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithMinTLSVersion sets the minimum TLS version accepted by the transport,
+// one of tls.VersionTLS10 to tls.VersionTLS13
+// and returns the ClientBuilder for method chaining
+// A TLS configuration set with WithTLSConfig keeps its own MinVersion if it
+// has one, this version only applies when its MinVersion is zero
+// If the value is invalid, a warning is logged and the default value (TLS 1.2) is used
+func (b *ClientBuilder) WithMinTLSVersion(version uint16) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.minTLSVersion = version
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithClientCertificate sets the files of the certificate the client presents
+// to servers requiring mutual TLS (mTLS), a PEM encoded certificate chain and
+// its private key
+// and returns the ClientBuilder for method chaining
+// The files are loaded by Build, and added to the TLS configuration set with
+// WithTLSConfig, if any, without modifying it
+// If they can't be loaded, a warning is logged and no client certificate is used
+func (b *ClientBuilder) WithClientCertificate(certFile, keyFile string) *ClientBuilder {
+	// Just set the value, Build will validate/load
+	b.client.clientCertFile = certFile
+	b.client.clientKeyFile = keyFile
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithRootCAFile sets the file of the PEM encoded certificates of the
+// authorities trusted to sign server certificates, in place of the system ones
+// and returns the ClientBuilder for method chaining
+// The file is loaded by Build, and its CAs replace the root CAs of the TLS
+// configuration set with WithTLSConfig, if any, without modifying it
+// If it can't be loaded, a warning is logged and the system root CAs are used
+func (b *ClientBuilder) WithRootCAFile(caFile string) *ClientBuilder {
+	// Just set the value, Build will validate/load
+	b.client.rootCAFile = caFile
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithProxy sets the function returning the proxy to use for a given request
+// and returns the ClientBuilder for method chaining
+// See http.Transport.Proxy for its semantics, e.g. http.ProxyFromEnvironment
+// If nil, no proxy is used. It replaces any proxy set by WithProxyURL
+func (b *ClientBuilder) WithProxy(proxy func(*http.Request) (*url.URL, error)) *ClientBuilder {
+	b.client.proxy = proxy
+	b.client.proxyURL = ""
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithProxyURL sets the URL of the proxy used for all requests
+// and returns the ClientBuilder for method chaining
+// The URL must be absolute, e.g. "http://proxy.example.com:3128"
+// If the URL cannot be parsed, a warning is logged and no proxy is used
+// It replaces any proxy set by WithProxy
+func (b *ClientBuilder) WithProxyURL(proxyURL string) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.proxyURL = proxyURL
+	b.client.proxy = nil
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithUnixSocket makes the transport dial the Unix domain socket at path for
+// every request, whatever its host
+// and returns the ClientBuilder for method chaining
+// Requests keep a normal URL, whose host is only sent in the Host header,
+// e.g. "http://localhost/status" to get /status from the service listening
+// on the socket. Retries work as over TCP
+// A Unix socket can't be used with a proxy: if one is set with WithProxy or
+// WithProxyURL, a warning is logged and no proxy is used
+func (b *ClientBuilder) WithUnixSocket(path string) *ClientBuilder {
+	b.client.unixSocket = path
+	b.client.transportSettingsSet = true
+	return b
+}
+
+// WithBaseTransport sets the transport the retry logic wraps
+// and returns the ClientBuilder for method chaining
+// This allows a pre-configured transport (e.g. with custom TLS, a proxy or
+// instrumentation) to be used instead of the http.Transport built from the
+// builder's settings. The transport settings (idle connections, timeouts,
+// keep-alives) are then ignored, and a warning is logged if any of them is set
+// If nil, Build creates an http.Transport as usual
+func (b *ClientBuilder) WithBaseTransport(transport http.RoundTripper) *ClientBuilder {
+	b.client.baseTransport = transport
 	return b
 }
 
 // WithTimeout sets the timeout for HTTP requests
 // and returns the ClientBuilder for method chaining
-// The timeout must be between ValidMinTimeout and ValidMaxTimeout
+// The timeout must be between ValidMinTimeout and ValidMaxTimeout, unless
+// the range is changed with WithValidationRanges
 // If the timeout is invalid, a warning is logged and the default value is used
 func (b *ClientBuilder) WithTimeout(timeout time.Duration) *ClientBuilder {
 	// Just set the value, Build will validate/default
@@ -215,9 +899,55 @@ func (b *ClientBuilder) WithTimeout(timeout time.Duration) *ClientBuilder {
 	return b
 }
 
+// WithCookieJar sets the cookie jar of the HTTP client
+// and returns the ClientBuilder for method chaining
+// Cookies are stored and sent across requests and their retries. Cookies set by
+// the response of a failed attempt persist into the following attempts,
+// which is usually what servers issuing session cookies expect
+// If nil, cookies are ignored
+func (b *ClientBuilder) WithCookieJar(jar http.CookieJar) *ClientBuilder {
+	b.client.cookieJar = jar
+	return b
+}
+
+// WithDefaultCookieJar sets an in-memory cookie jar on the HTTP client
+// and returns the ClientBuilder for method chaining
+// It is equivalent to WithCookieJar with a jar created by cookiejar.New(nil)
+func (b *ClientBuilder) WithDefaultCookieJar() *ClientBuilder {
+	// cookiejar.New never fails without options
+	jar, _ := cookiejar.New(nil)
+	return b.WithCookieJar(jar)
+}
+
+// WithCheckRedirect sets the redirect policy of the HTTP client
+// and returns the ClientBuilder for method chaining
+// See http.Client.CheckRedirect for its semantics. Every redirected request
+// goes through the retry transport like the original one
+// If nil, the default policy of following up to 10 redirects is used
+// It replaces any policy set by WithMaxRedirects
+func (b *ClientBuilder) WithCheckRedirect(checkRedirect func(req *http.Request, via []*http.Request) error) *ClientBuilder {
+	b.client.checkRedirect = checkRedirect
+	b.client.maxRedirectsSet = false
+	return b
+}
+
+// WithMaxRedirects sets the maximum number of redirects followed by the HTTP client
+// and returns the ClientBuilder for method chaining
+// With 0, redirects are not followed and the 3xx response is returned as is
+// If the value is negative, a warning is logged and the default policy is used
+// It replaces any policy set by WithCheckRedirect
+func (b *ClientBuilder) WithMaxRedirects(maxRedirects int) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.maxRedirects = maxRedirects
+	b.client.maxRedirectsSet = true
+	b.client.checkRedirect = nil
+	return b
+}
+
 // WithMaxRetries sets the maximum number of retry attempts
 // and returns the ClientBuilder for method chaining
 // The maximum number of retries must be between ValidMinRetries and ValidMaxRetries
+// With 0, every request is sent once, without retries
 // If the maximum number of retries is invalid, a warning is logged and the default value is used
 // This setting is useful for controlling the number of retry attempts
 // The maximum number of retries is the maximum number of times
@@ -225,6 +955,344 @@ func (b *ClientBuilder) WithTimeout(timeout time.Duration) *ClientBuilder {
 func (b *ClientBuilder) WithMaxRetries(maxRetries int) *ClientBuilder {
 	// Just set the value, Build will validate/default
 	b.client.maxRetries = maxRetries
+	b.client.maxRetriesSet = true
+	return b
+}
+
+// WithMaxAttempts sets the total number of attempts, including the first one
+// and returns the ClientBuilder for method chaining
+// It is an alternative to WithMaxRetries that avoids the retries vs attempts
+// ambiguity: WithMaxAttempts(n) is equivalent to WithMaxRetries(n-1), and
+// WithMaxAttempts(1) sends a single request without retries
+// The maximum number of attempts must be between ValidMinAttempts and ValidMaxAttempts
+// Only one of WithMaxRetries and WithMaxAttempts should be used. If both are,
+// a warning is logged and the max attempts take precedence
+// If the value is invalid, a warning is logged and the default value is used
+func (b *ClientBuilder) WithMaxAttempts(maxAttempts int) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.maxAttempts = maxAttempts
+	b.client.maxAttemptsSet = true
+	return b
+}
+
+// WithMaxElapsedTime sets the total time budget across all attempts and backoff delays
+// and returns the ClientBuilder for method chaining
+// Once the budget is spent, no further retries are made and the last error is
+// returned, even if retries remain. A retry whose backoff delay would not fit in
+// the remaining budget is skipped, see WithMinAttemptBudget. This gives a hard
+// ceiling independent of the number of retries, on top of the overall client timeout
+// A value of 0 means no budget. If the value is negative, a warning is logged and the default value is used
+func (b *ClientBuilder) WithMaxElapsedTime(maxElapsedTime time.Duration) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.maxElapsedTime = maxElapsedTime
+	return b
+}
+
+// WithMinAttemptBudget sets the least time an attempt needs before the deadline
+// and returns the ClientBuilder for method chaining
+// The deadline is the earliest of the request context deadline, which includes
+// the client timeout, and the end of the WithMaxElapsedTime budget. When the
+// backoff delay plus this budget would run past it, the retry is skipped and the
+// last error is returned right away instead of sleeping and then timing out
+// If the value is negative, a warning is logged and the default value is used
+func (b *ClientBuilder) WithMinAttemptBudget(minAttemptBudget time.Duration) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.minAttemptBudget = minAttemptBudget
+	return b
+}
+
+// WithRetryEvents sets a channel that receives a RetryEvent for every retry
+// and returns the ClientBuilder for method chaining
+// Events are sent without blocking: if the channel is full, the event is dropped
+// so that a slow consumer never delays requests. Use a buffered channel sized
+// for the expected burst of retries to avoid losing events
+// It complements WithOnRetry for consumers processing events asynchronously
+func (b *ClientBuilder) WithRetryEvents(events chan<- RetryEvent) *ClientBuilder {
+	b.client.retryEvents = events
+	return b
+}
+
+// WithRetryEventChannel sets a channel that receives a RetryEvent for every retry
+// and returns the ClientBuilder for method chaining
+//
+// Deprecated: use WithRetryEvents, which it is an alias of
+func (b *ClientBuilder) WithRetryEventChannel(events chan<- RetryEvent) *ClientBuilder {
+	return b.WithRetryEvents(events)
+}
+
+// WithOnRetry adds a callback called with a RetryEvent for every retry
+// and returns the ClientBuilder for method chaining
+// The callback runs synchronously, before the backoff delay, so it should
+// return quickly. Callbacks added by repeated calls are all called, in order,
+// which lets integrations such as metrics observe retries side by side
+func (b *ClientBuilder) WithOnRetry(onRetry func(RetryEvent)) *ClientBuilder {
+	if onRetry != nil {
+		b.client.onRetry = append(b.client.onRetry, onRetry)
+	}
+	return b
+}
+
+// WithOnRequestDone adds a callback called with the Stats of every request
+// once its attempts are over, whatever the outcome,
+// and returns the ClientBuilder for method chaining
+// Each request sent by the client is reported on its own, redirects included
+// Callbacks added by repeated calls are all called, in order
+func (b *ClientBuilder) WithOnRequestDone(onRequestDone func(Stats)) *ClientBuilder {
+	if onRequestDone != nil {
+		b.client.onRequestDone = append(b.client.onRequestDone, onRequestDone)
+	}
+	return b
+}
+
+// WithAttemptMiddleware adds a middleware wrapping the transport that sends each attempt
+// and returns the ClientBuilder for method chaining
+// The middleware sees every attempt, retries and hedges included, and
+// AttemptInfoFromContext gives the attempt index and preceding backoff delay
+// from the attempt's request. Middlewares added by repeated calls are nested,
+// the first one being the outermost
+func (b *ClientBuilder) WithAttemptMiddleware(middleware Middleware) *ClientBuilder {
+	if middleware != nil {
+		b.client.attemptMiddlewares = append(b.client.attemptMiddlewares, middleware)
+	}
+	return b
+}
+
+// WithRequestMiddleware adds a middleware wrapping the retry logic as a whole
+// and returns the ClientBuilder for method chaining
+// The middleware sees each request once, before its first attempt, and its
+// final outcome once the retries are over. Middlewares added by repeated calls
+// are nested, the first one being the outermost
+func (b *ClientBuilder) WithRequestMiddleware(middleware Middleware) *ClientBuilder {
+	if middleware != nil {
+		b.client.requestMiddlewares = append(b.client.requestMiddlewares, middleware)
+	}
+	return b
+}
+
+// WithAutoBufferBody sets whether request bodies without GetBody are buffered for retries
+// and returns the ClientBuilder for method chaining
+// Requests built with a plain io.Reader body have no GetBody, so retries would
+// otherwise send an empty body. When enabled, such bodies are read into memory
+// once, up to the limit set by WithMaxBufferSize, and replayed on every attempt
+// Bodies implementing io.Seeker, such as an *os.File, are rewound instead of
+// buffered whether or not this is enabled. The precedence is GetBody, then
+// seeking, then buffering
+func (b *ClientBuilder) WithAutoBufferBody(autoBufferBody bool) *ClientBuilder {
+	b.client.autoBufferBody = autoBufferBody
+	return b
+}
+
+// WithMaxBufferSize sets the maximum size in bytes of a request body buffered for retries
+// and returns the ClientBuilder for method chaining
+// Bodies larger than this are sent once without buffering and are not retried
+// If the value is invalid, a warning is logged and the default value is used
+func (b *ClientBuilder) WithMaxBufferSize(maxBufferSize int64) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.maxBufferSize = maxBufferSize
+	return b
+}
+
+// WithPreset applies the settings of a preset such as PresetProduction or PresetDevelopment
+// and returns the ClientBuilder for method chaining
+// Settings made afterward with individual With... calls override the preset's.
+// Preset values are validated by Build like any other value
+func (b *ClientBuilder) WithPreset(preset Preset) *ClientBuilder {
+	b.client.maxRetries = preset.MaxRetries
+	b.client.maxRetriesSet, b.client.maxAttemptsSet = false, false
+	b.client.retryStrategyType = preset.Strategy
+	b.client.retryBaseDelay = preset.BaseDelay
+	b.client.retryMaxDelay = preset.MaxDelay
+	b.client.timeout = preset.Timeout
+	if preset.Logger != nil {
+		b.client.logger = preset.Logger
+	}
+	return b
+}
+
+// WithLogger sets the logger receiving the retry log messages
+// and returns the ClientBuilder for method chaining
+// Each retry is logged at info level and each attempt at debug level.
+// If nil, slog.Default() is used
+func (b *ClientBuilder) WithLogger(logger *slog.Logger) *ClientBuilder {
+	b.client.logger = logger
+	return b
+}
+
+// WithClock sets the clock used to measure elapsed time and to sleep between attempts
+// and returns the ClientBuilder for method chaining
+// It is meant for tests, which can pass a httpretriertest.ManualClock to drive
+// backoff delays without real sleeps. If nil, the real clock is used
+func (b *ClientBuilder) WithClock(clock Clock) *ClientBuilder {
+	b.client.clock = clock
+	return b
+}
+
+// WithMaxTotalBufferBytes sets the total number of bytes that may be buffered at once
+// across all in-flight requests of the client by WithAutoBufferBody
+// and returns the ClientBuilder for method chaining
+// When buffering a new body would exceed the budget, the body is sent once without
+// buffering, unless WithWaitForBufferBudget is enabled. Use BufferedBytes to observe
+// the current usage
+// A value of 0 means no budget. If the value is negative, a warning is logged and the default value is used
+func (b *ClientBuilder) WithMaxTotalBufferBytes(maxTotalBufferBytes int64) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.maxTotalBufferBytes = maxTotalBufferBytes
+	return b
+}
+
+// WithWaitForBufferBudget sets whether requests wait for room in the buffer budget
+// and returns the ClientBuilder for method chaining
+// When enabled, a request whose body doesn't fit in the budget set by
+// WithMaxTotalBufferBytes blocks until other requests complete or its context is
+// done, instead of skipping buffering
+func (b *ClientBuilder) WithWaitForBufferBudget(wait bool) *ClientBuilder {
+	b.client.waitForBufferBudget = wait
+	return b
+}
+
+// WithOnBufferTruncated sets a hook called when a request body is too large to buffer
+// and returns the ClientBuilder for method chaining
+// Such a request is attempted only once, since its body can't be replayed. The hook
+// receives the body's ContentLength (0 or -1 when unknown), making the skipped
+// retries observable instead of silent
+func (b *ClientBuilder) WithOnBufferTruncated(hook func(size int64)) *ClientBuilder {
+	b.client.onBufferTruncated = hook
+	return b
+}
+
+// WithDefaultHeaders sets headers added to every request, such as an API
+// version or a client id
+// and returns the ClientBuilder for method chaining
+// The headers are added to the clone sent by each attempt; the caller's request
+// is left untouched. The caller's headers take precedence: for a header the
+// caller already set, its values come first and only the default values it
+// doesn't include are appended, so Header.Get on the server side returns the
+// caller's value. Headers the caller didn't set get all their default values
+// A later call replaces the headers of the previous one
+func (b *ClientBuilder) WithDefaultHeaders(headers http.Header) *ClientBuilder {
+	b.client.defaultHeaders = canonicalHeaders(headers)
+	return b
+}
+
+// WithUserAgent sets the User-Agent header sent with the requests instead of Go's default
+// and returns the ClientBuilder for method chaining
+// The header is set on the clone sent by each attempt, unless the request
+// already has one, set by the caller or by WithDefaultHeaders
+// An empty value keeps Go's default User-Agent
+func (b *ClientBuilder) WithUserAgent(userAgent string) *ClientBuilder {
+	b.client.userAgent = userAgent
+	return b
+}
+
+// WithAttemptHeader sets the name of the header carrying the attempt number on outgoing requests
+// and returns the ClientBuilder for method chaining
+// Every attempt's request carries the header, with 0 on the first try and N on
+// the N-th retry, so the server can deduplicate or log retries. The header is
+// set on a clone; the caller's request is left untouched
+// An empty name disables the header. The default is DefaultAttemptHeader
+func (b *ClientBuilder) WithAttemptHeader(name string) *ClientBuilder {
+	b.client.attemptHeader = name
+	return b
+}
+
+// WithIdempotencyKey sets the name of the header carrying an idempotency key on non-idempotent requests
+// and returns the ClientBuilder for method chaining
+// A random UUID is generated once per request with a non-idempotent method,
+// such as POST or PATCH, and sent in the header with every attempt, so the
+// server can recognize retries and apply the request only once. This makes
+// retrying such requests safe with servers supporting idempotency keys. Requests
+// already carrying the header keep their own key. The header is set on a clone;
+// the caller's request is left untouched
+// An empty name uses DefaultIdempotencyKeyHeader (Idempotency-Key). No key is sent by default
+func (b *ClientBuilder) WithIdempotencyKey(headerName string) *ClientBuilder {
+	if headerName == "" {
+		headerName = DefaultIdempotencyKeyHeader
+	}
+	b.client.idempotencyKeyHeader = headerName
+	return b
+}
+
+// WithAuthRefresh sets the function refreshing the credentials when a response is 401 Unauthorized
+// and returns the ClientBuilder for method chaining
+// On a 401 response, the function is called and the attempt is sent again right
+// away, without backoff, with the value it returns in the auth header (see
+// WithAuthHeader), e.g. "Bearer <token>". The credentials are then kept for the
+// following requests. Refreshes are capped per attempt, so a server rejecting
+// fresh credentials gets its 401 response returned. If the refresh fails, the
+// request fails with an error wrapping ErrAuthRefresh and the refresh error
+// Without this option, 401 responses are returned as is
+func (b *ClientBuilder) WithAuthRefresh(refresh func(ctx context.Context) (string, error)) *ClientBuilder {
+	b.client.authRefresh = refresh
+	return b
+}
+
+// WithAuthHeader sets the header carrying the credentials returned by the WithAuthRefresh function
+// and returns the ClientBuilder for method chaining
+// An empty name uses the default, DefaultAuthHeader (Authorization)
+func (b *ClientBuilder) WithAuthHeader(name string) *ClientBuilder {
+	if name == "" {
+		name = DefaultAuthHeader
+	}
+	b.client.authHeader = name
+	return b
+}
+
+// WithReturnLastResponse sets whether the last failed response is returned once the retries are over
+// and returns the ClientBuilder for method chaining
+// By default, a request whose retries are exhausted fails with a RetryError. When
+// enabled and the last attempt got a response (e.g. a 503), that response is
+// returned instead, with a nil error, so its status, headers and body can be
+// inspected. The caller must close its body as usual
+func (b *ClientBuilder) WithReturnLastResponse(returnLastResponse bool) *ClientBuilder {
+	b.client.returnLastResponse = returnLastResponse
+	return b
+}
+
+// WithRetryCondition sets the function deciding whether an attempt is retried
+// and returns the ClientBuilder for method chaining
+// It replaces the default decision, which retries 5xx responses and transient
+// transport errors such as timeouts and refused or reset connections, but not
+// cancellations, malformed requests or TLS certificate verification failures
+// If nil, the default decision is used
+func (b *ClientBuilder) WithRetryCondition(condition RetryCondition) *ClientBuilder {
+	b.client.retryCondition = condition
+	return b
+}
+
+// WithMaxDrainSize sets the maximum number of bytes read from a failed attempt's response body
+// and returns the ClientBuilder for method chaining
+// Draining lets the connection be reused for the retry; bodies longer than this
+// are closed instead, so a huge or slowly streamed error body never stalls retries
+// If the value is invalid, a warning is logged and the default value is used
+func (b *ClientBuilder) WithMaxDrainSize(maxDrainSize int64) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.maxDrainSize = maxDrainSize
+	return b
+}
+
+// WithDrainBufferSize sets the size of the buffers used to drain failed attempts' response bodies
+// and returns the ClientBuilder for method chaining
+// The buffers are pooled and reused across requests, so retries don't allocate
+// one each time. Draining reads at most WithMaxDrainSize bytes, so a buffer of
+// that size drains a body in a single read
+// The value must be between ValidMinDrainBufferSize and ValidMaxDrainBufferSize
+// If the value is invalid, a warning is logged and the default value is used
+func (b *ClientBuilder) WithDrainBufferSize(size int) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.drainBufferSize = size
+	return b
+}
+
+// WithPerAttemptTimeout sets the timeout for each individual attempt
+// and returns the ClientBuilder for method chaining
+// Unlike WithTimeout, which bounds the whole operation including all retries,
+// this gives every attempt its own deadline, so a hung connection fails fast
+// and the request is retried. The deadline also covers reading the response body
+// The overall client timeout and max elapsed time still apply on top
+// A value of 0 means no per-attempt timeout. If the value is negative, a warning is logged and the default value is used
+func (b *ClientBuilder) WithPerAttemptTimeout(perAttemptTimeout time.Duration) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.perAttemptTimeout = perAttemptTimeout
 	return b
 }
 
@@ -238,18 +1306,100 @@ func (b *ClientBuilder) WithRetryBaseDelay(baseDelay time.Duration) *ClientBuild
 
 // WithRetryMaxDelay sets the maximum delay for retry strategies like ExponentialBackoff and JitterBackoff.
 // This value is ignored by FixedDelay.
+// With NoMaxDelay (0), the delays keep growing with every attempt, e.g. for
+// background jobs backing off for minutes or hours; they are then only bounded
+// by the largest time.Duration, so use WithMaxElapsedTime or the request's
+// context to bound the whole operation.
+// Otherwise the max delay must be between ValidMinMaxDelay and ValidMaxMaxDelay,
+// unless the range is changed with WithValidationRanges
 func (b *ClientBuilder) WithRetryMaxDelay(maxDelay time.Duration) *ClientBuilder {
 	// Just set the value, Build will validate/default
 	b.client.retryMaxDelay = maxDelay
 	return b
 }
 
+// WithMinDelay sets a floor for the delays of every strategy, including a
+// custom RetryStrategy and the connect timeout backoff
+// and returns the ClientBuilder for method chaining
+// Delays computed below the floor are raised to it, e.g. to guard against a
+// misconfigured tiny base delay. The floor must not exceed the max delay
+// If the floor is negative, a warning is logged and no floor is used
+// If it is above the max delay, a warning is logged and the two are swapped
+func (b *ClientBuilder) WithMinDelay(minDelay time.Duration) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.minDelay = minDelay
+	return b
+}
+
+// WithRetryMultiplier sets the growth factor of the exponential, jitter,
+// full-jitter and equal-jitter strategies
+// and returns the ClientBuilder for method chaining
+// Each delay is base * multiplier^attempt, capped at the max delay
+// The multiplier must be between ValidMinRetryMultiplier and ValidMaxRetryMultiplier
+// If the multiplier is invalid, a warning is logged and the default value (2) is used
+func (b *ClientBuilder) WithRetryMultiplier(multiplier float64) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.retryMultiplier = multiplier
+	return b
+}
+
+// WithStrictMaxDelay sets whether the max delay also bounds the jitter
+// and returns the ClientBuilder for method chaining
+// By default, JitterBackoffStrategy adds up to half the exponential delay on
+// top of it, so a delay can exceed the max delay by up to 50%. When enabled, every
+// delay is clamped to the max delay, jitter included, so the cap holds strictly
+// at the cost of less jitter for delays near it
+// It has no effect without a max delay (NoMaxDelay)
+func (b *ClientBuilder) WithStrictMaxDelay(strict bool) *ClientBuilder {
+	b.client.strictMaxDelay = strict
+	return b
+}
+
+// WithMaxJitter sets an absolute bound on the random jitter of the jitter strategies
+// and returns the ClientBuilder for method chaining
+// JitterBackoffStrategy adds, and EqualJitterStrategy takes off, a random
+// share of up to half the exponential delay, which grows with it. With a
+// bound, that share is at most min(maxJitter, delay/2), keeping the tail
+// latency predictable at large delays
+// A value of 0 means no bound, which is the default. If the value is negative,
+// a warning is logged and the default value is used
+func (b *ClientBuilder) WithMaxJitter(maxJitter time.Duration) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.maxJitter = maxJitter
+	return b
+}
+
+// WithFixedJitter sets the jitter fraction of the fixed delay strategy
+// and returns the ClientBuilder for method chaining
+// Each delay is the base delay plus a random jitter of up to base * fraction,
+// spreading out the retries of clients failing at the same time
+// The fraction must be between ValidMinFixedJitter and ValidMaxFixedJitter, 0 disables jitter
+// If the fraction is invalid, a warning is logged and the default value (0) is used
+func (b *ClientBuilder) WithFixedJitter(fraction float64) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.fixedJitter = fraction
+	return b
+}
+
+// WithConnectTimeoutBackoff sets the strategy used after an attempt timed out
+// while connecting to the host
+// and returns the ClientBuilder for method chaining
+// Connect timeouts (the host can't be reached) often warrant backing off
+// harder than read timeouts (the host is reachable but slow), which keep
+// using the general retry strategy
+// If the strategy is nil, the general retry strategy is used for every error
+func (b *ClientBuilder) WithConnectTimeoutBackoff(strategy RetryStrategy) *ClientBuilder {
+	b.client.connectTimeoutBackoff = strategy
+	return b
+}
+
 // WithRetryStrategy sets the retry strategy for the client
 // and returns the ClientBuilder for method chaining
 // The retry strategy determines how the client will handle
 // retrying failed requests
 // The retry strategy can be one of the following:
-// "fixed", "jitter", or "exponential"
+// "fixed", "jitter", "exponential", "full-jitter", "equal-jitter", "decorrelated",
+// or a strategy registered with RegisterStrategy
 // If the retry strategy is invalid, a warning is logged and the default value is used
 // This setting is useful for controlling the retry behavior
 // The retry strategy is the strategy used to determine the delay
@@ -276,99 +1426,611 @@ func (b *ClientBuilder) WithRetryStrategyAsString(retryStrategy string) *ClientB
 	return b
 }
 
+// WithRequestSeededJitter sets a function that derives a seed from each request
+// and returns the ClientBuilder for method chaining
+// When set, the jitter strategy uses a random number generator seeded with
+// that value for the request, so replaying the same request (e.g. same URL and
+// request ID header) yields the same backoff sequence.
+// If the function is nil, the normal random source is used
+// This setting only affects the jitter strategy
+func (b *ClientBuilder) WithRequestSeededJitter(seedFunc func(req *http.Request) int64) *ClientBuilder {
+	b.client.requestSeed = seedFunc
+	return b
+}
+
+// WithRandomizedMaxDelay draws the max delay for each request from a random
+// distribution around mean instead of using the fixed max delay
+// and returns the ClientBuilder for method chaining
+// This de-correlates the backoff caps of concurrent requests, which is useful
+// for studying herd behavior. The distribution defaults to ExponentialDistribution
+// and can be changed with WithMaxDelayDistribution. Draws use the jitter seed when set
+// The drawn value is never below the base delay nor above the max delay range
+// The mean must be within the max delay range, ValidMinMaxDelay to ValidMaxMaxDelay by default
+// If the mean is invalid, a warning is logged and the fixed max delay is used
+func (b *ClientBuilder) WithRandomizedMaxDelay(mean time.Duration) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.randomMaxDelayMean = mean
+	return b
+}
+
+// WithRetryBudget limits the retries of the client to a share of its requests
+// and returns the ClientBuilder for method chaining
+// Every request deposits ratio retries in a budget shared by all requests of
+// the client, and every retry takes one out. Once the budget is spent, failed
+// attempts are not retried and the last error is returned, even if the request
+// has retries left. On top of the ratio, minRetriesPerSec retries per second
+// are always allowed so that clients with little traffic can still retry
+// The counts decay exponentially with a 10s time constant, so the budget
+// follows the traffic of the last few seconds: with a ratio of 0.1, retries
+// stay around 10% of the requests, as recommended by Google's SRE book, and a
+// failing downstream doesn't get three or four times its normal load
+// The ratio must be between 0 and 1 and minRetriesPerSec must not be negative.
+// If either is invalid, a warning is logged and no retry budget is applied
+func (b *ClientBuilder) WithRetryBudget(ratio float64, minRetriesPerSec float64) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.retryBudgetRatio = ratio
+	b.client.retryBudgetMinPerSec = minRetriesPerSec
+	b.client.retryBudgetSet = true
+	return b
+}
+
+// WithMaxDelayDistribution sets the distribution used by WithRandomizedMaxDelay
+// and returns the ClientBuilder for method chaining
+// If the distribution is nil, ExponentialDistribution is used
+func (b *ClientBuilder) WithMaxDelayDistribution(distribution DelayDistribution) *ClientBuilder {
+	b.client.maxDelayDistribution = distribution
+	return b
+}
+
+// WithRateLimit caps the rate of requests sent by the client to rps per second,
+// allowing bursts of up to burst requests
+// and returns the ClientBuilder for method chaining
+// The limit is shared by all requests made through the client and counts every
+// attempt, not just the initial request, so retries consume the same budget.
+// A request waiting for its turn gives up when its context is done
+// If rps is not positive, a warning is logged and no rate limit is applied
+// If burst is less than 1, a warning is logged and a burst of 1 is used
+func (b *ClientBuilder) WithRateLimit(rps float64, burst int) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.rateLimit = rps
+	b.client.rateLimitBurst = burst
+	b.client.rateLimitSet = true
+	return b
+}
+
+// WithFallbackHosts sets the hosts to fall back on when the retries fail
+// and returns the ClientBuilder for method chaining
+// Once the retries against the request's host are used up, the request is sent
+// to each fallback host in order, with the full retry strategy, keeping the
+// scheme, path and query. The first host that works answers the request,
+// otherwise a FallbackError lists the error of every host tried
+// Hosts are given as host or host:port, e.g. "backup.example.com:8443"
+// Invalid hosts are dropped with a warning. Fallbacks are off by default
+func (b *ClientBuilder) WithFallbackHosts(hosts []string) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.fallbackHosts = slices.Clone(hosts)
+	return b
+}
+
+// WithHedging enables request hedging for idempotent requests
+// and returns the ClientBuilder for method chaining
+// When an attempt hasn't answered after delay, a speculative copy of it is sent,
+// up to maxHedges copies spaced by delay. The first response that doesn't
+// warrant a retry is returned and the other copies are canceled
+// Only requests with an idempotent method (GET, HEAD, OPTIONS, TRACE, PUT, DELETE)
+// and a replayable body are hedged. Every copy is a real request, so hedging
+// adds load on the server, up to maxHedges+1 times the request rate
+// If delay is not positive or maxHedges is less than 1, a warning is logged and hedging is disabled
+func (b *ClientBuilder) WithHedging(delay time.Duration, maxHedges int) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.hedgeDelay = delay
+	b.client.maxHedges = maxHedges
+	return b
+}
+
+// WithCircuitBreaker enables a circuit breaker for each host
+// and returns the ClientBuilder for method chaining
+// After failureThreshold consecutive failed attempts to a host (those that
+// would be retried, e.g. 5xx responses or connection errors), requests to it
+// fail fast with ErrCircuitOpen for openDuration. A single probe is then let
+// through: if it succeeds the breaker closes, otherwise it opens again
+// If failureThreshold is less than 1 or openDuration is not positive,
+// a warning is logged and no circuit breaker is used
+func (b *ClientBuilder) WithCircuitBreaker(failureThreshold int, openDuration time.Duration) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.breakerThreshold = failureThreshold
+	b.client.breakerOpenDuration = openDuration
+	b.client.breakerSet = true
+	return b
+}
+
+// WithAdaptiveBackoff makes the client remember the recent failures of each
+// host, so that the retries to a host that has just been failing wait longer
+// and returns the ClientBuilder for method chaining
+// The delays of a request's retries are scaled up by the failure rate of its
+// host when the request starts, up to 4 times for a host whose every recent
+// attempt failed, without going past the max delay. The rate decays as
+// attempts succeed and is forgotten after a run of successes, so a service
+// that just recovered isn't overloaded again by retries at full speed
+func (b *ClientBuilder) WithAdaptiveBackoff() *ClientBuilder {
+	b.client.adaptiveBackoff = true
+	return b
+}
+
+// WithMaxConcurrent sets the maximum number of requests the client has in flight
+// and returns the ClientBuilder for method chaining
+// A request holds its slot from the first attempt until the last one, including
+// the backoff delays in between, independently of the connection pool.
+// A request waiting for a slot gives up when its context is done
+// A value of 0 means unlimited. If the value is negative, a warning is logged and the default value is used
+func (b *ClientBuilder) WithMaxConcurrent(maxConcurrent int) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.maxConcurrent = maxConcurrent
+	return b
+}
+
+// WithMaxConcurrentPerHost sets the maximum number of requests allowed
+// to proceed concurrently to a single host
+// and returns the ClientBuilder for method chaining
+// The limit applies to every attempt, including retries, and throttles
+// request initiation rather than connections. A request waiting for a slot
+// gives up when its context is done
+// A response holds its slot until its body is closed, so bodies must always be closed
+// A value of 0 means unlimited. If the value is negative, a warning is logged and the default value is used
+func (b *ClientBuilder) WithMaxConcurrentPerHost(maxConcurrentPerHost int) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.maxConcurrentPerHost = maxConcurrentPerHost
+	return b
+}
+
+// WithRetryableStatusRange makes responses with a status in [min, max] retryable
+// and returns the ClientBuilder for method chaining
+// It overrides the default behavior: once a range is set, 5xx responses are only
+// retried if they fall within one of the ranges. Calling it again adds a range,
+// so WithRetryableStatusRange(400, 599) retries any client or server error
+// Retrying 4xx is usually wrong, since the same request fails the same way,
+// but it helps telling transient failures apart while debugging
+// If the range is not within [100, 599] or min is greater than max, a warning
+// is logged and the range is ignored
+func (b *ClientBuilder) WithRetryableStatusRange(min, max int) *ClientBuilder {
+	// Just add the range, Build will validate it
+	b.client.retryableStatuses = append(b.client.retryableStatuses, StatusRange{Min: min, Max: max})
+	return b
+}
+
+// WithNoRetryOnStatus makes responses with one of the given statuses never
+// retried, returning them right away
+// and returns the ClientBuilder for method chaining
+// It filters the retries decided by the other rules, the default 5xx rule,
+// WithRetryableStatusRange or WithRetryCondition alike: a status both within a
+// retryable range and excluded here is not retried. Calling it again adds
+// statuses, e.g. WithNoRetryOnStatus(501, 505) for the server errors meaning
+// the server will never support the request
+// Statuses outside [100, 599] are ignored, with a warning logged
+func (b *ClientBuilder) WithNoRetryOnStatus(statuses ...int) *ClientBuilder {
+	// Just add the statuses, Build will validate them
+	b.client.noRetryStatuses = append(b.client.noRetryStatuses, statuses...)
+	return b
+}
+
+// WithRetryIfMissingHeader makes responses without the named header retryable
+// and returns the ClientBuilder for method chaining
+// This composes with the status code checks: a response is retried if its
+// status is retryable or if the header is missing
+// Some gateways omit a header the backend always sets (like X-Trace-Id)
+// when they short-circuit with a cached error
+// An empty name disables the check, which is the default
+func (b *ClientBuilder) WithRetryIfMissingHeader(name string) *ClientBuilder {
+	b.client.requiredHeader = name
+	return b
+}
+
+// WithRespectRetryAfter sets whether the Retry-After header of retryable responses is honored
+// and returns the ClientBuilder for method chaining
+// When enabled, the wait a server asks for, in seconds or as an HTTP date,
+// replaces the delay of the retry strategy, still raised to WithMinDelay.
+// If the wait would not leave WithMinAttemptBudget before the request's
+// deadline or the WithMaxElapsedTime budget, the retry is skipped and the last
+// error is returned right away rather than after sleeping
+// Invalid headers are ignored. It is disabled by default
+func (b *ClientBuilder) WithRespectRetryAfter(respect bool) *ClientBuilder {
+	b.client.respectRetryAfter = respect
+	return b
+}
+
+// WithStatusDelay sets the delays to wait before retrying responses with the given statuses
+// and returns the ClientBuilder for method chaining
+// A delay set for a status replaces the delay of the retry strategy, still
+// raised to WithMinDelay, e.g. a fixed 30s wait for 429 while 5xx responses
+// keep the exponential backoff. The delay comes, by precedence, from the
+// Retry-After header when WithRespectRetryAfter honors it, then from these
+// delays, then from the strategy
+// It only sets delays, not which statuses are retried. Calling it again adds
+// delays, replacing those of the same statuses
+// Statuses outside [100, 599] and negative delays are ignored, with a warning logged
+func (b *ClientBuilder) WithStatusDelay(delays map[int]time.Duration) *ClientBuilder {
+	if b.client.statusDelays == nil {
+		b.client.statusDelays = make(map[int]time.Duration, len(delays))
+	}
+	// Just copy the delays, Build will validate them
+	maps.Copy(b.client.statusDelays, delays)
+	return b
+}
+
+// WithDryRun sets whether the delays between attempts are skipped
+// and returns the ClientBuilder for method chaining
+// In dry run mode the requests are still sent and retried as usual, but
+// instead of sleeping, the delay each retry would wait is logged and the retry
+// sent right away, so a retry configuration can be tried out quickly, e.g. in
+// a staging environment. As retries come back to back, this changes the
+// timing seen by the servers, and the time based limits such as
+// WithMaxElapsedTime are hardly reached, so it is not meant for production
+// It is disabled by default
+func (b *ClientBuilder) WithDryRun(dryRun bool) *ClientBuilder {
+	b.client.dryRun = dryRun
+	return b
+}
+
+// WithExpectedContentType makes 2xx responses with another Content-Type retryable
+// and returns the ClientBuilder for method chaining
+// Misconfigured gateways sometimes answer with an HTML error page and a 200
+// status; with e.g. "application/json" such responses are retried like a 5xx.
+// Parameters such as the charset are ignored, and "type/*" accepts any subtype
+// Only the header is checked, the body is left unread for the caller
+// An empty value disables the check, which is the default. If the value is not
+// a valid media type, a warning is logged and the check is disabled
+func (b *ClientBuilder) WithExpectedContentType(contentType string) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.expectedContentType = contentType
+	return b
+}
+
+// WithResponseValidator sets a function deciding from its body whether a response is a success
+// and returns the ClientBuilder for method chaining
+// Some APIs always answer 200 and report errors in the body, e.g. {"status":"error"}.
+// The body of every response that would otherwise be a success is read into
+// memory and passed to the validator; if it returns false, the attempt is
+// retried like a 5xx. The response is returned with the buffered body, so the
+// caller reads it as usual
+// Enabling this forces the buffering of every such response. Bodies larger than
+// the limit set by WithMaxValidateBodySize are not validated and are returned as is
+func (b *ClientBuilder) WithResponseValidator(validator func(resp *http.Response, body []byte) bool) *ClientBuilder {
+	b.client.responseValidator = validator
+	return b
+}
+
+// WithMaxValidateBodySize sets the maximum size of the response bodies passed to the validator
+// and returns the ClientBuilder for method chaining
+// Larger bodies are not validated, so huge responses are never held in memory
+// If the value is not positive, a warning is logged and the default value is used
+func (b *ClientBuilder) WithMaxValidateBodySize(maxValidateBodySize int64) *ClientBuilder {
+	// Just set the value, Build will validate/default
+	b.client.maxValidateBodySize = maxValidateBodySize
+	return b
+}
+
+// WithJitterSeed seeds the random source used by the jitter strategy
+// and returns the ClientBuilder for method chaining
+// With a fixed seed the sequence of jittered delays is reproducible,
+// which is useful in tests. Without it, the package-level random source is used
+func (b *ClientBuilder) WithJitterSeed(seed int64) *ClientBuilder {
+	b.client.jitterSeed = seed
+	b.client.jitterSeedSet = true
+	return b
+}
+
+// WithSignalAwareShutdown makes the client stop retrying once the process
+// receives one of the given signals and returns the ClientBuilder for method chaining
+// Requests with pending retries return ErrStopped instead of waiting for the
+// next attempt. If no signals are given, os.Interrupt and SIGTERM are used
+// The handler is registered once per built client and can be removed
+// with StopSignalHandling
+func (b *ClientBuilder) WithSignalAwareShutdown(signals ...os.Signal) *ClientBuilder {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	b.client.shutdownSignals = signals
+	return b
+}
+
 // Build creates and returns a new HTTP client with the specified settings
 // and retry strategy
 func (b *ClientBuilder) Build() *http.Client {
-	// validate the settings and set defaults if necessary
+	b.validate()
+	return b.build()
+}
+
+// BuildTransport creates and returns the retry transport alone, validating
+// the settings like Build, for use in an http.Client configured elsewhere.
+// The settings of the client itself are then up to that client: the timeout
+// (WithTimeout), cookie jar and redirect policy are not applied. The cookies
+// set during failed attempts still reach their retries if a jar is configured.
+// Shutdown, StopSignalHandling, BufferedBytes and DoWithStats work on a client
+// using the transport, even wrapped by RoundTrippers, provided these have an
+// Unwrap() http.RoundTripper method returning the transport they wrap.
+func (b *ClientBuilder) BuildTransport() http.RoundTripper {
+	b.validate()
+	return b.buildTransport()
+}
+
+// validate validates the settings, replacing the invalid ones with their
+// default value and recording a warning for each
+func (b *ClientBuilder) validate() {
+	b.warnings = nil
+	for _, v := range b.client.validate() {
+		slog.Warn(v.logMessage(), "invalidValue", v.value, "defaultValue", v.fallback)
+		b.warnings = append(b.warnings, v.warning())
+	}
+}
+
+// Warnings returns a message for each setting the last Build call replaced
+// because it was invalid, naming the setting, its invalid value and the value used instead
+// It is empty if all settings were valid or Build hasn't been called yet.
+// Each Build call starts a new list
+func (b *ClientBuilder) Warnings() []string {
+	return slices.Clone(b.warnings)
+}
+
+// Validate checks the configured settings without building a client
+// It runs the same checks as Build and BuildStrict and returns a *ClientError
+// listing every invalid setting, or nil if all of them are valid.
+// The builder is left unchanged, so it can be used in tests or to lint a
+// configuration before building from it
+func (b *ClientBuilder) Validate() error {
+	cfg := *b.client
+	return cfg.validate().err()
+}
+
+// String returns a one-line description of the settings a client built now
+// would use, see Client.String. Invalid settings are shown with the default
+// values Build would replace them with, without logging or changing the builder
+func (b *ClientBuilder) String() string {
+	cfg := *b.client
+	cfg.validate()
+	return cfg.String()
+}
+
+// BuildStrict creates a new http.Client with the configured settings
+// like Build, but returns an error instead of falling back to default values
+// The error is a *ClientError listing every invalid setting, not only the first
+// one found, and the builder is left unchanged
+func (b *ClientBuilder) BuildStrict() (*http.Client, error) {
+	b.warnings = nil
 
-	if b.client.maxIdleConns < ValidMinIdleConns || b.client.maxIdleConns > ValidMaxIdleConns {
-		slog.Warn("Invalid max idle connections, using default value", "invalidValue", b.client.maxIdleConns, "defaultValue", DefaultMaxIdleConns)
-		b.client.maxIdleConns = DefaultMaxIdleConns
+	cfg := *b.client
+	if err := cfg.validate().err(); err != nil {
+		return nil, err
 	}
 
-	if b.client.idleConnTimeout < ValidMinIdleConnTimeout || b.client.idleConnTimeout > ValidMaxIdleConnTimeout {
-		slog.Warn("Invalid idle connection timeout, using default value", "invalidValue", b.client.idleConnTimeout, "defaultValue", DefaultIdleConnTimeout)
-		b.client.idleConnTimeout = DefaultIdleConnTimeout
+	// Keep what validation derives from valid settings, e.g. max retries from max attempts
+	*b.client = cfg
+
+	return b.build(), nil
+}
+
+// build creates the http.Client from settings that have already been validated
+func (b *ClientBuilder) build() *http.Client {
+	rt := b.buildTransport()
+
+	checkRedirect := b.client.checkRedirect
+	if b.client.maxRedirectsSet {
+		checkRedirect = maxRedirectsPolicy(b.client.maxRedirects)
 	}
 
-	if b.client.tlsHandshakeTimeout < ValidMinTLSHandshakeTimeout || b.client.tlsHandshakeTimeout > ValidMaxTLSHandshakeTimeout {
-		slog.Warn("Invalid TLS handshake timeout, using default value", "invalidValue", b.client.tlsHandshakeTimeout, "defaultValue", DefaultTLSHandshakeTimeout)
-		b.client.tlsHandshakeTimeout = DefaultTLSHandshakeTimeout
+	// Create the HTTP client with the specified settings
+	return &http.Client{
+		Timeout:       b.client.timeout,
+		Jar:           b.client.cookieJar,
+		CheckRedirect: checkRedirect,
+		Transport:     rt,
 	}
+}
+
+// buildTransport creates the retry transport from settings that have already been validated
+func (b *ClientBuilder) buildTransport() *retryTransport {
+	finalStrategyType := b.client.retryStrategyType
 
-	if b.client.expectContinueTimeout < ValidMinExpectContinueTimeout || b.client.expectContinueTimeout > ValidMaxExpectContinueTimeout {
-		slog.Warn("Invalid expect continue timeout, using default value", "invalidValue", b.client.expectContinueTimeout, "defaultValue", DefaultExpectContinueTimeout)
-		b.client.expectContinueTimeout = DefaultExpectContinueTimeout
+	// Jitter-based strategies draw from the seeded source when one is configured
+	var src *rand.Rand
+	if b.client.jitterSeedSet {
+		src = rand.New(rand.NewSource(b.client.jitterSeed))
 	}
+	shared := &lockedRand{rng: src}
+
+	// Now create the actual strategy function using the validated type and delays.
+	// The closures below keep a copy of the settings so later builder calls don't affect this client
+	cfg := *b.client
+	finalRetryStrategy := cfg.newRetryStrategy(finalStrategyType, shared.Int63n)
 
-	if b.client.maxIdleConnsPerHost < ValidMinIdleConnsPerHost || b.client.maxIdleConnsPerHost > ValidMaxIdleConnsPerHost {
-		slog.Warn("Invalid max idle connections per host, using default value", "invalidValue", b.client.maxIdleConnsPerHost, "defaultValue", DefaultMaxIdleConnsPerHost)
-		b.client.maxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	// Stateful strategies and randomized max delays get a fresh instance for
+	// every request. A plain decorrelated jitter only needs a fresh Backoff,
+	// without wrapping it in a strategy
+	var newStrategy func() RetryStrategy
+	var newBackoff func() Backoff
+	if finalStrategyType == DecorrelatedJitterStrategy && cfg.randomMaxDelayMean <= 0 && !cfg.strictMaxDelay {
+		newBackoff = func() Backoff {
+			return newDecorrelatedJitterBackoff(cfg.retryBaseDelay, cfg.maxDelayCap(), shared.Int63n)
+		}
+	} else if finalStrategyType == DecorrelatedJitterStrategy || cfg.randomMaxDelayMean > 0 {
+		newStrategy = func() RetryStrategy {
+			return cfg.newRequestStrategy(finalStrategyType, shared.Int63n, shared.Float64)
+		}
 	}
 
-	if b.client.timeout < ValidMinTimeout || b.client.timeout > ValidMaxTimeout {
-		slog.Warn("Invalid timeout, using default value", "invalidValue", b.client.timeout, "defaultValue", DefaultTimeout)
-		b.client.timeout = DefaultTimeout
+	// Per-request seeded randomness, only meaningful when the strategy draws random numbers
+	var seededStrategy func(rng *rand.Rand) RetryStrategy
+	if cfg.requestSeed != nil && (finalStrategyType.usesJitter() || cfg.usesFixedJitter() || cfg.randomMaxDelayMean > 0) {
+		seededStrategy = func(rng *rand.Rand) RetryStrategy {
+			return cfg.newRequestStrategy(finalStrategyType, rng.Int63n, rng.Float64)
+		}
 	}
 
-	if b.client.maxRetries < ValidMinRetries || b.client.maxRetries > ValidMaxRetries {
-		slog.Warn("Invalid max retries, using default value", "invalidValue", b.client.maxRetries, "defaultValue", DefaultMaxRetries)
-		b.client.maxRetries = DefaultMaxRetries
+	// Wrap the caller's transport if one was given, otherwise create the underlying standard transport
+	var transport http.RoundTripper
+	if b.client.baseTransport != nil {
+		if b.client.transportSettingsSet {
+			slog.Warn("Transport settings are ignored when a base transport is set")
+			b.warnings = append(b.warnings, "transport settings: ignored because a base transport is set")
+		}
+		transport = b.client.baseTransport
+	} else {
+		dialer := &net.Dialer{
+			Timeout:   b.client.dialTimeout,
+			KeepAlive: b.client.dialKeepAlive,
+		}
+		dial := dialFunc(dialer.DialContext)
+		if socket := b.client.unixSocket; socket != "" {
+			dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socket)
+			}
+		}
+		if b.client.connMaxLifetime > 0 {
+			clock := b.client.clock
+			if clock == nil {
+				clock = realClock{}
+			}
+			dial = withConnMaxLifetime(dial, b.client.connMaxLifetime, clock)
+		}
+		t := &http.Transport{
+			DialContext:            dial,
+			MaxIdleConns:           b.client.maxIdleConns,
+			IdleConnTimeout:        b.client.idleConnTimeout,
+			TLSHandshakeTimeout:    b.client.tlsHandshakeTimeout,
+			ExpectContinueTimeout:  b.client.expectContinueTimeout,
+			ResponseHeaderTimeout:  b.client.responseHeaderTimeout,
+			DisableKeepAlives:      b.client.disableKeepAlives,
+			MaxIdleConnsPerHost:    b.client.maxIdleConnsPerHost,
+			MaxConnsPerHost:        b.client.maxConnsPerHost,
+			WriteBufferSize:        b.client.writeBufferSize,
+			ReadBufferSize:         b.client.readBufferSize,
+			MaxResponseHeaderBytes: b.client.maxResponseHeaderBytes,
+			TLSClientConfig:        b.client.transportTLSConfig(),
+			Proxy:                  b.client.proxy,
+			ForceAttemptHTTP2:      b.client.forceAttemptHTTP2,
+		}
+
+		// A non-nil, empty TLSNextProto map disables HTTP/2
+		if b.client.disableHTTP2 {
+			t.ForceAttemptHTTP2 = false
+			t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		}
+		transport = t
 	}
+	idleConns, _ := transport.(idleConnCloser)
+	transport = wrapMiddlewares(transport, b.client.attemptMiddlewares)
 
-	// Validate delays *before* creating the strategy function
-	if b.client.retryBaseDelay < ValidMinBaseDelay || b.client.retryBaseDelay > ValidMaxBaseDelay {
-		slog.Warn("Invalid base delay, using default value", "invalidValue", b.client.retryBaseDelay, "defaultValue", DefaultBaseDelay)
-		b.client.retryBaseDelay = DefaultBaseDelay
+	var stop *signalStop
+	if len(b.client.shutdownSignals) > 0 {
+		stop = newSignalStop(b.client.shutdownSignals...)
 	}
 
-	if b.client.retryMaxDelay < ValidMinMaxDelay || b.client.retryMaxDelay > ValidMaxMaxDelay {
-		slog.Warn("Invalid max delay, using default value", "invalidValue", b.client.retryMaxDelay, "defaultValue", DefaultMaxDelay)
-		b.client.retryMaxDelay = DefaultMaxDelay
+	var budget *bufferBudget
+	if b.client.maxTotalBufferBytes > 0 {
+		budget = newBufferBudget(b.client.maxTotalBufferBytes, b.client.waitForBufferBudget)
 	}
 
-	// Determine the final strategy type, defaulting if necessary
-	finalStrategyType := b.client.retryStrategyType
-	switch finalStrategyType {
-	case FixedDelayStrategy, JitterBackoffStrategy, ExponentialBackoffStrategy:
-		// Valid type provided
-	default:
-		// No type set or invalid type somehow persisted, use default
-		slog.Warn("No valid retry strategy type set, using default (Exponential)", "currentType", finalStrategyType)
-		finalStrategyType = ExponentialBackoffStrategy
+	var limiter *hostLimiter
+	if b.client.maxConcurrentPerHost > 0 {
+		limiter = newHostLimiter(b.client.maxConcurrentPerHost)
 	}
 
-	// Now create the actual strategy function using the validated type and delays
-	var finalRetryStrategy RetryStrategy
-	switch finalStrategyType {
-	case FixedDelayStrategy:
-		finalRetryStrategy = FixedDelay(b.client.retryBaseDelay)
-	case JitterBackoffStrategy:
-		finalRetryStrategy = JitterBackoff(b.client.retryBaseDelay, b.client.retryMaxDelay)
-	case ExponentialBackoffStrategy:
-		finalRetryStrategy = ExponentialBackoff(b.client.retryBaseDelay, b.client.retryMaxDelay)
-	default: // Handles invalid types explicitly defaulting to Exponential
-		// This case is reached if finalStrategyType was initially invalid ("" or "invalid")
-		finalRetryStrategy = ExponentialBackoff(b.client.retryBaseDelay, b.client.retryMaxDelay)
+	var rateLimiter *rateLimiter
+	if b.client.rateLimitSet {
+		rateLimiter = newRateLimiter(b.client.rateLimit, b.client.rateLimitBurst)
 	}
 
-	// Create the underlying standard transport
-	transport := &http.Transport{
-		MaxIdleConns:          b.client.maxIdleConns,
-		IdleConnTimeout:       b.client.idleConnTimeout,
-		TLSHandshakeTimeout:   b.client.tlsHandshakeTimeout,
-		ExpectContinueTimeout: b.client.expectContinueTimeout,
-		DisableKeepAlives:     b.client.disableKeepAlives,
-		MaxIdleConnsPerHost:   b.client.maxIdleConnsPerHost,
+	var retries *retryBudget
+	if b.client.retryBudgetSet {
+		retries = newRetryBudget(b.client.retryBudgetRatio, b.client.retryBudgetMinPerSec)
 	}
 
-	// Create the HTTP client with the specified settings
-	return &http.Client{
-		Timeout: b.client.timeout,
-		Transport: &retryTransport{
-			Transport:     transport,
-			MaxRetries:    b.client.maxRetries,
-			RetryStrategy: finalRetryStrategy, // Use the function created in Build
+	var breaker *circuitBreaker
+	if b.client.breakerSet {
+		breaker = newCircuitBreaker(b.client.breakerThreshold, b.client.breakerOpenDuration)
+	}
+
+	var adaptive *adaptiveBackoff
+	if b.client.adaptiveBackoff {
+		adaptive = newAdaptiveBackoff()
+	}
+
+	var auth *authRefresher
+	if b.client.authRefresh != nil {
+		auth = newAuthRefresher(b.client.authHeader, b.client.authRefresh)
+	}
+
+	var inFlight chan struct{}
+	if b.client.maxConcurrent > 0 {
+		inFlight = make(chan struct{}, b.client.maxConcurrent)
+	}
+
+	rt := &retryTransport{
+		Transport:              transport,
+		Jar:                    b.client.cookieJar,
+		FreshConnOnError:       b.client.freshConnOnError,
+		idleConns:              idleConns,
+		MaxRetries:             b.client.maxRetries,
+		RetryStrategy:          finalRetryStrategy, // Use the function created in Build
+		RequestSeed:            b.client.requestSeed,
+		SeededStrategy:         seededStrategy,
+		NewStrategy:            newStrategy,
+		NewBackoff:             newBackoff,
+		stop:                   stop,
+		hostLimiter:            limiter,
+		rateLimiter:            rateLimiter,
+		retryBudget:            retries,
+		inFlight:               inFlight,
+		circuitBreaker:         breaker,
+		adaptiveBackoff:        adaptive,
+		MaxHedges:              cfg.maxHedges,
+		HedgeDelay:             cfg.hedgeDelay,
+		FallbackHosts:          cfg.fallbackHosts,
+		RetryableStatuses:      cfg.retryableStatuses,
+		NoRetryStatuses:        cfg.noRetryStatuses,
+		StatusDelays:           maps.Clone(cfg.statusDelays),
+		RequiredHeader:         cfg.requiredHeader,
+		ExpectedContentType:    cfg.expectedContentType,
+		RespectRetryAfter:      cfg.respectRetryAfter,
+		DryRun:                 cfg.dryRun,
+		ResponseValidator:      cfg.responseValidator,
+		MaxValidateBodySize:    cfg.maxValidateBodySize,
+		RetryCondition:         cfg.retryCondition,
+		ReturnLastResponse:     cfg.returnLastResponse,
+		AttemptHeader:          cfg.attemptHeader,
+		DefaultHeaders:         cfg.defaultHeaders,
+		UserAgent:              cfg.userAgent,
+		IdempotencyKeyHeader:   cfg.idempotencyKeyHeader,
+		auth:                   auth,
+		ConnectTimeoutStrategy: cfg.connectTimeoutBackoff,
+		MinDelay:               cfg.minDelay,
+		MaxElapsedTime:         cfg.maxElapsedTime,
+		MinAttemptBudget:       cfg.minAttemptBudget,
+		PerAttemptTimeout:      cfg.perAttemptTimeout,
+		RetryEvents:            cfg.retryEvents,
+		OnRetry:                chainCallbacks(cfg.onRetry),
+		OnRequestDone:          chainCallbacks(cfg.onRequestDone),
+		AutoBufferBody:         cfg.autoBufferBody,
+		MaxBufferSize:          cfg.maxBufferSize,
+		OnBufferTruncated:      cfg.onBufferTruncated,
+		bufferBudget:           budget,
+		Clock:                  cfg.clock,
+		Logger:                 cfg.logger,
+		MaxDrainSize:           cfg.maxDrainSize,
+		drainBuffers:           newBufferPool(cfg.drainBufferSize),
+		config: RetryConfig{
+			MaxRetries: cfg.maxRetries,
+			Strategy:   finalStrategyType,
+			BaseDelay:  cfg.retryBaseDelay,
+			MaxDelay:   cfg.retryMaxDelay,
 		},
 	}
+
+	// Request middlewares wrap the retry logic itself, so the client's
+	// transport is still the retry transport
+	if len(b.client.requestMiddlewares) > 0 {
+		rt.requestRoundTripper = wrapMiddlewares(roundTripperFunc(rt.roundTrip), b.client.requestMiddlewares)
+	}
+
+	return rt
 }
@@ -1,7 +1,17 @@
 package httpretrier
 
 import (
+	"crypto/tls"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -46,7 +56,7 @@ func TestClientBuilder_WithMethods(t *testing.T) {
 					WithExpectContinueTimeout(0).             // Invalid, use default
 					WithMaxIdleConnsPerHost(0).               // Invalid, use default
 					WithTimeout(0).                           // Invalid, use default
-					WithMaxRetries(0).                        // Invalid, use default
+					WithMaxRetries(-1).                       // Invalid, use default
 					WithRetryBaseDelay(1 * time.Millisecond). // Invalid, use default
 					WithRetryMaxDelay(50 * time.Millisecond). // Invalid, use default
 					WithRetryStrategy("invalid")              // Invalid strategy type
@@ -59,7 +69,7 @@ func TestClientBuilder_WithMethods(t *testing.T) {
 	assert.Equal(t, 0*time.Second, client.expectContinueTimeout)
 	assert.Equal(t, 0, client.maxIdleConnsPerHost)
 	assert.Equal(t, 0*time.Second, client.timeout)
-	assert.Equal(t, 0, client.maxRetries)
+	assert.Equal(t, -1, client.maxRetries)
 	assert.Equal(t, 1*time.Millisecond, client.retryBaseDelay)
 	assert.Equal(t, 50*time.Millisecond, client.retryMaxDelay)
 	// Check that the invalid strategy type was set
@@ -153,6 +163,1032 @@ func TestClientBuilder_Build(t *testing.T) {
 	assert.Equal(t, 1*time.Second, delay, "FixedDelay strategy delay check failed")
 }
 
+func TestClientBuilder_BuildTransport(t *testing.T) {
+	builder := NewClientBuilder().
+		WithMaxRetries(4).
+		WithRetryBaseDelay(1 * time.Millisecond). // Invalid, use default
+		WithTimeout(11 * time.Second)
+	transport := builder.BuildTransport()
+
+	rt, ok := transport.(*retryTransport)
+	if !assert.True(t, ok, "Transport should be of type *retryTransport") {
+		return
+	}
+	assert.Equal(t, 4, rt.MaxRetries)
+	assert.Equal(t, DefaultBaseDelay, rt.config.BaseDelay)
+	assert.Len(t, builder.Warnings(), 1)
+	_, ok = rt.Transport.(*http.Transport)
+	assert.True(t, ok, "Inner transport should be of type *http.Transport")
+
+	// The transport retries in any client
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	httpClient := &http.Client{Transport: NewClientBuilder().WithRetryStrategy(FixedDelayStrategy).BuildTransport()}
+	resp, err := httpClient.Get(server.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Equal(t, int32(2), attempts.Load())
+}
+
+func TestClientBuilder_WithRequestSeededJitter(t *testing.T) {
+	seedFunc := func(req *http.Request) int64 { return int64(len(req.URL.Path)) }
+
+	httpClient := NewClientBuilder().
+		WithRetryStrategy(JitterBackoffStrategy).
+		WithRequestSeededJitter(seedFunc).
+		Build()
+
+	rt, ok := httpClient.Transport.(*retryTransport)
+	assert.True(t, ok, "Transport should be of type *retryTransport")
+	assert.NotNil(t, rt.RequestSeed)
+	assert.NotNil(t, rt.SeededStrategy)
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com/seeded", nil)
+	assert.NoError(t, err)
+	first, second := rt.backoffFor(req).Next, rt.backoffFor(req).Next
+	for attempt := range 5 {
+		assert.Equal(t, first(attempt), second(attempt), "Seeded jitter for attempt %d should be reproducible", attempt)
+	}
+
+	// The seed is ignored by non-jitter strategies
+	httpClient = NewClientBuilder().
+		WithRetryStrategy(ExponentialBackoffStrategy).
+		WithRequestSeededJitter(seedFunc).
+		Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Nil(t, rt.SeededStrategy)
+}
+
+func TestClientBuilder_WithJitterSeed(t *testing.T) {
+	build := func() RetryStrategy {
+		httpClient := NewClientBuilder().
+			WithRetryStrategy(JitterBackoffStrategy).
+			WithJitterSeed(7).
+			Build()
+		rt, ok := httpClient.Transport.(*retryTransport)
+		assert.True(t, ok, "Transport should be of type *retryTransport")
+		return rt.RetryStrategy
+	}
+
+	first, second := build(), build()
+	for attempt := range 5 {
+		assert.Equal(t, first(attempt), second(attempt), "Seeded jitter for attempt %d should be reproducible", attempt)
+	}
+}
+
+func TestClientBuilder_WithStrictMaxDelay(t *testing.T) {
+	build := func(strict bool) RetryStrategy {
+		httpClient := NewClientBuilder().
+			WithRetryStrategy(JitterBackoffStrategy).
+			WithRetryBaseDelay(1 * time.Second).
+			WithRetryMaxDelay(4 * time.Second).
+			WithJitterSeed(1).
+			WithStrictMaxDelay(strict).
+			Build()
+		rt, _ := httpClient.Transport.(*retryTransport)
+		return rt.RetryStrategy
+	}
+
+	// By default, the jitter goes on top of the capped delay
+	lenient := build(false)
+	exceeded := false
+	for range 100 {
+		exceeded = exceeded || lenient(5) > 4*time.Second
+	}
+	assert.True(t, exceeded, "Expected the jitter to exceed the max delay by default")
+
+	strict := build(true)
+	for attempt := range 10 {
+		delay := strict(attempt)
+		assert.LessOrEqual(t, delay, 4*time.Second, "Attempt %d", attempt)
+
+		// Below the cap, the jitter is kept
+		if attempt == 0 {
+			assert.GreaterOrEqual(t, delay, 1*time.Second)
+		}
+	}
+
+	// At the cap, the exponential delay leaves no room for jitter
+	assert.Equal(t, 4*time.Second, strict(2))
+	assert.Equal(t, 4*time.Second, strict(9))
+}
+
+func TestClientBuilder_WithMaxJitter(t *testing.T) {
+	for _, strategy := range []Strategy{JitterBackoffStrategy, EqualJitterStrategy} {
+		t.Run(string(strategy), func(t *testing.T) {
+			httpClient := NewClientBuilder().
+				WithRetryStrategy(strategy).
+				WithRetryBaseDelay(1 * time.Second).
+				WithRetryMaxDelay(30 * time.Second).
+				WithMaxJitter(200 * time.Millisecond).
+				Build()
+			rt, _ := httpClient.Transport.(*retryTransport)
+
+			// At 16s, the jitter would otherwise span 8s
+			for range 100 {
+				delay := rt.RetryStrategy(4)
+				if strategy == JitterBackoffStrategy {
+					assert.GreaterOrEqual(t, delay, 16*time.Second)
+					assert.Less(t, delay, 16*time.Second+200*time.Millisecond)
+				} else {
+					assert.GreaterOrEqual(t, delay, 16*time.Second-200*time.Millisecond)
+					assert.Less(t, delay, 16*time.Second)
+				}
+			}
+		})
+	}
+
+	builder := NewClientBuilder().WithMaxJitter(-1 * time.Second)
+	builder.Build()
+	assert.Equal(t, DefaultMaxJitter, builder.client.maxJitter)
+}
+
+func TestClientBuilder_WithMaxConcurrentPerHost(t *testing.T) {
+	httpClient := NewClientBuilder().WithMaxConcurrentPerHost(4).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.NotNil(t, rt.hostLimiter)
+	assert.Equal(t, 4, rt.hostLimiter.limit)
+
+	// Unlimited by default
+	httpClient = NewClientBuilder().Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Nil(t, rt.hostLimiter)
+
+	// Negative values fall back to the default (unlimited)
+	builder := NewClientBuilder().WithMaxConcurrentPerHost(-1)
+	httpClient = builder.Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Nil(t, rt.hostLimiter)
+	assert.Equal(t, DefaultMaxConcurrentPerHost, builder.client.maxConcurrentPerHost)
+}
+
+func TestClientBuilder_DecorrelatedJitterStrategy(t *testing.T) {
+	httpClient := NewClientBuilder().
+		WithRetryStrategy(DecorrelatedJitterStrategy).
+		WithRetryBaseDelay(500 * time.Millisecond).
+		WithRetryMaxDelay(5 * time.Second).
+		Build()
+
+	rt, ok := httpClient.Transport.(*retryTransport)
+	assert.True(t, ok, "Transport should be of type *retryTransport")
+	assert.NotNil(t, rt.NewBackoff, "Decorrelated jitter should create a backoff per request")
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	assert.NoError(t, err)
+	assert.NotSame(t, rt.backoffFor(req), rt.backoffFor(req), "Requests should not share the backoff state")
+	strategy := rt.backoffFor(req).Next
+	for attempt := range 5 {
+		delay := strategy(attempt)
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		assert.LessOrEqual(t, delay, 5*time.Second)
+	}
+
+	assert.True(t, DecorrelatedJitterStrategy.IsValid())
+}
+
+func TestClientBuilder_FullAndEqualJitterStrategies(t *testing.T) {
+	baseDelay := 500 * time.Millisecond
+	maxDelay := 5 * time.Second
+	expStrategy := ExponentialBackoff(baseDelay, maxDelay)
+
+	httpClient := NewClientBuilder().
+		WithRetryStrategy(FullJitterStrategy).
+		WithRetryBaseDelay(baseDelay).
+		WithRetryMaxDelay(maxDelay).
+		Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	for attempt := range 5 {
+		delay := rt.RetryStrategy(attempt)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.Less(t, delay, expStrategy(attempt))
+	}
+
+	httpClient = NewClientBuilder().
+		WithRetryStrategy(EqualJitterStrategy).
+		WithRetryBaseDelay(baseDelay).
+		WithRetryMaxDelay(maxDelay).
+		Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	for attempt := range 5 {
+		delay := rt.RetryStrategy(attempt)
+		assert.GreaterOrEqual(t, delay, expStrategy(attempt)/2)
+		assert.Less(t, delay, expStrategy(attempt))
+	}
+
+	assert.True(t, FullJitterStrategy.IsValid())
+	assert.True(t, EqualJitterStrategy.IsValid())
+}
+
+func TestClientBuilder_WithRandomizedMaxDelay(t *testing.T) {
+	mean := 10 * time.Second
+	httpClient := NewClientBuilder().
+		WithRetryStrategy(ExponentialBackoffStrategy).
+		WithRandomizedMaxDelay(mean).
+		WithJitterSeed(1).
+		Build()
+
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.NotNil(t, rt.NewStrategy, "Randomized max delay should create a strategy per request")
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	assert.NoError(t, err)
+
+	// A late attempt always hits the cap, which exposes the effective max delay
+	const requests = 2000
+	var sum time.Duration
+	lowest, highest := ValidMaxMaxDelay, time.Duration(0)
+	for range requests {
+		effectiveMax := rt.backoffFor(req).Next(30)
+		assert.GreaterOrEqual(t, effectiveMax, DefaultBaseDelay)
+		assert.LessOrEqual(t, effectiveMax, ValidMaxMaxDelay)
+		lowest, highest = min(lowest, effectiveMax), max(highest, effectiveMax)
+		sum += effectiveMax
+	}
+
+	assert.Less(t, lowest, mean/2, "Effective max delay should vary below the mean")
+	assert.Greater(t, highest, mean*2, "Effective max delay should vary above the mean")
+	sampleMean := sum / requests
+	assert.InDelta(t, float64(mean), float64(sampleMean), float64(mean)*0.15, "Sample mean %v should be close to %v", sampleMean, mean)
+
+	// Uniform distribution stays within [base, 2*mean)
+	httpClient = NewClientBuilder().
+		WithRandomizedMaxDelay(mean).
+		WithMaxDelayDistribution(UniformDistribution).
+		Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	for range 200 {
+		effectiveMax := rt.backoffFor(req).Next(30)
+		assert.GreaterOrEqual(t, effectiveMax, DefaultBaseDelay)
+		assert.Less(t, effectiveMax, 2*mean)
+	}
+
+	// An invalid mean falls back to the fixed max delay
+	builder := NewClientBuilder().WithRandomizedMaxDelay(1 * time.Millisecond)
+	httpClient = builder.Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Nil(t, rt.NewStrategy)
+	assert.Equal(t, time.Duration(0), builder.client.randomMaxDelayMean)
+}
+
+func TestClientBuilder_WithRetryIfMissingHeader(t *testing.T) {
+	httpClient := NewClientBuilder().WithRetryIfMissingHeader("X-Trace-Id").Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.Equal(t, "X-Trace-Id", rt.RequiredHeader)
+}
+
+func TestClientBuilder_WithRetryMultiplier(t *testing.T) {
+	baseDelay := 1 * time.Second
+	maxDelay := 60 * time.Second
+
+	httpClient := NewClientBuilder().
+		WithRetryStrategy(ExponentialBackoffStrategy).
+		WithRetryBaseDelay(baseDelay).
+		WithRetryMaxDelay(maxDelay).
+		WithRetryMultiplier(3).
+		Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.Equal(t, baseDelay, rt.RetryStrategy(0))
+	assert.Equal(t, 3*time.Second, rt.RetryStrategy(1))
+	assert.Equal(t, 9*time.Second, rt.RetryStrategy(2))
+	assert.Equal(t, maxDelay, rt.RetryStrategy(4))
+
+	// Jitter strategies build on the same factor
+	httpClient = NewClientBuilder().
+		WithRetryStrategy(JitterBackoffStrategy).
+		WithRetryBaseDelay(baseDelay).
+		WithRetryMaxDelay(maxDelay).
+		WithRetryMultiplier(1.5).
+		Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	delay := rt.RetryStrategy(2) // 1s * 1.5^2 = 2.25s plus up to half as jitter
+	assert.GreaterOrEqual(t, delay, 2250*time.Millisecond)
+	assert.Less(t, delay, 2250*time.Millisecond*3/2)
+
+	// Invalid multipliers fall back to the default
+	for _, multiplier := range []float64{0.5, 11, math.NaN()} {
+		builder := NewClientBuilder().WithRetryMultiplier(multiplier)
+		httpClient = builder.Build()
+		rt, _ = httpClient.Transport.(*retryTransport)
+		assert.Equal(t, DefaultRetryMultiplier, builder.client.retryMultiplier)
+		assert.Equal(t, DefaultBaseDelay*2, rt.RetryStrategy(1))
+	}
+}
+
+func TestClientBuilder_WithConnectTimeoutBackoff(t *testing.T) {
+	httpClient := NewClientBuilder().WithConnectTimeoutBackoff(FixedDelay(3 * time.Second)).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.NotNil(t, rt.ConnectTimeoutStrategy)
+	assert.Equal(t, 3*time.Second, rt.ConnectTimeoutStrategy(0))
+}
+
+func TestNewClientBuilderFrom(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := &http.Transport{
+		MaxIdleConns:        20,
+		MaxIdleConnsPerHost: 5,
+		IdleConnTimeout:     30 * time.Second,
+	}
+	jar, _ := cookiejar.New(nil)
+	original := &http.Client{Transport: transport, Timeout: 7 * time.Second, Jar: jar}
+
+	builder := NewClientBuilderFrom(original).
+		WithRetryStrategy(FixedDelayStrategy).
+		WithRetryBaseDelay(300 * time.Millisecond).
+		WithClock(&fakeClock{now: time.Now()})
+	httpClient := builder.Build()
+	assert.Empty(t, builder.Warnings())
+
+	assert.Equal(t, 7*time.Second, httpClient.Timeout)
+	assert.Same(t, jar, httpClient.Jar)
+	assert.Equal(t, 20, builder.client.maxIdleConns)
+	assert.Equal(t, 5, builder.client.maxIdleConnsPerHost)
+	assert.Equal(t, 30*time.Second, builder.client.idleConnTimeout)
+	assert.Equal(t, DefaultTLSHandshakeTimeout, builder.client.tlsHandshakeTimeout) // Zero in the transport
+
+	// The original transport is wrapped as is
+	rt, ok := httpClient.Transport.(*retryTransport)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Same(t, transport, rt.Transport)
+
+	resp, err := httpClient.Get(server.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// A retrying client gets its retry logic replaced, not wrapped twice
+	rebuilt := NewClientBuilderFrom(httpClient).Build()
+	assert.Same(t, transport, rebuilt.Transport.(*retryTransport).Transport)
+
+	// Without a transport or timeout, the defaults apply
+	plain := NewClientBuilderFrom(&http.Client{}).Build()
+	assert.Equal(t, DefaultTimeout, plain.Timeout)
+	assert.Equal(t, http.DefaultTransport, plain.Transport.(*retryTransport).Transport)
+}
+
+func TestClientBuilder_WithNoMaxDelay(t *testing.T) {
+	builder := NewClientBuilder().
+		WithRetryMaxDelay(NoMaxDelay).
+		WithMinDelay(1 * time.Hour)
+	httpClient := builder.Build()
+	assert.Empty(t, builder.Warnings())
+
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.Equal(t, NoMaxDelay, rt.config.MaxDelay)
+	assert.Equal(t, 1*time.Hour, rt.MinDelay)
+	assert.Equal(t, DefaultBaseDelay*1024, rt.RetryStrategy(10))
+}
+
+func TestClientBuilder_WithMinDelay(t *testing.T) {
+	httpClient := NewClientBuilder().WithMinDelay(1 * time.Second).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.Equal(t, 1*time.Second, rt.MinDelay)
+	assert.Equal(t, DefaultMaxDelay, rt.config.MaxDelay)
+
+	// A floor above the cap is swapped with it
+	builder := NewClientBuilder().WithRetryMaxDelay(2 * time.Second).WithMinDelay(5 * time.Second)
+	httpClient = builder.Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Equal(t, 2*time.Second, rt.MinDelay)
+	assert.Equal(t, 5*time.Second, rt.config.MaxDelay)
+	assert.Len(t, builder.Warnings(), 1)
+
+	httpClient = NewClientBuilder().WithMinDelay(-1 * time.Second).Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Zero(t, rt.MinDelay)
+}
+
+func TestClientBuilder_WithMaxElapsedTime(t *testing.T) {
+	httpClient := NewClientBuilder().WithMaxElapsedTime(20 * time.Second).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.Equal(t, 20*time.Second, rt.MaxElapsedTime)
+
+	builder := NewClientBuilder().WithMaxElapsedTime(-1 * time.Second)
+	httpClient = builder.Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Equal(t, DefaultMaxElapsedTime, rt.MaxElapsedTime)
+}
+
+func TestClientBuilder_WithMinAttemptBudget(t *testing.T) {
+	httpClient := NewClientBuilder().WithMinAttemptBudget(500 * time.Millisecond).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.Equal(t, 500*time.Millisecond, rt.MinAttemptBudget)
+
+	httpClient = NewClientBuilder().WithMinAttemptBudget(-1 * time.Second).Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Equal(t, DefaultMinAttemptBudget, rt.MinAttemptBudget)
+}
+
+func TestClientBuilder_WithMaxAttempts(t *testing.T) {
+	tests := []struct {
+		name          string
+		builder       *ClientBuilder
+		expectRetries int
+	}{
+		{
+			name:          "single attempt means no retries",
+			builder:       NewClientBuilder().WithMaxAttempts(1),
+			expectRetries: 0,
+		},
+		{
+			name:          "attempts include the first request",
+			builder:       NewClientBuilder().WithMaxAttempts(4),
+			expectRetries: 3,
+		},
+		{
+			name:          "invalid attempts use the default",
+			builder:       NewClientBuilder().WithMaxAttempts(0),
+			expectRetries: DefaultMaxRetries,
+		},
+		{
+			name:          "max attempts win over max retries",
+			builder:       NewClientBuilder().WithMaxRetries(5).WithMaxAttempts(2),
+			expectRetries: 1,
+		},
+		{
+			name:          "a later preset overrides max attempts",
+			builder:       NewClientBuilder().WithMaxAttempts(2).WithPreset(PresetProduction()),
+			expectRetries: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := tt.builder.Build()
+			rt, _ := httpClient.Transport.(*retryTransport)
+			assert.Equal(t, tt.expectRetries, rt.MaxRetries)
+		})
+	}
+}
+
+func TestClientBuilder_WithMaxAttemptsSingleRequest(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	httpClient := NewClientBuilder().WithMaxAttempts(1).Build()
+	resp, err := httpClient.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+	}
+
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestClientBuilder_WithMaxRetriesZero(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	builder := NewClientBuilder().WithMaxRetries(0)
+	httpClient := builder.Build()
+	assert.Empty(t, builder.Warnings())
+
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.Equal(t, 0, rt.MaxRetries)
+
+	resp, err := httpClient.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+	}
+
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestClientBuilder_WithPerAttemptTimeout(t *testing.T) {
+	httpClient := NewClientBuilder().WithPerAttemptTimeout(2 * time.Second).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.Equal(t, 2*time.Second, rt.PerAttemptTimeout)
+
+	builder := NewClientBuilder().WithPerAttemptTimeout(-1 * time.Second)
+	httpClient = builder.Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Equal(t, DefaultPerAttemptTimeout, rt.PerAttemptTimeout)
+}
+
+func TestClientBuilder_WithRetryEvents(t *testing.T) {
+	events := make(chan RetryEvent, 1)
+	httpClient := NewClientBuilder().WithRetryEvents(events).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.NotNil(t, rt.RetryEvents)
+
+	// The deprecated name still works
+	httpClient = NewClientBuilder().WithRetryEventChannel(events).Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.NotNil(t, rt.RetryEvents)
+
+	httpClient = NewClientBuilder().Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Nil(t, rt.RetryEvents)
+}
+
+func TestClientBuilder_WithOnRetry(t *testing.T) {
+	var calls []string
+	httpClient := NewClientBuilder().
+		WithOnRetry(func(RetryEvent) { calls = append(calls, "first") }).
+		WithOnRetry(nil).
+		WithOnRetry(func(RetryEvent) { calls = append(calls, "second") }).
+		WithOnRequestDone(func(Stats) { calls = append(calls, "done") }).
+		Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	if assert.NotNil(t, rt.OnRetry) && assert.NotNil(t, rt.OnRequestDone) {
+		rt.OnRetry(RetryEvent{})
+		rt.OnRequestDone(Stats{})
+		assert.Equal(t, []string{"first", "second", "done"}, calls)
+	}
+
+	httpClient = NewClientBuilder().Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Nil(t, rt.OnRetry)
+	assert.Nil(t, rt.OnRequestDone)
+}
+
+func TestClientBuilder_WithAutoBufferBody(t *testing.T) {
+	httpClient := NewClientBuilder().WithAutoBufferBody(true).WithMaxBufferSize(1024).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.True(t, rt.AutoBufferBody)
+	assert.Equal(t, int64(1024), rt.MaxBufferSize)
+
+	builder := NewClientBuilder().WithMaxBufferSize(0)
+	httpClient = builder.Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.False(t, rt.AutoBufferBody)
+	assert.Equal(t, int64(DefaultMaxBufferSize), rt.MaxBufferSize)
+}
+
+func TestClientBuilder_WithOnBufferTruncated(t *testing.T) {
+	var called bool
+	httpClient := NewClientBuilder().WithOnBufferTruncated(func(size int64) { called = true }).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.NotNil(t, rt.OnBufferTruncated)
+
+	rt.OnBufferTruncated(0)
+	assert.True(t, called)
+}
+
+func TestClientBuilder_WithRetryCondition(t *testing.T) {
+	httpClient := NewClientBuilder().WithRetryCondition(func(resp *http.Response, err error) bool { return false }).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.NotNil(t, rt.RetryCondition)
+	assert.False(t, rt.shouldRetry(nil, assert.AnError))
+
+	httpClient = NewClientBuilder().Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Nil(t, rt.RetryCondition)
+	assert.True(t, rt.shouldRetry(nil, assert.AnError))
+}
+
+func TestClientBuilder_WithMaxDrainSize(t *testing.T) {
+	httpClient := NewClientBuilder().WithMaxDrainSize(1024).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.Equal(t, int64(1024), rt.MaxDrainSize)
+
+	builder := NewClientBuilder().WithMaxDrainSize(-1)
+	httpClient = builder.Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Equal(t, int64(DefaultMaxDrainSize), rt.MaxDrainSize)
+}
+
+func TestClientBuilder_WithDrainBufferSize(t *testing.T) {
+	httpClient := NewClientBuilder().WithDrainBufferSize(1024).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	assert.Len(t, *rt.drainBufferPool().get(), 1024)
+
+	builder := NewClientBuilder().WithDrainBufferSize(100)
+	httpClient = builder.Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Len(t, *rt.drainBufferPool().get(), DefaultDrainBufferSize)
+	assert.Len(t, builder.Warnings(), 1)
+
+	// Transports built without a pool share the default one
+	assert.Same(t, defaultDrainBuffers, (&retryTransport{}).drainBufferPool())
+}
+
+func TestClientBuilder_WithBaseTransport(t *testing.T) {
+	base := &mockRoundTripper{}
+
+	builder := NewClientBuilder().WithBaseTransport(base)
+	httpClient := builder.Build()
+	rt, ok := httpClient.Transport.(*retryTransport)
+	assert.True(t, ok)
+	assert.Same(t, base, rt.Transport)
+	assert.Empty(t, builder.Warnings())
+
+	// Transport settings cannot be applied to a caller-provided transport
+	builder = NewClientBuilder().WithBaseTransport(base).WithMaxIdleConns(50)
+	httpClient = builder.Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Same(t, base, rt.Transport)
+	assert.Len(t, builder.Warnings(), 1)
+
+	// Without a base transport a standard transport is created
+	httpClient = NewClientBuilder().WithMaxIdleConns(50).Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	transport, ok := rt.Transport.(*http.Transport)
+	assert.True(t, ok)
+	assert.Equal(t, 50, transport.MaxIdleConns)
+}
+
+func TestClientBuilder_WithTLSConfig(t *testing.T) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS13}
+
+	httpClient := NewClientBuilder().WithTLSConfig(tlsConfig).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	transport, ok := rt.Transport.(*http.Transport)
+	assert.True(t, ok)
+	assert.Same(t, tlsConfig, transport.TLSClientConfig)
+
+	// Without a configuration, only the min TLS version is set
+	httpClient = NewClientBuilder().Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	transport, _ = rt.Transport.(*http.Transport)
+	assert.Equal(t, &tls.Config{MinVersion: DefaultMinTLSVersion}, transport.TLSClientConfig)
+}
+
+func TestClientBuilder_WithProxy(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	proxyURL, _ := url.Parse("http://proxy.example.com:3128")
+
+	httpClient := NewClientBuilder().WithProxy(http.ProxyURL(proxyURL)).Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	transport, _ := rt.Transport.(*http.Transport)
+	got, err := transport.Proxy(req)
+	assert.NoError(t, err)
+	assert.Equal(t, proxyURL, got)
+
+	builder := NewClientBuilder().WithProxyURL("http://proxy.example.com:3128")
+	httpClient = builder.Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	transport, _ = rt.Transport.(*http.Transport)
+	got, err = transport.Proxy(req)
+	assert.NoError(t, err)
+	assert.Equal(t, proxyURL.String(), got.String())
+	assert.Empty(t, builder.Warnings())
+
+	// An unparseable URL leaves the proxy unset
+	for _, invalid := range []string{"://missing-scheme", "proxy.example.com:3128", "http://%zz"} {
+		builder = NewClientBuilder().WithProxyURL(invalid)
+		httpClient = builder.Build()
+		rt, _ = httpClient.Transport.(*retryTransport)
+		transport, _ = rt.Transport.(*http.Transport)
+		assert.Nil(t, transport.Proxy, invalid)
+		assert.Len(t, builder.Warnings(), 1, invalid)
+
+		_, err = NewClientBuilder().WithProxyURL(invalid).BuildStrict()
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestClientBuilder_WithUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix sockets unavailable: %v", err)
+	}
+
+	var attempts atomic.Int32
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(r.Host + r.URL.Path))
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	builder := NewClientBuilder().
+		WithUnixSocket(socket).
+		WithRetryStrategy(FixedDelayStrategy)
+	httpClient := builder.Build()
+	assert.Equal(t, socket, builder.UnixSocket())
+
+	resp, err := httpClient.Get("http://localhost/status")
+	if assert.NoError(t, err) {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "localhost/status", string(body))
+	}
+	assert.Equal(t, int32(2), attempts.Load())
+
+	// A proxy can't be used together with the socket
+	builder = NewClientBuilder().WithUnixSocket(socket).WithProxyURL("http://proxy.example.com:3128")
+	httpClient = builder.Build()
+	assert.Len(t, builder.Warnings(), 1)
+	rt, _ := httpClient.Transport.(*retryTransport)
+	transport, _ := rt.Transport.(*http.Transport)
+	assert.Nil(t, transport.Proxy)
+
+	_, err = NewClientBuilder().WithUnixSocket(socket).WithProxy(http.ProxyFromEnvironment).BuildStrict()
+	assert.Error(t, err)
+}
+
+func TestClientBuilder_WithDialTimeoutAndKeepAlive(t *testing.T) {
+	builder := NewClientBuilder().
+		WithDialTimeout(2 * time.Second).
+		WithDialKeepAlive(15 * time.Second)
+	httpClient := builder.Build()
+	assert.Equal(t, 2*time.Second, builder.client.dialTimeout)
+	assert.Equal(t, 15*time.Second, builder.client.dialKeepAlive)
+	assert.Empty(t, builder.Warnings())
+
+	rt, _ := httpClient.Transport.(*retryTransport)
+	transport, _ := rt.Transport.(*http.Transport)
+	assert.NotNil(t, transport.DialContext)
+
+	// Invalid values fall back to the defaults
+	builder = NewClientBuilder().
+		WithDialTimeout(10 * time.Millisecond).
+		WithDialKeepAlive(time.Hour)
+	builder.Build()
+	assert.Equal(t, DefaultDialTimeout, builder.client.dialTimeout)
+	assert.Equal(t, DefaultDialKeepAlive, builder.client.dialKeepAlive)
+	assert.Len(t, builder.Warnings(), 2)
+}
+
+func TestClientBuilder_WithResponseHeaderTimeout(t *testing.T) {
+	builder := NewClientBuilder().WithResponseHeaderTimeout(2 * time.Second)
+	httpClient := builder.Build()
+	assert.Equal(t, 2*time.Second, builder.ResponseHeaderTimeout())
+	assert.Empty(t, builder.Warnings())
+
+	rt, _ := httpClient.Transport.(*retryTransport)
+	transport, _ := rt.Transport.(*http.Transport)
+	assert.Equal(t, 2*time.Second, transport.ResponseHeaderTimeout)
+
+	// No timeout by default
+	builder = NewClientBuilder()
+	builder.Build()
+	assert.Equal(t, DefaultResponseHeaderTimeout, builder.ResponseHeaderTimeout())
+	assert.Empty(t, builder.Warnings())
+
+	// Invalid values fall back to the default
+	builder = NewClientBuilder().WithResponseHeaderTimeout(time.Millisecond)
+	builder.Build()
+	assert.Equal(t, DefaultResponseHeaderTimeout, builder.ResponseHeaderTimeout())
+	assert.Len(t, builder.Warnings(), 1)
+}
+
+func TestClientBuilder_WithResponseHeaderTimeoutRetriesStalledServer(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first request stalls before answering
+		if requests.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	httpClient := NewClientBuilder().
+		WithResponseHeaderTimeout(200 * time.Millisecond).
+		WithRetryStrategy(FixedDelayStrategy).
+		WithRetryBaseDelay(ValidMinBaseDelay).
+		Build()
+
+	resp, err := httpClient.Get(server.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestClientBuilder_HTTP2Settings(t *testing.T) {
+	transportOf := func(httpClient *http.Client) *http.Transport {
+		rt, _ := httpClient.Transport.(*retryTransport)
+		transport, _ := rt.Transport.(*http.Transport)
+		return transport
+	}
+
+	transport := transportOf(NewClientBuilder().Build())
+	assert.True(t, transport.ForceAttemptHTTP2)
+	assert.Nil(t, transport.TLSNextProto)
+
+	transport = transportOf(NewClientBuilder().WithForceAttemptHTTP2(false).Build())
+	assert.False(t, transport.ForceAttemptHTTP2)
+
+	transport = transportOf(NewClientBuilder().WithDisableHTTP2(true).Build())
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto)
+	assert.Empty(t, transport.TLSNextProto)
+}
+
+func TestClientBuilder_WithDisableHTTP2UsesHTTP1(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	tlsConfig := server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	for _, tt := range []struct {
+		disableHTTP2 bool
+		expected     int
+	}{
+		{disableHTTP2: false, expected: 2},
+		{disableHTTP2: true, expected: 1},
+	} {
+		httpClient := NewClientBuilder().
+			WithTLSConfig(tlsConfig.Clone()).
+			WithDisableHTTP2(tt.disableHTTP2).
+			Build()
+
+		resp, err := httpClient.Get(server.URL)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		resp.Body.Close()
+		assert.Equal(t, tt.expected, resp.ProtoMajor)
+	}
+}
+
+func TestClientBuilder_WithMaxConnsPerHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    int
+		expected int
+		warnings int
+	}{
+		{name: "Unlimited", value: 0, expected: 0, warnings: 0},
+		{name: "Valid", value: 10, expected: 10, warnings: 0},
+		{name: "Negative", value: -1, expected: DefaultMaxConnsPerHost, warnings: 1},
+		{name: "Too Large", value: ValidMaxMaxConnsPerHost + 1, expected: DefaultMaxConnsPerHost, warnings: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewClientBuilder().WithMaxConnsPerHost(tt.value)
+			httpClient := builder.Build()
+			rt, _ := httpClient.Transport.(*retryTransport)
+			transport, _ := rt.Transport.(*http.Transport)
+			assert.Equal(t, tt.expected, transport.MaxConnsPerHost)
+			assert.Len(t, builder.Warnings(), tt.warnings)
+		})
+	}
+}
+
+func TestClientBuilder_WithBufferSizes(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    int
+		expected int
+		warnings int
+	}{
+		{name: "Default", value: 0, expected: 0, warnings: 0},
+		{name: "Valid", value: 64 << 10, expected: 64 << 10, warnings: 0},
+		{name: "Too Small", value: ValidMinWriteBufferSize - 1, expected: DefaultWriteBufferSize, warnings: 2},
+		{name: "Too Large", value: ValidMaxWriteBufferSize + 1, expected: DefaultWriteBufferSize, warnings: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewClientBuilder().WithWriteBufferSize(tt.value).WithReadBufferSize(tt.value)
+			httpClient := builder.Build()
+			rt, _ := httpClient.Transport.(*retryTransport)
+			transport, _ := rt.Transport.(*http.Transport)
+			assert.Equal(t, tt.expected, transport.WriteBufferSize)
+			assert.Equal(t, tt.expected, transport.ReadBufferSize)
+			assert.Len(t, builder.Warnings(), tt.warnings)
+		})
+	}
+}
+
+func TestClientBuilder_WithMaxResponseHeaderBytes(t *testing.T) {
+	builder := NewClientBuilder().WithMaxResponseHeaderBytes(4 << 10)
+	httpClient := builder.Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	transport, _ := rt.Transport.(*http.Transport)
+	assert.Equal(t, int64(4<<10), transport.MaxResponseHeaderBytes)
+	assert.Empty(t, builder.Warnings())
+
+	// Invalid values fall back to the default
+	builder = NewClientBuilder().WithMaxResponseHeaderBytes(ValidMaxResponseHeaderBytes + 1)
+	builder.Build()
+	assert.Equal(t, DefaultMaxResponseHeaderBytes, builder.MaxResponseHeaderBytes())
+	assert.Len(t, builder.Warnings(), 1)
+
+	// Oversized headers aren't retried
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("X-Large", strings.Repeat("a", 2*ValidMinResponseHeaderBytes))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	httpClient = NewClientBuilder().WithMaxResponseHeaderBytes(ValidMinResponseHeaderBytes).Build()
+	_, err := httpClient.Get(server.URL)
+	assert.ErrorContains(t, err, "server response headers exceeded")
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestClientBuilder_WithCookieJar(t *testing.T) {
+	assert.Nil(t, NewClientBuilder().Build().Jar)
+
+	jar, _ := cookiejar.New(nil)
+	assert.Same(t, jar, NewClientBuilder().WithCookieJar(jar).Build().Jar)
+}
+
+func TestClientBuilder_WithDefaultCookieJarPersistsAcrossRetries(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			// The failed attempt hands out a session cookie
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "abc" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	httpClient := NewClientBuilder().
+		WithDefaultCookieJar().
+		WithRetryStrategy(FixedDelayStrategy).
+		WithRetryBaseDelay(ValidMinBaseDelay).
+		Build()
+	assert.NotNil(t, httpClient.Jar)
+
+	resp, err := httpClient.Get(server.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestClientBuilder_WithFixedJitter(t *testing.T) {
+	builder := NewClientBuilder().
+		WithRetryStrategy(FixedDelayStrategy).
+		WithRetryBaseDelay(time.Second).
+		WithFixedJitter(0.5)
+	httpClient := builder.Build()
+	assert.Empty(t, builder.Warnings())
+
+	rt, _ := httpClient.Transport.(*retryTransport)
+	for attempt := range 20 {
+		delay := rt.RetryStrategy(attempt)
+		assert.GreaterOrEqual(t, delay, time.Second)
+		assert.LessOrEqual(t, delay, 1500*time.Millisecond)
+	}
+
+	// Out of range fractions fall back to no jitter
+	for _, fraction := range []float64{-0.1, 1.5, math.NaN()} {
+		builder = NewClientBuilder().
+			WithRetryStrategy(FixedDelayStrategy).
+			WithRetryBaseDelay(time.Second).
+			WithFixedJitter(fraction)
+		httpClient = builder.Build()
+		assert.Len(t, builder.Warnings(), 1)
+
+		rt, _ = httpClient.Transport.(*retryTransport)
+		assert.Equal(t, time.Second, rt.RetryStrategy(0))
+	}
+}
+
 func TestStrategyString(t *testing.T) {
 	assert.Equal(t, "fixed", FixedDelayStrategy.String())
 	assert.Equal(t, "jitter", JitterBackoffStrategy.String())
@@ -190,6 +1226,12 @@ func TestClientBuilder_WithRetryStrategyAsString(t *testing.T) {
 			expectedType:  ExponentialBackoffStrategy,
 			expectWarning: false,
 		},
+		{
+			name:          "Valid Decorrelated Strategy",
+			inputStrategy: "decorrelated",
+			expectedType:  DecorrelatedJitterStrategy,
+			expectWarning: false,
+		},
 		{
 			name:          "Invalid Strategy",
 			inputStrategy: "invalid-strategy",
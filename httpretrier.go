@@ -1,45 +1,124 @@
 package httpretrier
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"io"
+	"log/slog"
+	"math"
 	"math/rand"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 )
 
 var ErrAllRetriesFailed = errors.New("all retry attempts failed")
 
-// RetryStrategy defines the function signature for different retry strategies
+// RetryError is returned when all retry attempts fail.
+// It matches ErrAllRetriesFailed with errors.Is and unwraps to the last
+// transport error, if any.
+type RetryError struct {
+	// Attempts is the number of attempts made
+	Attempts int
+
+	// StatusCode is the status of the last response, or 0 if the last
+	// attempt failed with a transport error
+	StatusCode int
+
+	// Err is the last transport error, or nil if the last attempt got a response
+	Err error
+
+	// Outcome classifies the last attempt
+	Outcome Outcome
+}
+
+func (e *RetryError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("all retries failed; last error: %v", e.Err)
+	}
+
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%v: last attempt failed with status %d", ErrAllRetriesFailed, e.StatusCode)
+	}
+
+	return ErrAllRetriesFailed.Error()
+}
+
+// newRetryError builds the error returned once retrying is over, from the
+// result of the last attempt
+func newRetryError(attempts int, resp *http.Response, err error) *RetryError {
+	retryErr := &RetryError{
+		Attempts: attempts,
+		Err:      err,
+		Outcome:  ClassifyOutcome(resp, err),
+	}
+
+	// If the last attempt resulted in a response without a transport error,
+	// keep the status code for a more specific error
+	if err == nil && resp != nil {
+		retryErr.StatusCode = resp.StatusCode
+	}
+
+	return retryErr
+}
+
+func (e *RetryError) Unwrap() []error {
+	if e.Err != nil {
+		return []error{ErrAllRetriesFailed, e.Err}
+	}
+
+	return []error{ErrAllRetriesFailed}
+}
+
+// RetryStrategy defines the function signature for different retry strategies.
+// It returns the delay before a retry, where attempt counts the retries from 0:
+// attempt 0 is the delay after the first request failed, before the first
+// retry, attempt 1 the delay before the second retry, and so on. Exponential
+// strategies therefore wait base, base*2, base*4, ... before the successive retries.
 type RetryStrategy func(attempt int) time.Duration
 
 // ExponentialBackoff returns a RetryStrategy that calculates delays
-// growing exponentially with each retry attempt, starting from base
-// and capped at maxDelay.
+// growing exponentially with each retry attempt, base * 2^attempt, so the
+// first retry waits base. Every delay is capped at maxDelay, including the
+// first one when base is larger.
 func ExponentialBackoff(base, maxDelay time.Duration) RetryStrategy {
 	return func(attempt int) time.Duration {
-		// Special case from test: If base > maxDelay, the first attempt returns base,
-		// subsequent attempts calculate normally and cap at maxDelay.
-		if attempt == 0 && base > maxDelay {
-			return base
+		// Cap at maxDelay before calculating base * 2^attempt, so that the
+		// result never overflows, even when maxDelay is the largest Duration
+		if attempt >= 63 || base > maxDelay>>uint(attempt) {
+			return maxDelay
 		}
+		delay := base << uint(attempt)
 
-		// Calculate delay: base * 2^attempt
-		// Use uint for bit shift robustness, though overflow is unlikely before capping.
-		delay := base * (1 << uint(attempt))
-
-		// Cap at maxDelay. Also handle potential overflow resulting in negative/zero delay.
-		if delay > maxDelay || delay <= 0 {
+		// Non-positive bases get the cap too
+		if delay <= 0 {
 			delay = maxDelay
 		}
-		// Note: The original check `if delay < base { delay = base }` is removed
-		// as the logic now correctly handles the base > maxDelay case based on the test,
-		// and for base <= maxDelay, the calculated delay won't be less than base for attempt >= 0.
 		return delay
 	}
 }
 
+// ExponentialBackoffWithFactor returns a RetryStrategy like ExponentialBackoff
+// but growing by factor instead of 2, i.e. base * factor^attempt, capped at
+// maxDelay. A gentler factor such as 1.5 spreads retries over more attempts,
+// while a larger one backs off more aggressively.
+// Results too large to represent are clamped to maxDelay.
+func ExponentialBackoffWithFactor(base, maxDelay time.Duration, factor float64) RetryStrategy {
+	return func(attempt int) time.Duration {
+		delay := float64(base) * math.Pow(factor, float64(attempt))
+
+		// Cap at maxDelay, which also covers overflow to +Inf and NaN results.
+		// float64(maxDelay) may round up, so the cap itself is excluded, which
+		// keeps the conversion below from overflowing when maxDelay is the largest Duration
+		if !(delay < float64(maxDelay)) || delay < 0 {
+			return maxDelay
+		}
+
+		return time.Duration(delay)
+	}
+}
+
 // FixedDelay returns a RetryStrategy that provides a constant delay
 // for each retry attempt.
 func FixedDelay(delay time.Duration) RetryStrategy {
@@ -48,99 +127,969 @@ func FixedDelay(delay time.Duration) RetryStrategy {
 	}
 }
 
+// FixedDelayWithJitter returns a RetryStrategy that adds a random jitter of up
+// to delay*jitterFraction to a constant delay, so clients failing at the same
+// time don't retry in lockstep. A jitterFraction <= 0 adds no jitter, and
+// values above 1 are capped at 1.
+func FixedDelayWithJitter(delay time.Duration, jitterFraction float64) RetryStrategy {
+	return fixedDelayWithJitter(delay, jitterFraction, rand.Int63n)
+}
+
+// fixedDelayWithJitter is FixedDelayWithJitter with the random number generator injected
+func fixedDelayWithJitter(delay time.Duration, jitterFraction float64, int63n func(n int64) int64) RetryStrategy {
+	// Written so that NaN adds no jitter too
+	if !(jitterFraction > 0) {
+		return FixedDelay(delay)
+	}
+	jitterFraction = min(jitterFraction, 1.0)
+
+	maxJitter := int64(float64(delay) * jitterFraction)
+	if maxJitter <= 0 {
+		return FixedDelay(delay)
+	}
+
+	return func(attempt int) time.Duration {
+		return delay + time.Duration(int63n(maxJitter+1))
+	}
+}
+
 // JitterBackoff returns a RetryStrategy that adds a random jitter
 // to the exponential backoff delay calculated using base and maxDelay.
 func JitterBackoff(base, maxDelay time.Duration) RetryStrategy {
-	expBackoff := ExponentialBackoff(base, maxDelay)
+	return JitterBackoffWithSource(base, maxDelay, nil)
+}
+
+// JitterBackoffWithSource is like JitterBackoff but draws the jitter from src
+// instead of the package-level random source, so a fixed seed yields
+// reproducible delays. If src is nil, the package-level source is used.
+// The returned strategy is safe for concurrent use; access to src is
+// serialized internally, so src must not be used elsewhere.
+func JitterBackoffWithSource(base, maxDelay time.Duration, src *rand.Rand) RetryStrategy {
+	return jitterBackoff(ExponentialBackoff(base, maxDelay), (&lockedRand{rng: src}).Int63n, 0)
+}
+
+// jitterBackoff is JitterBackoff with the exponential strategy and the random
+// number generator injected, so a request-scoped generator or a different
+// growth factor can be used. A positive maxJitter bounds the jitter.
+func jitterBackoff(expBackoff RetryStrategy, int63n func(n int64) int64, maxJitter time.Duration) RetryStrategy {
 	return func(attempt int) time.Duration {
 		baseDelay := expBackoff(attempt)
-		// Add jitter: random duration between 0 and baseDelay/2
-		jitter := time.Duration(rand.Int63n(int64(baseDelay / 2)))
+		// Int63n panics on n <= 0, so delays too small to halve get no jitter
+		half := int64(jitterRange(baseDelay, maxJitter))
+		if half <= 0 {
+			return baseDelay
+		}
+		// Add jitter: random duration between 0 and baseDelay/2, or maxJitter
+		jitter := time.Duration(int63n(half))
+		if baseDelay > math.MaxInt64-jitter { // Uncapped delays saturate rather than overflow
+			return math.MaxInt64
+		}
 		return baseDelay + jitter
 	}
 }
 
+// capDelay returns a RetryStrategy clamping the delays of strategy to maxDelay
+func capDelay(strategy RetryStrategy, maxDelay time.Duration) RetryStrategy {
+	return func(attempt int) time.Duration {
+		return min(strategy(attempt), maxDelay)
+	}
+}
+
+// jitterRange returns the range of the random jitter for an exponential
+// delay: half the delay, bounded by maxJitter when it is positive
+func jitterRange(expDelay, maxJitter time.Duration) time.Duration {
+	if maxJitter > 0 {
+		return min(expDelay/2, maxJitter)
+	}
+
+	return expDelay / 2
+}
+
+// lockedRand serializes access to a *rand.Rand so a single seeded source can
+// be shared by concurrent requests. A nil rng uses the package-level source.
+type lockedRand struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// Int63n returns a non-negative pseudo-random number in [0,n)
+func (l *lockedRand) Int63n(n int64) int64 {
+	if l.rng == nil {
+		return rand.Int63n(n)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rng.Int63n(n)
+}
+
+// Float64 returns a pseudo-random number in [0.0,1.0)
+func (l *lockedRand) Float64() float64 {
+	if l.rng == nil {
+		return rand.Float64()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rng.Float64()
+}
+
+// DelayDistribution draws a random duration around mean.
+// u is a uniformly distributed number in [0.0,1.0) to transform.
+type DelayDistribution func(mean time.Duration, u float64) time.Duration
+
+// ExponentialDistribution draws exponentially distributed durations with the
+// given mean, the inter-arrival distribution of a Poisson process.
+// Most values fall below the mean with a long tail above it.
+func ExponentialDistribution(mean time.Duration, u float64) time.Duration {
+	return time.Duration(-math.Log(1-u) * float64(mean))
+}
+
+// UniformDistribution draws uniformly distributed durations in [0, 2*mean)
+func UniformDistribution(mean time.Duration, u float64) time.Duration {
+	return time.Duration(u * 2 * float64(mean))
+}
+
+// FullJitterBackoff returns a RetryStrategy that picks a random delay between
+// zero and the exponential backoff delay calculated using base and maxDelay
+// ("full jitter"). It spreads retries the most, at the cost of sometimes
+// retrying almost immediately.
+func FullJitterBackoff(base, maxDelay time.Duration) RetryStrategy {
+	return fullJitterBackoff(ExponentialBackoff(base, maxDelay), rand.Int63n)
+}
+
+// fullJitterBackoff is FullJitterBackoff with the exponential strategy and the
+// random number generator injected
+func fullJitterBackoff(expBackoff RetryStrategy, int63n func(n int64) int64) RetryStrategy {
+	return func(attempt int) time.Duration {
+		expDelay := expBackoff(attempt)
+		if expDelay <= 0 {
+			return 0
+		}
+		return time.Duration(int63n(int64(expDelay)))
+	}
+}
+
+// EqualJitterBackoff returns a RetryStrategy that keeps half of the exponential
+// backoff delay calculated using base and maxDelay and randomizes the other
+// half ("equal jitter"), so the delay is always at least half the exponential one.
+func EqualJitterBackoff(base, maxDelay time.Duration) RetryStrategy {
+	return equalJitterBackoff(ExponentialBackoff(base, maxDelay), rand.Int63n, 0)
+}
+
+// equalJitterBackoff is EqualJitterBackoff with the exponential strategy and the
+// random number generator injected. A positive maxJitter bounds the randomized
+// share of the delay.
+func equalJitterBackoff(expBackoff RetryStrategy, int63n func(n int64) int64, maxJitter time.Duration) RetryStrategy {
+	return func(attempt int) time.Duration {
+		expDelay := expBackoff(attempt)
+		half := jitterRange(expDelay, maxJitter)
+		if half <= 0 {
+			return expDelay
+		}
+		return (expDelay - half) + time.Duration(int63n(int64(half)))
+	}
+}
+
+// DecorrelatedJitter returns a RetryStrategy implementing "decorrelated jitter":
+// each delay is a random duration between base and three times the previous
+// delay, capped at maxDelay. This spreads retries out better than additive
+// jitter when many clients fail at once.
+//
+// The strategy is stateful: it remembers the previous delay, and attempt 0
+// resets it to base. It is safe for concurrent use, but interleaving the
+// attempts of several requests on one instance mixes their state, so each
+// request should use its own instance. Clients built with
+// DecorrelatedJitterStrategy do this automatically.
+func DecorrelatedJitter(base, maxDelay time.Duration) RetryStrategy {
+	return decorrelatedJitter(base, maxDelay, rand.Int63n)
+}
+
+// decorrelatedJitter is DecorrelatedJitter with the random number generator injected
+func decorrelatedJitter(base, maxDelay time.Duration, int63n func(n int64) int64) RetryStrategy {
+	return newDecorrelatedJitterBackoff(base, maxDelay, int63n).Next
+}
+
 // retryTransport wraps http.RoundTripper to add retry logic
 type retryTransport struct {
 	Transport     http.RoundTripper // Underlying transport (e.g., http.DefaultTransport)
 	MaxRetries    int
 	RetryStrategy RetryStrategy // The strategy function to calculate delay
+
+	// RequestSeed derives a seed from the request. When set together with
+	// SeededStrategy, each request gets its own strategy driven by a random
+	// number generator seeded with that value, so replaying the same request
+	// yields the same backoff sequence.
+	RequestSeed    func(req *http.Request) int64
+	SeededStrategy func(rng *rand.Rand) RetryStrategy
+
+	// NewStrategy, when set, creates a fresh strategy for every request.
+	// It is used by stateful strategies such as DecorrelatedJitter, whose
+	// state must not be shared between concurrent requests.
+	NewStrategy func() RetryStrategy
+
+	// NewBackoff, when set, creates a fresh Backoff for every request, for
+	// stateful backoffs needing no other wrapping, taking precedence over
+	// NewStrategy. Stateless strategies are reused rather than recreated.
+	NewBackoff func() Backoff
+
+	// Backoff, when set, computes the delays instead of the strategies above.
+	// It is Reset at the start of every request.
+	Backoff Backoff
+
+	// stop, when set, lets a shutdown signal abandon pending retries
+	stop *signalStop
+
+	// hostLimiter, when set, caps concurrent attempts per host
+	hostLimiter *hostLimiter
+
+	// circuitBreaker, when set, stops sending attempts to hosts failing
+	// consecutively, returning ErrCircuitOpen instead
+	circuitBreaker *circuitBreaker
+
+	// adaptiveBackoff, when set, lengthens the delays of the retries to hosts
+	// that have been failing recently
+	adaptiveBackoff *adaptiveBackoff
+
+	// MaxHedges, when positive, sends up to this many speculative copies of
+	// each attempt of an idempotent request, HedgeDelay apart, as long as no
+	// response worth keeping has arrived. The first one wins and the others
+	// are canceled. Every copy adds load on the server.
+	MaxHedges  int
+	HedgeDelay time.Duration
+
+	// FallbackHosts, when set, are tried in order once the retries against
+	// the request's host are used up, each with the full retry strategy.
+	// The request's URL is rewritten to the fallback host, keeping its scheme.
+	FallbackHosts []string
+
+	// rateLimiter, when set, caps the rate of attempts across all requests
+	rateLimiter *rateLimiter
+
+	// retryBudget, when set, caps the retries to a share of the requests
+	retryBudget *retryBudget
+
+	// inFlight, when set, is a semaphore capping the requests in flight
+	// across all hosts. A request holds its slot from the first attempt until
+	// RoundTrip returns.
+	inFlight chan struct{}
+
+	// RetryCondition, when set, replaces the default decision of which
+	// responses and errors are retried
+	RetryCondition RetryCondition
+
+	// RetryableStatuses, when set, are the status ranges worth retrying,
+	// replacing the default of retrying 5xx responses
+	RetryableStatuses []StatusRange
+
+	// NoRetryStatuses, when set, are statuses never retried, whatever the
+	// other rules, RetryCondition included, say
+	NoRetryStatuses []int
+
+	// RequiredHeader, when set, makes responses lacking this header retryable,
+	// in addition to the status code checks
+	RequiredHeader string
+
+	// ExpectedContentType, when set, makes 2xx responses whose Content-Type
+	// has another media type retryable, e.g. an HTML error page served with
+	// a 200 status by a misconfigured gateway. It is a lowercase media type,
+	// such as "application/json", or "type/*" to accept any subtype.
+	ExpectedContentType string
+
+	// RespectRetryAfter, when true, makes the Retry-After header of a
+	// retryable response, in seconds or as an HTTP date, replace the
+	// strategy's delay
+	RespectRetryAfter bool
+
+	// StatusDelays, when set, are the delays to wait after responses with
+	// the given statuses, instead of the strategy's delay. A honored
+	// Retry-After header still takes precedence.
+	StatusDelays map[int]time.Duration
+
+	// DryRun, when true, skips the delays between attempts, only logging
+	// them, while the attempts and retry decisions are unchanged
+	DryRun bool
+
+	// MaxElapsedTime, when positive, bounds the total time spent across all
+	// attempts and backoff delays, independently of MaxRetries
+	MaxElapsedTime time.Duration
+
+	// MinAttemptBudget is the least time an attempt needs before the
+	// deadline of the request or of MaxElapsedTime. A retry that would start
+	// with less time left is skipped, returning the last result right away
+	// rather than after a useless backoff delay
+	MinAttemptBudget time.Duration
+
+	// ConnectTimeoutStrategy, when set, computes the delay after attempts that
+	// timed out while connecting, instead of the general strategy
+	ConnectTimeoutStrategy RetryStrategy
+
+	// MinDelay is the floor of every delay, whichever strategy computed it
+	MinDelay time.Duration
+
+	// PerAttemptTimeout, when positive, bounds each individual attempt with its
+	// own deadline derived from the request context, so a hung attempt fails
+	// fast and is retried. The deadline also covers reading the response body.
+	PerAttemptTimeout time.Duration
+
+	// AutoBufferBody, when true, reads request bodies lacking GetBody into
+	// memory before the first attempt so they can be replayed on retries.
+	// Bodies implementing io.Seeker are rewound instead, without buffering.
+	// Bodies larger than MaxBufferSize (if positive) are sent once, without
+	// buffering, and are not retried.
+	AutoBufferBody bool
+	MaxBufferSize  int64
+
+	// OnBufferTruncated, when set, is called when a body can't be buffered,
+	// because it exceeds MaxBufferSize or the buffer budget, and the request is
+	// therefore attempted only once. It receives the body's ContentLength,
+	// which is 0 or -1 when unknown.
+	OnBufferTruncated func(size int64)
+
+	// auth, when set, sets credentials on every attempt and refreshes them
+	// when a response is 401 Unauthorized
+	auth *authRefresher
+
+	// IdempotencyKeyHeader, when set, names a header carrying a random key
+	// generated once per non-idempotent request, e.g. a POST, and sent with
+	// each of its attempts, so the server can deduplicate the retries.
+	// Requests already carrying the header keep their key.
+	IdempotencyKeyHeader string
+
+	// ResponseValidator, when set, receives the body of every response that
+	// would otherwise be a success, buffered up to MaxValidateBodySize bytes,
+	// and makes the attempt retryable if it returns false. The response is
+	// returned with the buffered body. Larger bodies are not validated.
+	ResponseValidator   func(resp *http.Response, body []byte) bool
+	MaxValidateBodySize int64
+
+	// DefaultHeaders are added to every attempt's request, with canonical keys.
+	// The caller's values come first, default values they already include aren't repeated
+	DefaultHeaders http.Header
+
+	// UserAgent, when set, is sent as the User-Agent of the requests without one
+	UserAgent string
+
+	// AttemptHeader, when set, names a header carrying the attempt number,
+	// starting at 0, on every attempt's request
+	AttemptHeader string
+
+	// ReturnLastResponse, when true, returns the response of the last attempt
+	// once the retries are over, instead of a RetryError, leaving its body for
+	// the caller to read and close
+	ReturnLastResponse bool
+
+	// FreshConnOnError, when true, closes the idle connections of the
+	// underlying transport after an attempt failed on a broken connection,
+	// so the retry dials a new one rather than reusing a dead pooled one
+	FreshConnOnError bool
+
+	// idleConns, when set, is the underlying transport whose idle connections
+	// are closed, for when Transport is wrapped by middlewares. If nil,
+	// Transport is used if it supports it.
+	idleConns idleConnCloser
+
+	// Jar, when set, stores the cookies of failed attempts and sends them with
+	// the following retries. It should be the Jar of the http.Client using
+	// this transport, which handles the cookies of the returned response
+	Jar http.CookieJar
+
+	// bufferBudget, when set, caps the bytes buffered across concurrent requests
+	bufferBudget *bufferBudget
+
+	// RetryEvents, when set, receives a RetryEvent for every retry.
+	// Sends never block: events are dropped while the channel is full.
+	RetryEvents chan<- RetryEvent
+
+	// OnRetry, when set, is called with a RetryEvent for every retry, before
+	// waiting for the backoff delay. It runs on the request's goroutine, so it
+	// should return quickly.
+	OnRetry func(RetryEvent)
+
+	// OnRequestDone, when set, is called with the Stats of every request once
+	// RoundTrip is over, whatever its outcome
+	OnRequestDone func(Stats)
+
+	// Clock, when set, replaces the real clock for measuring elapsed time and
+	// sleeping between attempts
+	Clock Clock
+
+	// MaxDrainSize caps how many bytes of a failed attempt's response body are
+	// read before closing it. If zero, DefaultMaxDrainSize is used.
+	MaxDrainSize int64
+
+	// drainBuffers, when set, provides the buffers draining the response
+	// bodies, instead of the pool of DefaultDrainBufferSize buffers
+	drainBuffers *bufferPool
+
+	// Logger receives the retry log messages. If nil, slog.Default() is used.
+	Logger *slog.Logger
+
+	// requestRoundTripper, when set, is the retry logic wrapped by the
+	// request middlewares, through which RoundTrip sends requests
+	requestRoundTripper http.RoundTripper
+
+	// config describes the settings the transport was built with
+	config RetryConfig
+}
+
+// nextDelay returns how long to wait after the given failed attempt
+func (r *retryTransport) nextDelay(backoff Backoff, attempt int, err error) time.Duration {
+	var delay time.Duration
+	// Unreachable hosts get their own, usually harsher, backoff
+	if r.ConnectTimeoutStrategy != nil && isConnectTimeout(err) {
+		delay = r.ConnectTimeoutStrategy(attempt)
+	} else {
+		delay = backoff.Next(attempt)
+	}
+
+	// Build keeps the floor within the max delay, so raising the delay to it
+	// never goes past the cap
+	return max(delay, r.MinDelay)
+}
+
+// timeLeft returns the time left before the earliest of the request context's
+// deadline, which includes the http.Client timeout, and the end of the
+// MaxElapsedTime budget. The context deadline is wall-clock time, while the
+// budget is measured on the transport's clock like the backoff delays
+func (r *retryTransport) timeLeft(ctx context.Context, start time.Time) (time.Duration, bool) {
+	var left time.Duration
+	deadline, ok := ctx.Deadline()
+	if ok {
+		left = time.Until(deadline)
+	}
+	if r.MaxElapsedTime > 0 {
+		if budgetLeft := r.MaxElapsedTime - r.clock().Now().Sub(start); !ok || budgetLeft < left {
+			left, ok = budgetLeft, true
+		}
+	}
+
+	return left, ok
+}
+
+// attemptRequest returns a clone of req to send for a single attempt, carrying
+// info in its context and bounded by PerAttemptTimeout when set, along with
+// the function releasing its context.
+// The request context still bounds the operation as a whole.
+func (r *retryTransport) attemptRequest(req *http.Request, info AttemptInfo) (*http.Request, context.CancelFunc) {
+	ctx := context.WithValue(req.Context(), AttemptInfoKey{}, info)
+	if r.PerAttemptTimeout <= 0 {
+		return req.Clone(ctx), func() {}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.PerAttemptTimeout)
+	return req.Clone(ctx), cancel
+}
+
+// idleConnCloser is implemented by transports pooling connections, such as http.Transport
+type idleConnCloser interface {
+	CloseIdleConnections()
+}
+
+// closeIdleConnections closes the idle connections of the underlying
+// transport, if it pools connections
+func (r *retryTransport) closeIdleConnections(transport http.RoundTripper) {
+	closer := r.idleConns
+	if closer == nil {
+		closer, _ = transport.(idleConnCloser)
+	}
+	if closer != nil {
+		r.logger().Debug("Closing idle connections after a connection error")
+		closer.CloseIdleConnections()
+	}
+}
+
+// CloseIdleConnections closes the idle connections of the underlying
+// transport, if it pools connections. It makes http.Client.CloseIdleConnections
+// reach the transport wrapped by the retry logic.
+func (r *retryTransport) CloseIdleConnections() {
+	closer := r.idleConns
+	if closer == nil {
+		closer, _ = r.Transport.(idleConnCloser)
+	}
+	if closer != nil {
+		closer.CloseIdleConnections()
+	}
+}
+
+// clock returns the transport's Clock, defaulting to the real clock
+func (r *retryTransport) clock() Clock {
+	if r.Clock == nil {
+		return realClock{}
+	}
+
+	return r.Clock
+}
+
+// drainBufferPool returns the transport's pool of drain buffers, defaulting to
+// the pool shared by the transports without one
+func (r *retryTransport) drainBufferPool() *bufferPool {
+	if r.drainBuffers == nil {
+		return defaultDrainBuffers
+	}
+
+	return r.drainBuffers
+}
+
+// maxDrainSize returns the transport's MaxDrainSize, defaulting to DefaultMaxDrainSize
+func (r *retryTransport) maxDrainSize() int64 {
+	if r.MaxDrainSize <= 0 {
+		return DefaultMaxDrainSize
+	}
+
+	return r.MaxDrainSize
+}
+
+// logger returns the transport's Logger, defaulting to slog.Default()
+func (r *retryTransport) logger() *slog.Logger {
+	if r.Logger == nil {
+		return slog.Default()
+	}
+
+	return r.Logger
 }
 
+// shouldRetry reports whether the result of an attempt warrants a retry
+func (r *retryTransport) shouldRetry(resp *http.Response, err error) bool {
+	// Excluded statuses win over the rules deciding which responses are retried
+	if noRetryStatus(r.NoRetryStatuses, resp) {
+		return false
+	}
+
+	if r.RetryCondition != nil {
+		return r.RetryCondition(resp, err)
+	}
+
+	if err != nil {
+		return isRetryable(err)
+	}
+
+	if retryableStatus(r.RetryableStatuses, resp.StatusCode) {
+		return true
+	}
+
+	// Gateways short-circuiting with a cached error may omit a header the
+	// real backend always sets
+	if r.RequiredHeader != "" && resp.Header.Get(r.RequiredHeader) == "" {
+		return true
+	}
+
+	// Misconfigured gateways may answer with an error page and a 2xx status
+	if r.ExpectedContentType != "" && resp.StatusCode >= 200 && resp.StatusCode < 300 &&
+		!contentTypeMatches(resp.Header.Get("Content-Type"), r.ExpectedContentType) {
+		return true
+	}
+
+	return false
+}
+
+// backoffFor returns the backoff to use for the given request
+func (r *retryTransport) backoffFor(req *http.Request) Backoff {
+	// A strategy set for this request wins over the transport's settings
+	if options := requestRetryOptionsFromContext(req.Context()); options != nil && options.strategy != nil {
+		return StrategyBackoff(options.strategy)
+	}
+
+	if r.Backoff != nil {
+		return r.Backoff
+	}
+
+	if r.RequestSeed != nil && r.SeededStrategy != nil {
+		return StrategyBackoff(r.SeededStrategy(rand.New(rand.NewSource(r.RequestSeed(req)))))
+	}
+
+	if r.NewBackoff != nil {
+		return r.NewBackoff()
+	}
+
+	if r.NewStrategy != nil {
+		return StrategyBackoff(r.NewStrategy())
+	}
+
+	// Stateless strategies are shared by the requests, so converting them
+	// doesn't allocate
+	if r.RetryStrategy != nil {
+		return StrategyBackoff(r.RetryStrategy)
+	}
+
+	return defaultBackoff
+}
+
+// defaultBackoff is the basic exponential backoff of transports without a
+// strategy, created once rather than for every request
+var defaultBackoff = StrategyBackoff(ExponentialBackoff(500*time.Millisecond, 10*time.Second))
+
 // RoundTrip executes an HTTP request with retry logic
 func (r *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	var resp *http.Response
-	var err error
+	if r.requestRoundTripper != nil {
+		return r.requestRoundTripper.RoundTrip(req)
+	}
+
+	return r.roundTrip(req)
+}
 
+// roundTrip executes an HTTP request with retry logic, under the request middlewares if any
+func (r *retryTransport) roundTrip(req *http.Request) (*http.Response, error) {
 	// Ensure transport is set
 	transport := r.Transport
 	if transport == nil {
 		transport = http.DefaultTransport
 	}
 
-	// Ensure a retry strategy is set, default to a basic exponential backoff
-	retryStrategy := r.RetryStrategy
-	if retryStrategy == nil {
-		retryStrategy = ExponentialBackoff(500*time.Millisecond, 10*time.Second) // Default strategy
+	// Refresh the credentials and resend right away on 401 responses
+	if r.auth != nil {
+		transport = &authTransport{next: transport, auth: r.auth}
 	}
 
-	for attempt := 0; attempt <= r.MaxRetries; attempt++ {
-		// Clone the request body if it exists and is GetBody is defined
-		// This allows the body to be read multiple times on retries
-		if req.Body != nil && req.GetBody != nil {
-			bodyClone, err := req.GetBody()
+	// Collect the request's stats for OnRequestDone, on top of the ones
+	// DoWithStats may be collecting across redirects
+	if r.OnRequestDone != nil {
+		stats := statsFromContext(req.Context())
+		if stats == nil {
+			stats = &Stats{}
+			req = req.WithContext(context.WithValue(req.Context(), statsKey{}, stats))
+		}
+		before := *stats
+		defer func() { r.OnRequestDone(stats.since(before)) }()
+	}
+
+	// Let the server recognize the retries of non-idempotent requests
+	req = r.withIdempotencyKey(req)
+
+	// Every request feeds the retry budget, whether it is retried or not
+	if r.retryBudget != nil {
+		r.retryBudget.recordRequest(r.clock().Now())
+	}
+
+	// Wait for an in-flight slot, held by the request across all its attempts
+	if r.inFlight != nil {
+		select {
+		case r.inFlight <- struct{}{}:
+			defer func() { <-r.inFlight }()
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	// Work out how each attempt gets its body, making bodies without GetBody
+	// replayable when auto-buffering is enabled
+	body, err := r.newRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	defer body.release()
+
+	// The request may set its own number of retries, but a body too large
+	// to buffer can't be replayed: send it once and let the caller know
+	// retries were skipped
+	maxRetries := r.MaxRetries
+	if options := requestRetryOptionsFromContext(req.Context()); options != nil && options.maxRetriesSet {
+		maxRetries = options.maxRetries
+	}
+	if body.truncated {
+		maxRetries = 0
+		if r.OnBufferTruncated != nil {
+			r.OnBufferTruncated(req.ContentLength)
+		}
+	}
+
+	resp, exhausted, err := r.retryHost(transport, req, body, maxRetries)
+	if !exhausted || len(r.FallbackHosts) == 0 || body.truncated {
+		return resp, err
+	}
+
+	// The retries against the request's host are used up, go on with the fallbacks
+	return r.retryFallbackHosts(transport, req, body, maxRetries, resp, err)
+}
+
+// retryHost sends req, retrying it up to maxRetries times according to the
+// transport's settings. exhausted reports whether it gave up because the
+// retries or the time budget were used up, or the host's circuit breaker is
+// open, rather than because of a success, a permanent error or a cancellation.
+func (r *retryTransport) retryHost(transport http.RoundTripper, req *http.Request, body *requestBody, maxRetries int) (resp *http.Response, exhausted bool, err error) {
+	// Ensure a backoff is set, default to a basic exponential backoff, and
+	// start it over for this request
+	backoff := r.backoffFor(req)
+	backoff.Reset()
+
+	// Track the time spent across all attempts for the MaxElapsedTime budget
+	start := r.clock().Now()
+
+	// Stats requested by DoWithStats, nil otherwise
+	stats := statsFromContext(req.Context())
+
+	// Backoff delay waited before the current attempt, and in total
+	var delay, backoffTime time.Duration
+
+	// Retries to a host that has been failing recently wait longer, as of
+	// when the request started
+	delayFactor := 1.0
+	if r.adaptiveBackoff != nil {
+		delayFactor = r.adaptiveBackoff.factor(req.URL.Host)
+	}
+
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		// Don't start a new retry once shutdown has begun
+		if attempt > 0 && r.stop != nil && r.stop.isStopped() {
+			return nil, false, ErrStopped
+		}
+
+		// Each attempt works on its own clone of the request, with its own
+		// deadline, so the caller's request is never modified
+		attemptReq, cancel := r.attemptRequest(req, AttemptInfo{Attempt: attempt, Delay: delay})
+		attemptReq, conn := traceConn(attemptReq)
+
+		// Fail fast while the host keeps failing
+		var probe bool
+		if r.circuitBreaker != nil {
+			var err error
+			if probe, err = r.circuitBreaker.allow(req.URL.Host, r.clock().Now()); err != nil {
+				cancel()
+				return nil, true, fmt.Errorf("%w for host %s", err, req.URL.Host)
+			}
+		}
+
+		// Every attempt, retries included, waits for its turn under the rate
+		// limit. An attempt giving up while waiting was never sent, so it
+		// must not hold the breaker's half-open probe
+		if r.rateLimiter != nil {
+			if err := r.rateLimiter.wait(req.Context(), r.clock()); err != nil {
+				if r.circuitBreaker != nil {
+					r.circuitBreaker.abandon(req.URL.Host, probe)
+				}
+				cancel()
+				return nil, false, err
+			}
+		}
+
+		// Wait for a per-host slot, held until the attempt's response is closed
+		if r.hostLimiter != nil {
+			if err := r.hostLimiter.acquire(req.Context(), req.URL.Host); err != nil {
+				if r.circuitBreaker != nil {
+					r.circuitBreaker.abandon(req.URL.Host, probe)
+				}
+				cancel()
+				return nil, false, err
+			}
+		}
+
+		// The body is only opened once the attempt is cleared to go, so a
+		// rejected attempt has nothing to close
+		if err := body.apply(attemptReq); err != nil {
+			if r.hostLimiter != nil {
+				r.hostLimiter.release(req.URL.Host)
+			}
+			if r.circuitBreaker != nil {
+				r.circuitBreaker.abandon(req.URL.Host, probe)
+			}
+			cancel()
+			return nil, false, err
+		}
+
+		r.applyDefaultHeaders(attemptReq)
+		r.applyUserAgent(attemptReq)
+
+		// Let the server tell retries apart from first tries
+		if r.AttemptHeader != "" {
+			if attemptReq.Header == nil {
+				attemptReq.Header = make(http.Header)
+			}
+			attemptReq.Header.Set(r.AttemptHeader, strconv.Itoa(attempt))
+		}
+
+		// Retries carry the cookies set by the previous attempts
+		if attempt > 0 {
+			r.applyCookies(attemptReq)
+		}
+
+		r.logger().Debug("Sending request", "attempt", attempt+1, "method", req.Method, "url", req.URL.String())
+		if r.MaxHedges > 0 && !body.shared && isHedgeable(attemptReq) {
+			resp, err = r.hedgedRoundTrip(transport, attemptReq)
+		} else {
+			resp, err = transport.RoundTrip(attemptReq)
+		}
+		stats.recordAttempt(resp)
+
+		// The host slot is held until the response body is closed, failed
+		// responses being closed before the backoff
+		if r.hostLimiter != nil {
+			host := req.URL.Host
+			if resp != nil && resp.Body != nil {
+				resp.Body = &releaseOnCloseBody{ReadCloser: resp.Body, release: func() { r.hostLimiter.release(host) }}
+			} else {
+				r.hostLimiter.release(host)
+			}
+		}
+
+		// Responses that would be a success must also pass the validator
+		var invalid bool
+		if err == nil && r.ResponseValidator != nil && !r.shouldRetry(resp, nil) && !noRetryStatus(r.NoRetryStatuses, resp) {
+			invalid, err = r.validateResponse(resp)
 			if err != nil {
-				return nil, fmt.Errorf("failed to get request body for retry: %w", err)
+				resp = nil
+			}
+		}
+
+		// Tell a per-attempt timeout apart from the request's own deadline
+		if err != nil && r.PerAttemptTimeout > 0 &&
+			errors.Is(attemptReq.Context().Err(), context.DeadlineExceeded) && req.Context().Err() == nil {
+			err = &attemptTimeoutError{err: err}
+		}
+
+		// A canceled or expired request is over: return right away, whatever
+		// the retry condition says
+		if err != nil && isContextError(err) {
+			if r.circuitBreaker != nil {
+				r.circuitBreaker.abandon(req.URL.Host, probe)
 			}
-			req.Body = bodyClone
+			cancel()
+			return nil, false, err
+		}
+
+		// A keep-alive connection closed by the server as it was reused never
+		// carried the request: retry it whatever the retry condition says
+		race := conn.idleConnRace(err)
+		retry := invalid || race || r.shouldRetry(resp, err)
+
+		// Attempts worth retrying count as failures of the host, except for
+		// connection races that aren't its fault
+		if r.circuitBreaker != nil {
+			r.circuitBreaker.record(req.URL.Host, probe, retry && !race, r.clock().Now())
+		}
+		if r.adaptiveBackoff != nil {
+			r.adaptiveBackoff.record(req.URL.Host, retry && !race)
 		}
 
-		resp, err = transport.RoundTrip(req)
+		// Permanent errors are returned as is, without retrying
+		if err != nil && !retry {
+			cancel()
+			return nil, false, err
+		}
 
-		// Success conditions: no error and status code below 500
-		if err == nil && resp.StatusCode < http.StatusInternalServerError {
-			return resp, nil
+		// Success conditions: no error and a response that doesn't warrant a retry
+		if err == nil && !retry {
+			return r.finishResponse(resp, attemptReq, responseInfo{attempts: attempt + 1, backoffTime: backoffTime}, cancel), false, nil
 		}
 
-		// If there was an error or a server-side error (5xx), prepare for retry
+		// If there was an error or a retryable response (e.g. 5xx), prepare for retry
+
+		// The retries are over once they are used up or the overall time
+		// budget is spent
+		remaining := r.MaxElapsedTime - r.clock().Now().Sub(start)
+		lastAttempt := attempt == maxRetries || (r.MaxElapsedTime > 0 && remaining <= 0)
+
+		// Don't sleep only to run out of time: give up now if, after the
+		// delay, the next attempt would have less than MinAttemptBudget left
+		// before the deadline. This includes a server asking, with
+		// Retry-After, to wait longer than the request can
+		if !lastAttempt {
+			// attempt is the index of the retry to come, from 0, so the
+			// first retry waits the strategy's base delay
+			// The delay is, by precedence, the Retry-After wait, the one set for
+			// the response's status, then the strategy's
+			delay = scaleDelay(r.nextDelay(backoff, attempt, err), delayFactor, r.config.MaxDelay)
+			if wait, ok := statusDelay(r.StatusDelays, resp); ok {
+				delay = max(wait, r.MinDelay)
+			}
+			if wait, ok := r.retryAfter(resp); ok {
+				delay = max(wait, r.MinDelay)
+			}
+			if left, ok := r.timeLeft(req.Context(), start); ok && delay > left-r.MinAttemptBudget {
+				r.logger().Debug("Not enough time left for another attempt", "attempt", attempt+1, "delay", delay, "timeLeft", left)
+				lastAttempt = true
+			}
+		}
+
+		// Retries shared by the whole client may be used up, even if this
+		// request has retries left
+		if !lastAttempt && r.retryBudget != nil && !r.retryBudget.tryRetry(r.clock().Now()) {
+			r.logger().Debug("Retry budget exhausted", "attempt", attempt+1)
+			lastAttempt = true
+		}
+
+		// Hand the last failed response back as is, if the caller asked for it
+		if lastAttempt && resp != nil && r.ReturnLastResponse {
+			return r.finishResponse(resp, attemptReq, responseInfo{attempts: attempt + 1, backoffTime: backoffTime}, cancel), true, nil
+		}
 
 		// Close response body to prevent resource leaks before retrying
 		if resp != nil {
-			// Drain the body before closing
-			_, copyErr := io.Copy(io.Discard, resp.Body)
-			closeErr := resp.Body.Close()
-			if copyErr != nil {
-				// Prioritize returning the copy error
-				return nil, fmt.Errorf("failed to discard response body: %w", copyErr)
+			r.storeCookies(req, resp)
+
+			// Drain a bounded amount of the body before closing, giving up if the
+			// request is canceled
+			drainErr, closeErr := drainBody(attemptReq.Context(), resp.Body, r.maxDrainSize(), r.drainBufferPool())
+			// The attempt failed anyway, often on a broken connection, so neither
+			// error stops the retries. The failed response's status is reported
+			// instead, being the more useful error
+			if drainErr != nil {
+				r.logger().Warn("Failed to discard response body", "attempt", attempt+1, "error", drainErr)
 			}
 			if closeErr != nil {
-				return nil, fmt.Errorf("failed to close response body: %w", closeErr)
+				r.logger().Warn("Failed to close response body", "attempt", attempt+1, "error", closeErr)
 			}
 		}
+		cancel()
 
-		// Check if we should retry
-		if attempt < r.MaxRetries {
-			delay := retryStrategy(attempt)
-			fmt.Printf("Attempt %d failed. Retrying after %v...\n", attempt+1, delay) // Consider using a logger
-			time.Sleep(delay)
-		} else {
+		if lastAttempt {
 			// Max retries reached, return the last error or a generic failure error
-			if err != nil {
-				return nil, fmt.Errorf("all retries failed; last error: %w", err)
+			return nil, true, newRetryError(attempt+1, resp, err)
+		}
+
+		// The pool may hold more connections as dead as this one, make the
+		// retry dial afresh
+		if race || (r.FreshConnOnError && isConnectionError(err)) {
+			r.closeIdleConnections(transport)
+		}
+
+		stats.recordDelay(delay)
+
+		if r.OnRetry != nil || r.RetryEvents != nil {
+			event := newRetryEvent(req, attempt+1, resp, err, delay)
+			if r.OnRetry != nil {
+				r.OnRetry(event)
 			}
-			// If the last attempt resulted in a 5xx response without a transport error
-			if resp != nil {
-				// Return a more specific error including the status code
-				return nil, fmt.Errorf("%w: last attempt failed with status %d", ErrAllRetriesFailed, resp.StatusCode)
+			if r.RetryEvents != nil {
+				sendRetryEvent(r.RetryEvents, event)
 			}
-			// Fallback generic error
-			return nil, ErrAllRetriesFailed
 		}
+
+		r.logger().Info("Attempt failed, retrying", "attempt", attempt+1, "delay", delay, "method", req.Method, "url", req.URL.String())
+		if err := r.wait(req.Context(), delay); err != nil {
+			return nil, false, err
+		}
+		backoffTime += delay
 	}
 
 	// This point should theoretically not be reached due to the loop logic,
 	// but return the generic error just in case.
-	return nil, ErrAllRetriesFailed
+	return nil, true, ErrAllRetriesFailed
+}
+
+// wait pauses for the given delay and returns an error if retrying should
+// not go on: ErrStopped if retries were stopped while waiting, or the context
+// error if ctx was canceled.
+func (r *retryTransport) wait(ctx context.Context, delay time.Duration) error {
+	if r.DryRun {
+		r.logger().Info("Dry run, not waiting before retrying", "delay", delay)
+		return ctx.Err()
+	}
+
+	if r.stop != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		defer context.AfterFunc(r.stop.ctx, cancel)()
+	}
+
+	if err := r.clock().Sleep(ctx, delay); err != nil {
+		if r.stop != nil && r.stop.isStopped() {
+			return ErrStopped
+		}
+		return err
+	}
+
+	return nil
 }
 
 // NewClient creates a new http.Client configured with the retry transport.
@@ -157,6 +1106,7 @@ func NewClient(maxRetries int, strategy RetryStrategy, baseTransport http.RoundT
 			Transport:     baseTransport,
 			MaxRetries:    maxRetries,
 			RetryStrategy: strategy,
+			AttemptHeader: DefaultAttemptHeader,
 		},
 	}
 }
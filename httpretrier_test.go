@@ -1,13 +1,21 @@
 package httpretrier
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -36,16 +44,119 @@ func TestExponentialBackoff(t *testing.T) {
 		}
 	}
 
-	// Test case where base > max (should cap at max, but logic ensures base is min)
+	// A base above max is capped from the first retry on
 	strategyHighBase := ExponentialBackoff(2*time.Second, 1*time.Second)
-	if delay := strategyHighBase(0); delay != 2*time.Second { // Should return base even if > max initially
-		t.Errorf("High base test: Expected delay %v, got %v", 2*time.Second, delay)
+	if delay := strategyHighBase(0); delay != 1*time.Second {
+		t.Errorf("High base test: Expected delay %v, got %v", 1*time.Second, delay)
 	}
-	if delay := strategyHighBase(1); delay != 1*time.Second { // Subsequent attempts capped at max
+	if delay := strategyHighBase(1); delay != 1*time.Second {
 		t.Errorf("High base test attempt 1: Expected delay %v, got %v", 1*time.Second, delay)
 	}
 }
 
+func TestRetryTransport_FirstRetriesDelays(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	retryRT := &retryTransport{
+		Transport: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("simulated transport error")
+			},
+		},
+		MaxRetries:    3,
+		RetryStrategy: ExponentialBackoff(100*time.Millisecond, 10*time.Second),
+		Clock:         clock,
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	_, _ = retryRT.RoundTrip(req)
+
+	// The first retry waits the base delay, then it doubles
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}
+	if !slices.Equal(clock.sleeps, expected) {
+		t.Errorf("Expected delays %v, got %v", expected, clock.sleeps)
+	}
+}
+
+func TestExponentialBackoff_Uncapped(t *testing.T) {
+	base := 500 * time.Millisecond
+	uncapped := time.Duration(math.MaxInt64)
+
+	strategies := map[string]RetryStrategy{
+		"Exponential":            ExponentialBackoff(base, uncapped),
+		"Exponential factor 1.5": ExponentialBackoffWithFactor(base, uncapped, 1.5),
+		"Exponential factor 10":  ExponentialBackoffWithFactor(base, uncapped, 10),
+		"Jitter":                 JitterBackoff(base, uncapped),
+		"Decorrelated":           DecorrelatedJitter(base, uncapped),
+	}
+
+	for name, strategy := range strategies {
+		t.Run(name, func(t *testing.T) {
+			// Delays grow past the usual caps without ever overflowing
+			if delay := strategy(20); delay <= ValidMaxMaxDelay && name != "Decorrelated" {
+				t.Errorf("Expected the delay after attempt 20 to exceed %v, got %v", ValidMaxMaxDelay, delay)
+			}
+			for attempt := range 200 {
+				if delay := strategy(attempt); delay <= 0 {
+					t.Fatalf("Attempt %d: Expected a positive delay, got %v", attempt, delay)
+				}
+			}
+		})
+	}
+
+	if delay := ExponentialBackoff(base, uncapped)(8); delay != base*256 {
+		t.Errorf("Expected %v, got %v", base*256, delay)
+	}
+	if delay := ExponentialBackoff(base, uncapped)(100); delay != uncapped {
+		t.Errorf("Expected the delay to saturate at %v, got %v", uncapped, delay)
+	}
+}
+
+func TestExponentialBackoffWithFactor(t *testing.T) {
+	base := 100 * time.Millisecond
+	max := 1 * time.Second
+
+	tests := []struct {
+		name     string
+		factor   float64
+		expected []time.Duration
+	}{
+		{
+			name:     "Factor 1.5",
+			factor:   1.5,
+			expected: []time.Duration{base, 150 * time.Millisecond, 225 * time.Millisecond, 337500 * time.Microsecond, 506250 * time.Microsecond, 759375 * time.Microsecond, max},
+		},
+		{
+			name:     "Factor 2 matches ExponentialBackoff",
+			factor:   2,
+			expected: []time.Duration{base, base * 2, base * 4, base * 8, max, max},
+		},
+		{
+			name:     "Factor 3",
+			factor:   3,
+			expected: []time.Duration{base, base * 3, base * 9, max},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := ExponentialBackoffWithFactor(base, max, tt.factor)
+			for i, expected := range tt.expected {
+				if actual := strategy(i); actual != expected {
+					t.Errorf("Attempt %d: Expected delay %v, got %v", i, expected, actual)
+				}
+			}
+		})
+	}
+
+	// Float overflow is clamped to maxDelay
+	strategy := ExponentialBackoffWithFactor(base, max, 1e300)
+	for _, attempt := range []int{5, 100, 10000} {
+		if actual := strategy(attempt); actual != max {
+			t.Errorf("Attempt %d: Expected overflow to clamp to %v, got %v", attempt, max, actual)
+		}
+	}
+}
+
 func TestFixedDelay(t *testing.T) {
 	delay := 500 * time.Millisecond
 	strategy := FixedDelay(delay)
@@ -58,6 +169,39 @@ func TestFixedDelay(t *testing.T) {
 	}
 }
 
+func TestFixedDelayWithJitter(t *testing.T) {
+	delay := 1 * time.Second
+
+	tests := []struct {
+		name      string
+		fraction  float64
+		maxJitter time.Duration
+	}{
+		{name: "No Jitter", fraction: 0, maxJitter: 0},
+		{name: "Negative", fraction: -0.5, maxJitter: 0},
+		{name: "NaN", fraction: math.NaN(), maxJitter: 0},
+		{name: "Quarter", fraction: 0.25, maxJitter: 250 * time.Millisecond},
+		{name: "Capped", fraction: 3, maxJitter: delay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Always pick the largest jitter so the upper bound is exercised
+			strategy := fixedDelayWithJitter(delay, tt.fraction, func(n int64) int64 { return n - 1 })
+			if got := strategy(0); got != delay+tt.maxJitter {
+				t.Errorf("Expected max delay %v, got %v", delay+tt.maxJitter, got)
+			}
+
+			strategy = FixedDelayWithJitter(delay, tt.fraction)
+			for i := range 20 {
+				if got := strategy(i); got < delay || got > delay+tt.maxJitter {
+					t.Errorf("Attempt %d: Expected delay between %v and %v, got %v", i, delay, delay+tt.maxJitter, got)
+				}
+			}
+		})
+	}
+}
+
 func TestJitterBackoff(t *testing.T) {
 	base := 100 * time.Millisecond
 	max := 1 * time.Second
@@ -78,6 +222,268 @@ func TestJitterBackoff(t *testing.T) {
 	}
 }
 
+func TestJitterRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		expDelay  time.Duration
+		maxJitter time.Duration
+		expected  time.Duration
+	}{
+		{name: "No Bound", expDelay: 10 * time.Second, expected: 5 * time.Second},
+		{name: "Bound", expDelay: 10 * time.Second, maxJitter: 200 * time.Millisecond, expected: 200 * time.Millisecond},
+		{name: "Half Below Bound", expDelay: 300 * time.Millisecond, maxJitter: 200 * time.Millisecond, expected: 150 * time.Millisecond},
+		{name: "Half At Bound", expDelay: 400 * time.Millisecond, maxJitter: 200 * time.Millisecond, expected: 200 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := jitterRange(tt.expDelay, tt.maxJitter); actual != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestJitterBackoffWithSource(t *testing.T) {
+	base := 100 * time.Millisecond
+	max := 1 * time.Second
+	seed := int64(42)
+
+	strategy := JitterBackoffWithSource(base, max, rand.New(rand.NewSource(seed)))
+	expStrategy := ExponentialBackoff(base, max)
+
+	// Replay the same source to compute the exact expected delays
+	expectedSrc := rand.New(rand.NewSource(seed))
+	for i := range 5 {
+		baseDelay := expStrategy(i)
+		expected := baseDelay + time.Duration(expectedSrc.Int63n(int64(baseDelay/2)))
+		actual := strategy(i)
+		if actual != expected {
+			t.Errorf("Attempt %d: Expected delay %v, got %v", i, expected, actual)
+		}
+	}
+
+	// A nil source falls back to the package-level one
+	strategyNil := JitterBackoffWithSource(base, max, nil)
+	for i := range 5 {
+		baseDelay := expStrategy(i)
+		if actual := strategyNil(i); actual < baseDelay || actual >= baseDelay+(baseDelay/2) {
+			t.Errorf("Attempt %d: Expected delay between %v and %v, got %v", i, baseDelay, baseDelay+(baseDelay/2), actual)
+		}
+	}
+}
+
+func TestJitterBackoffWithSource_Concurrent(t *testing.T) {
+	base := 100 * time.Millisecond
+	max := 1 * time.Second
+	strategy := JitterBackoffWithSource(base, max, rand.New(rand.NewSource(1)))
+	expStrategy := ExponentialBackoff(base, max)
+
+	// Run under -race to prove the shared source is guarded
+	var wg sync.WaitGroup
+	for g := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempt := g % 5
+			baseDelay := expStrategy(attempt)
+			if actual := strategy(attempt); actual < baseDelay || actual >= baseDelay+(baseDelay/2) {
+				t.Errorf("Attempt %d: Expected delay between %v and %v, got %v", attempt, baseDelay, baseDelay+(baseDelay/2), actual)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFullJitterBackoff(t *testing.T) {
+	base := 100 * time.Millisecond
+	max := 1 * time.Second
+	strategy := FullJitterBackoff(base, max)
+	expStrategy := ExponentialBackoff(base, max)
+
+	for i := range 6 {
+		expDelay := expStrategy(i)
+		for range 20 {
+			// Check if actual delay is within [0, expDelay)
+			if actual := strategy(i); actual < 0 || actual >= expDelay {
+				t.Errorf("Attempt %d: Expected delay between 0 and %v, got %v", i, expDelay, actual)
+			}
+		}
+	}
+
+	// Zero delays must not panic
+	if actual := FullJitterBackoff(0, 0)(0); actual != 0 {
+		t.Errorf("Expected zero delay, got %v", actual)
+	}
+}
+
+func TestEqualJitterBackoff(t *testing.T) {
+	base := 100 * time.Millisecond
+	max := 1 * time.Second
+	strategy := EqualJitterBackoff(base, max)
+	expStrategy := ExponentialBackoff(base, max)
+
+	for i := range 6 {
+		expDelay := expStrategy(i)
+		for range 20 {
+			// Check if actual delay is within [expDelay/2, expDelay)
+			if actual := strategy(i); actual < expDelay/2 || actual >= expDelay {
+				t.Errorf("Attempt %d: Expected delay between %v and %v, got %v", i, expDelay/2, expDelay, actual)
+			}
+		}
+	}
+
+	// Delays too small to halve get no jitter
+	if actual := EqualJitterBackoff(1*time.Nanosecond, 1*time.Nanosecond)(0); actual != 1*time.Nanosecond {
+		t.Errorf("Expected delay %v, got %v", 1*time.Nanosecond, actual)
+	}
+}
+
+func TestDecorrelatedJitter(t *testing.T) {
+	base := 100 * time.Millisecond
+	max := 2 * time.Second
+	strategy := DecorrelatedJitter(base, max)
+
+	for run := range 3 {
+		prev := base
+		for i := range 10 {
+			actual := strategy(i)
+			upper := prev * 3
+			if upper > max {
+				upper = max
+			}
+			if actual < base || actual > upper {
+				t.Errorf("Run %d attempt %d: Expected delay between %v and %v, got %v", run, i, base, upper, actual)
+			}
+			prev = actual
+		}
+	}
+
+	// Attempt 0 resets the state back to base, so the first delay is bounded by base*3
+	for range 100 {
+		if actual := strategy(0); actual > base*3 {
+			t.Fatalf("Attempt 0: Expected delay at most %v after reset, got %v", base*3, actual)
+		}
+	}
+}
+
+func TestRetryTransport_NewStrategyPerRequest(t *testing.T) {
+	var created int32
+	retryRT := &retryTransport{
+		RetryStrategy: FixedDelay(time.Second),
+		NewStrategy: func() RetryStrategy {
+			atomic.AddInt32(&created, 1)
+			return DecorrelatedJitter(10*time.Millisecond, time.Second)
+		},
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	retryRT.backoffFor(req)
+	retryRT.backoffFor(req)
+
+	if atomic.LoadInt32(&created) != 2 {
+		t.Errorf("Expected a new strategy per request, got %d created", atomic.LoadInt32(&created))
+	}
+}
+
+func TestDelayDistributions(t *testing.T) {
+	mean := 10 * time.Second
+	tests := []struct {
+		name         string
+		distribution DelayDistribution
+		upper        time.Duration // exclusive, 0 means unbounded
+	}{
+		{name: "Exponential", distribution: ExponentialDistribution},
+		{name: "Uniform", distribution: UniformDistribution, upper: 2 * mean},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			const samples = 10000
+			var sum float64
+			for range samples {
+				d := tt.distribution(mean, rng.Float64())
+				if d < 0 || (tt.upper > 0 && d >= tt.upper) {
+					t.Fatalf("Sample %v out of range", d)
+				}
+				sum += float64(d)
+			}
+
+			// The sample mean should be close to the requested mean
+			sampleMean := time.Duration(sum / samples)
+			if sampleMean < mean*95/100 || sampleMean > mean*105/100 {
+				t.Errorf("Expected sample mean close to %v, got %v", mean, sampleMean)
+			}
+		})
+	}
+}
+
+func TestJitterBackoff_SmallBaseDelay(t *testing.T) {
+	strategy := JitterBackoff(1*time.Nanosecond, 1*time.Nanosecond)
+
+	for i := range 5 {
+		// Must not panic with "invalid argument to Int63n"
+		actual := strategy(i)
+		if actual != 1*time.Nanosecond {
+			t.Errorf("Attempt %d: Expected delay %v with no jitter, got %v", i, 1*time.Nanosecond, actual)
+		}
+	}
+}
+
+func TestRetryTransport_RequestSeededJitter(t *testing.T) {
+	base := 100 * time.Millisecond
+	max := 10 * time.Second
+
+	retryRT := &retryTransport{
+		MaxRetries: 5,
+		RequestSeed: func(req *http.Request) int64 {
+			var seed int64
+			for _, c := range req.URL.String() + req.Header.Get("X-Request-Id") {
+				seed = seed*31 + int64(c)
+			}
+			return seed
+		},
+		SeededStrategy: func(rng *rand.Rand) RetryStrategy {
+			return jitterBackoff(ExponentialBackoff(base, max), rng.Int63n, 0)
+		},
+	}
+
+	newRequest := func(id string) *http.Request {
+		req := httptest.NewRequest("GET", "http://example.com/resource", nil)
+		req.Header.Set("X-Request-Id", id)
+		return req
+	}
+
+	sequence := func(req *http.Request) []time.Duration {
+		strategy := retryRT.backoffFor(req).Next
+		delays := make([]time.Duration, 5)
+		for i := range delays {
+			delays[i] = strategy(i)
+		}
+		return delays
+	}
+
+	first := sequence(newRequest("abc"))
+	second := sequence(newRequest("abc"))
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("Attempt %d: Expected identical jitter for the same seed, got %v and %v", i, first[i], second[i])
+		}
+	}
+
+	other := sequence(newRequest("xyz"))
+	identical := true
+	for i := range first {
+		if first[i] != other[i] {
+			identical = false
+		}
+	}
+	if identical {
+		t.Errorf("Expected different jitter sequences for different seeds, got %v for both", first)
+	}
+}
+
 // --- Test retryTransport ---
 
 // mockRoundTripper allows mocking http.RoundTripper behavior.
@@ -263,6 +669,470 @@ func TestRetryTransport_FailureAfterMaxRetries_TransportError(t *testing.T) {
 	}
 }
 
+func TestRetryTransport_RetryIfMissingHeader(t *testing.T) {
+	var attempts int32 = 0
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			header := make(http.Header)
+			// The first attempt hits a gateway that omits the trace header
+			if atomic.AddInt32(&attempts, 1) > 1 {
+				header.Set("X-Trace-Id", "abc123")
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("OK")),
+				Header:     header,
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:      mockRT,
+		MaxRetries:     3,
+		RetryStrategy:  FixedDelay(1 * time.Millisecond),
+		RequiredHeader: "X-Trace-Id",
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("X-Trace-Id") != "abc123" {
+		t.Errorf("Expected the response containing the header, got header %q", resp.Header.Get("X-Trace-Id"))
+	}
+	if atomic.LoadInt32(&attempts) != 2 {
+		t.Errorf("Expected 2 attempts, got %d", atomic.LoadInt32(&attempts))
+	}
+
+	// A response containing the header is accepted on the first attempt
+	atomic.StoreInt32(&attempts, 1)
+	resp, err = retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if atomic.LoadInt32(&attempts) != 2 {
+		t.Errorf("Expected a single attempt, got %d", atomic.LoadInt32(&attempts)-1)
+	}
+
+	// Disabled by default
+	retryRT.RequiredHeader = ""
+	atomic.StoreInt32(&attempts, 0)
+	resp, err = retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if atomic.LoadInt32(&attempts) != 1 {
+		t.Errorf("Expected a single attempt without the check, got %d", atomic.LoadInt32(&attempts))
+	}
+}
+
+func TestRetryTransport_ConnectTimeoutBackoff(t *testing.T) {
+	retryRT := &retryTransport{
+		RetryStrategy:          FixedDelay(10 * time.Millisecond),
+		ConnectTimeoutStrategy: FixedDelay(1 * time.Second),
+	}
+	backoff := retryRT.backoffFor(httptest.NewRequest("GET", "http://example.com", nil))
+
+	connectTimeout := &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}
+	readTimeout := &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}
+
+	if delay := retryRT.nextDelay(backoff, 0, connectTimeout); delay != 1*time.Second {
+		t.Errorf("Expected connect timeout delay %v, got %v", 1*time.Second, delay)
+	}
+	if delay := retryRT.nextDelay(backoff, 0, readTimeout); delay != 10*time.Millisecond {
+		t.Errorf("Expected read timeout delay %v, got %v", 10*time.Millisecond, delay)
+	}
+	if delay := retryRT.nextDelay(backoff, 0, nil); delay != 10*time.Millisecond {
+		t.Errorf("Expected status-based retry delay %v, got %v", 10*time.Millisecond, delay)
+	}
+
+	// Without a dedicated strategy, connect timeouts use the general one
+	retryRT.ConnectTimeoutStrategy = nil
+	if delay := retryRT.nextDelay(backoff, 0, connectTimeout); delay != 10*time.Millisecond {
+		t.Errorf("Expected general delay %v, got %v", 10*time.Millisecond, delay)
+	}
+}
+
+func TestRetryTransport_MinDelay(t *testing.T) {
+	retryRT := &retryTransport{
+		RetryStrategy:          ExponentialBackoff(10*time.Millisecond, 1*time.Second),
+		ConnectTimeoutStrategy: FixedDelay(1 * time.Millisecond),
+		MinDelay:               50 * time.Millisecond,
+	}
+	backoff := retryRT.backoffFor(httptest.NewRequest("GET", "http://example.com", nil))
+
+	// Delays below the floor are raised to it, the others are kept
+	for attempt, want := range []time.Duration{50, 50, 50, 80, 160} {
+		if delay := retryRT.nextDelay(backoff, attempt, nil); delay != want*time.Millisecond {
+			t.Errorf("Expected delay %v after attempt %d, got %v", want*time.Millisecond, attempt, delay)
+		}
+	}
+
+	connectTimeout := &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}
+	if delay := retryRT.nextDelay(backoff, 0, connectTimeout); delay != 50*time.Millisecond {
+		t.Errorf("Expected the connect timeout delay raised to %v, got %v", 50*time.Millisecond, delay)
+	}
+}
+
+func TestRetryTransport_RetryErrorOutcome(t *testing.T) {
+	tests := []struct {
+		name            string
+		roundTrip       func(req *http.Request) (*http.Response, error)
+		expectedOutcome Outcome
+		expectedStatus  int
+	}{
+		{
+			name: "Server Error",
+			roundTrip: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusBadGateway,
+					Body:       io.NopCloser(strings.NewReader("Bad Gateway")),
+					Header:     make(http.Header),
+				}, nil
+			},
+			expectedOutcome: OutcomeServerError,
+			expectedStatus:  http.StatusBadGateway,
+		},
+		{
+			name: "Timeout",
+			roundTrip: func(req *http.Request) (*http.Response, error) {
+				return nil, timeoutError{}
+			},
+			expectedOutcome: OutcomeTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retryRT := &retryTransport{
+				Transport:     &mockRoundTripper{roundTripFunc: tt.roundTrip},
+				MaxRetries:    1,
+				RetryStrategy: FixedDelay(1 * time.Millisecond),
+			}
+
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			_, err := retryRT.RoundTrip(req)
+
+			var retryErr *RetryError
+			if !errors.As(err, &retryErr) {
+				t.Fatalf("Expected a *RetryError, got %T: %v", err, err)
+			}
+			if retryErr.Outcome != tt.expectedOutcome {
+				t.Errorf("Expected outcome %q, got %q", tt.expectedOutcome, retryErr.Outcome)
+			}
+			if retryErr.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, retryErr.StatusCode)
+			}
+			if retryErr.Attempts != 2 {
+				t.Errorf("Expected 2 attempts, got %d", retryErr.Attempts)
+			}
+			if !errors.Is(err, ErrAllRetriesFailed) {
+				t.Errorf("Expected error to wrap ErrAllRetriesFailed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_MaxElapsedTime(t *testing.T) {
+	var attempts int32 = 0
+	simulatedError := errors.New("simulated transport error")
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&attempts, 1)
+			return nil, simulatedError
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:        mockRT,
+		MaxRetries:       100, // The budget, not the retry count, ends the loop
+		RetryStrategy:    FixedDelay(20 * time.Millisecond),
+		MaxElapsedTime:   50 * time.Millisecond,
+		MinAttemptBudget: 5 * time.Millisecond,
+	}
+
+	start := time.Now()
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	_, err := retryRT.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, simulatedError) {
+		t.Errorf("Expected the last error to be returned, got %v", err)
+	}
+	// Two 20ms delays fit in the budget, a third one wouldn't leave 5ms for the attempt
+	if atomic.LoadInt32(&attempts) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", atomic.LoadInt32(&attempts))
+	}
+	if elapsed < 40*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("Expected to stop within the 50ms budget, took %v", elapsed)
+	}
+}
+
+func TestRetryTransport_SkipsRetryPastDeadline(t *testing.T) {
+	simulatedError := errors.New("simulated transport error")
+
+	tests := []struct {
+		name           string
+		maxElapsedTime time.Duration
+		ctxTimeout     time.Duration
+	}{
+		{name: "MaxElapsedTime", maxElapsedTime: 1 * time.Second},
+		{name: "Context deadline", ctxTimeout: 1 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			retryRT := &retryTransport{
+				Transport: &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						atomic.AddInt32(&attempts, 1)
+						return nil, simulatedError
+					},
+				},
+				MaxRetries:       3,
+				RetryStrategy:    FixedDelay(1 * time.Hour), // Would block the test if slept
+				MaxElapsedTime:   tt.maxElapsedTime,
+				MinAttemptBudget: 100 * time.Millisecond,
+			}
+
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			if tt.ctxTimeout > 0 {
+				ctx, cancel := context.WithTimeout(req.Context(), tt.ctxTimeout)
+				defer cancel()
+				req = req.WithContext(ctx)
+			}
+
+			start := time.Now()
+			_, err := retryRT.RoundTrip(req)
+
+			// The last error comes back right away instead of after the delay
+			var retryErr *RetryError
+			if !errors.As(err, &retryErr) || !errors.Is(err, simulatedError) {
+				t.Errorf("Expected a RetryError with the last error, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("Expected to give up without waiting, took %v", elapsed)
+			}
+			if atomic.LoadInt32(&attempts) != 1 {
+				t.Errorf("Expected 1 attempt, got %d", atomic.LoadInt32(&attempts))
+			}
+		})
+	}
+}
+
+func TestRetryTransport_PerAttemptTimeout(t *testing.T) {
+	var attempts int32 = 0
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			if _, ok := req.Context().Deadline(); !ok {
+				t.Error("Expected each attempt to carry a deadline")
+			}
+
+			// The first attempt hangs until its own deadline expires
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("OK")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:         mockRT,
+		MaxRetries:        2,
+		RetryStrategy:     FixedDelay(1 * time.Millisecond),
+		PerAttemptTimeout: 20 * time.Millisecond,
+	}
+
+	start := time.Now()
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected the hung attempt to be retried, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the hung attempt to fail fast, took %v", elapsed)
+	}
+	if atomic.LoadInt32(&attempts) != 2 {
+		t.Errorf("Expected 2 attempts, got %d", atomic.LoadInt32(&attempts))
+	}
+
+	// The successful attempt's context stays alive until the body is closed
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "OK" {
+		t.Errorf("Expected body 'OK', got %q (err %v)", body, err)
+	}
+}
+
+func TestRetryTransport_PerAttemptTimeoutAllAttemptsHang(t *testing.T) {
+	var attempts int32 = 0
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&attempts, 1)
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:         mockRT,
+		MaxRetries:        2,
+		RetryStrategy:     FixedDelay(1 * time.Millisecond),
+		PerAttemptTimeout: 10 * time.Millisecond,
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	_, err := retryRT.RoundTrip(req)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded as the last error, got %v", err)
+	}
+	if atomic.LoadInt32(&attempts) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", atomic.LoadInt32(&attempts))
+	}
+	if req.Context().Err() != nil {
+		t.Error("Expected the original request context to be left untouched")
+	}
+}
+
+func TestRetryTransport_StopsOnContextCancel(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition RetryCondition
+	}{
+		{name: "Default Condition"},
+		{name: "Condition Retrying Everything", condition: func(resp *http.Response, err error) bool { return true }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32 = 0
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			mockRT := &mockRoundTripper{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					atomic.AddInt32(&calls, 1)
+					// The caller gives up while the first attempt is in flight
+					cancel()
+					return nil, fmt.Errorf("request aborted: %w", req.Context().Err())
+				},
+			}
+
+			retryRT := &retryTransport{
+				Transport:      mockRT,
+				MaxRetries:     3,
+				RetryStrategy:  FixedDelay(1 * time.Hour), // Would block the test if slept
+				RetryCondition: tt.condition,
+			}
+
+			req := httptest.NewRequest("GET", "http://example.com", nil).WithContext(ctx)
+			_, err := retryRT.RoundTrip(req)
+
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected context.Canceled, got %v", err)
+			}
+			if atomic.LoadInt32(&calls) != 1 {
+				t.Errorf("Expected exactly 1 underlying call, got %d", atomic.LoadInt32(&calls))
+			}
+		})
+	}
+}
+
+func TestRetryTransport_ContextCancelInterruptsBackoff(t *testing.T) {
+	var calls int32 = 0
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, errors.New("simulated transport error")
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Hour), // Would block the test if not interrupted
+	}
+
+	// Without a deadline, the delay is waited until the request is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	defer cancel()
+	req := httptest.NewRequest("GET", "http://example.com", nil).WithContext(ctx)
+
+	start := time.Now()
+	_, err := retryRT.RoundTrip(req)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the cancellation to interrupt the backoff delay, took %v", elapsed)
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected 1 underlying call, got %d", atomic.LoadInt32(&calls))
+	}
+}
+
+func TestRetryTransport_DoesNotMutateRequest(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			if _, err := io.ReadAll(req.Body); err != nil {
+				return nil, err
+			}
+			req.Header.Set("X-Mutated-By-Transport", "true")
+			return nil, errors.New("simulated transport error")
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    2,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+	}
+
+	req, err := http.NewRequest("POST", "http://example.com", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	originalBody := req.Body
+
+	// The same request object is sent concurrently; run with -race to catch
+	// any write to the shared request
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = retryRT.RoundTrip(req)
+		}()
+	}
+	wg.Wait()
+
+	if req.Body != originalBody {
+		t.Error("Expected the caller's request body to be left untouched")
+	}
+	if req.Header.Get("X-Mutated-By-Transport") != "" {
+		t.Error("Expected attempts to work on a clone of the request")
+	}
+}
+
 func TestRetryTransport_RequestBodyCloning(t *testing.T) {
 	var attempts int32 = 0
 	maxRetries := 1
@@ -513,9 +1383,11 @@ func (e *errorReaderCloser) Close() error {
 }
 
 func TestRetryTransport_BodyDrainError(t *testing.T) {
+	var attempts int32 = 0
 	simulatedReadError := errors.New("simulated read error during drain")
 	mockRT := &mockRoundTripper{
 		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&attempts, 1)
 			// Fail the request with a 5xx status and a body that errors on read
 			return &http.Response{
 				StatusCode: http.StatusInternalServerError,
@@ -528,34 +1400,41 @@ func TestRetryTransport_BodyDrainError(t *testing.T) {
 		},
 	}
 
+	var logs bytes.Buffer
 	retryRT := &retryTransport{
 		Transport:     mockRT,
 		MaxRetries:    1, // Allow one retry attempt
 		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		Logger:        slog.New(slog.NewTextHandler(&logs, nil)),
 	}
 
 	req := httptest.NewRequest("GET", "http://example.com", nil)
 	_, err := retryRT.RoundTrip(req)
 
-	if err == nil {
-		t.Fatal("Expected an error due to body drain failure, got nil")
+	// A drain error is only logged, it doesn't abort the retries
+	if atomic.LoadInt32(&attempts) != 2 {
+		t.Errorf("Expected 2 attempts despite the drain error, got %d", atomic.LoadInt32(&attempts))
+	}
+	if !strings.Contains(logs.String(), "Failed to discard response body") {
+		t.Errorf("Expected the drain error to be logged as a warning, got %q", logs.String())
 	}
 
-	// The error should be related to failing to discard the body
-	expectedErrMsg := "failed to discard response body"
-	if !strings.Contains(err.Error(), expectedErrMsg) {
-		t.Errorf("Expected error message to contain '%s', got '%s'", expectedErrMsg, err.Error())
+	// The status of the last attempt is a better error than the drain failure
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || retryErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Expected a RetryError with the last status, got %v", err)
 	}
-	// Check if the original read error is wrapped
-	if !errors.Is(err, simulatedReadError) {
-		t.Errorf("Expected error to wrap the original read error '%v', but it didn't. Got: %v", simulatedReadError, err)
+	if errors.Is(err, simulatedReadError) {
+		t.Errorf("Expected the drain error not to be surfaced, got %v", err)
 	}
 }
 
 func TestRetryTransport_BodyCloseError(t *testing.T) {
+	var attempts int32 = 0
 	simulatedCloseError := errors.New("simulated close error")
 	mockRT := &mockRoundTripper{
 		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&attempts, 1)
 			// Fail the request with a 5xx status and a body that errors on close
 			return &http.Response{
 				StatusCode: http.StatusInternalServerError,
@@ -568,27 +1447,126 @@ func TestRetryTransport_BodyCloseError(t *testing.T) {
 		},
 	}
 
+	var logs bytes.Buffer
 	retryRT := &retryTransport{
 		Transport:     mockRT,
 		MaxRetries:    1, // Allow one retry attempt
 		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		Logger:        slog.New(slog.NewTextHandler(&logs, nil)),
 	}
 
 	req := httptest.NewRequest("GET", "http://example.com", nil)
 	_, err := retryRT.RoundTrip(req)
 
+	// A close error is only logged, it doesn't abort the retries
+	if atomic.LoadInt32(&attempts) != 2 {
+		t.Errorf("Expected 2 attempts despite the close error, got %d", atomic.LoadInt32(&attempts))
+	}
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || retryErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected a RetryError with the last status, got %v", err)
+	}
+	if !strings.Contains(logs.String(), "Failed to close response body") || !strings.Contains(logs.String(), "simulated close error") {
+		t.Errorf("Expected the close error to be logged as a warning, got %q", logs.String())
+	}
+}
+
+// slowReaderCloser serves content a byte at a time, then blocks until closed
+type slowReaderCloser struct {
+	content []byte
+	read    int
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func (s *slowReaderCloser) Read(p []byte) (int, error) {
+	if s.read < len(s.content) && len(p) > 0 {
+		p[0] = s.content[s.read]
+		s.read++
+		return 1, nil
+	}
+	<-s.closed
+	return 0, errors.New("read on closed body")
+}
+
+func (s *slowReaderCloser) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
+
+func TestRetryTransport_DrainIsBounded(t *testing.T) {
+	var bodies []*slowReaderCloser
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			// A body far larger than the drain cap; reading past the cap would block
+			body := &slowReaderCloser{content: []byte(strings.Repeat("x", 100)), closed: make(chan struct{})}
+			bodies = append(bodies, body)
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Body:       body,
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		MaxDrainSize:  10,
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	_, err := retryRT.RoundTrip(req)
 	if err == nil {
-		t.Fatal("Expected an error due to body close failure, got nil")
+		t.Fatal("Expected an error after all retries failed")
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", len(bodies))
+	}
+	for i, body := range bodies {
+		if body.read != 10 {
+			t.Errorf("Attempt %d: expected 10 bytes drained, got %d", i+1, body.read)
+		}
+	}
+}
+
+func TestRetryTransport_DrainRespectsContext(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			// A body that trickles nothing and hangs until closed
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Body:       &slowReaderCloser{closed: make(chan struct{})},
+				Header:     make(http.Header),
+			}, nil
+		},
 	}
 
-	// The error should be related to failing to close the body
-	expectedErrMsg := "failed to close response body"
-	if !strings.Contains(err.Error(), expectedErrMsg) {
-		t.Errorf("Expected error message to contain '%s', got '%s'", expectedErrMsg, err.Error())
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
 	}
-	// Check if the original close error is wrapped
-	if !errors.Is(err, simulatedCloseError) {
-		t.Errorf("Expected error to wrap the original close error '%v', but it didn't. Got: %v", simulatedCloseError, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest("GET", "http://example.com", nil).WithContext(ctx)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := retryRT.RoundTrip(req)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected an error after the request was canceled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Draining the body blocked past the request's cancellation")
 	}
 }
 
@@ -655,3 +1633,148 @@ func TestRetryTransport_RequestBodyGetBodyError(t *testing.T) {
 		t.Errorf("Expected only 1 attempt before GetBody error, got %d", atomic.LoadInt32(&attempts))
 	}
 }
+
+func TestRetryTransport_MaxConcurrentHoldsSlotAcrossRetries(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	attempts := make(map[string]int)
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			// Each request fails once, so it goes through a backoff delay
+			id := req.Header.Get("X-Request-Id")
+			mu.Lock()
+			order = append(order, id)
+			attempts[id]++
+			first := attempts[id] == 1
+			mu.Unlock()
+
+			status := http.StatusOK
+			if first {
+				status = http.StatusInternalServerError
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader("body")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(5 * time.Millisecond),
+		inFlight:      make(chan struct{}, 1),
+	}
+
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			req.Header.Set("X-Request-Id", strconv.Itoa(i))
+			resp, err := retryRT.RoundTrip(req)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	// With a single slot, no other request may run between a request's
+	// attempt and its retry
+	if len(order) != 10 {
+		t.Fatalf("Expected 10 attempts, got %d", len(order))
+	}
+	for i := 0; i < len(order); i += 2 {
+		if order[i] != order[i+1] {
+			t.Errorf("Expected the retry of request %s to follow its first attempt, got order %v", order[i], order)
+			break
+		}
+	}
+}
+
+func TestRetryTransport_MaxConcurrentContextCanceled(t *testing.T) {
+	retryRT := &retryTransport{
+		Transport: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				t.Error("Expected no attempt while waiting for a slot")
+				return nil, errors.New("unexpected")
+			},
+		},
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(time.Millisecond),
+		inFlight:      make(chan struct{}, 1),
+	}
+	retryRT.inFlight <- struct{}{} // The only slot is taken
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest("GET", "http://example.com", nil).WithContext(ctx)
+
+	if _, err := retryRT.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context deadline error while waiting for a slot, got %v", err)
+	}
+}
+
+func TestClientBuilder_WithMaxConcurrent(t *testing.T) {
+	rt, _ := NewClientBuilder().WithMaxConcurrent(3).Build().Transport.(*retryTransport)
+	if cap(rt.inFlight) != 3 {
+		t.Errorf("Expected 3 slots, got %d", cap(rt.inFlight))
+	}
+
+	builder := NewClientBuilder().WithMaxConcurrent(-1)
+	rt, _ = builder.Build().Transport.(*retryTransport)
+	if rt.inFlight != nil {
+		t.Error("Expected no limit for an invalid value")
+	}
+	if len(builder.Warnings()) != 1 {
+		t.Errorf("Expected 1 warning, got %v", builder.Warnings())
+	}
+}
+
+func TestClientBuilder_WithDryRun(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	clock := &fakeClock{now: time.Now()}
+	client := NewClientBuilder().
+		WithMaxRetries(3).
+		WithRetryStrategy(ExponentialBackoffStrategy).
+		WithRetryBaseDelay(1 * time.Second).
+		WithDryRun(true).
+		WithClock(clock).
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))).
+		Build()
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	// The requests are retried as usual, without waiting
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+	if len(clock.sleeps) != 0 {
+		t.Errorf("Expected no sleep, got %v", clock.sleeps)
+	}
+	for _, delay := range []string{"delay=1s", "delay=2s"} {
+		if !strings.Contains(logs.String(), `msg="Dry run, not waiting before retrying" `+delay) {
+			t.Errorf("Expected the would-be %s to be logged, got %q", delay, logs.String())
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Package httpretriermetrics exposes the retries of httpretrier clients as
+// Prometheus metrics. It lives in its own package so that only programs using
+// it depend on the Prometheus client library.
+package httpretriermetrics
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/p2p-b2b/httpretrier"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics holds the Prometheus collectors fed by an httpretrier client
+type Metrics struct {
+	// Retries counts the retries made, across all requests
+	Retries prometheus.Counter
+
+	// Attempts observes the number of attempts each request took, including the first one
+	Attempts prometheus.Histogram
+
+	// Backoff observes the total backoff delay waited by each request, in seconds
+	Backoff prometheus.Histogram
+}
+
+// NewMetrics creates the collectors, not registered yet
+func NewMetrics() *Metrics {
+	return &Metrics{
+		Retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "httpretrier",
+			Name:      "retries_total",
+			Help:      "Total number of retries made.",
+		}),
+		Attempts: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "httpretrier",
+			Name:      "attempts_per_request",
+			Help:      "Number of attempts per request, including the first one.",
+			Buckets:   []float64{1, 2, 3, 4, 5, 7, 10},
+		}),
+		Backoff: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "httpretrier",
+			Name:      "backoff_seconds",
+			Help:      "Total backoff delay waited per request, in seconds.",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+		}),
+	}
+}
+
+// Collectors returns the collectors, to register them with a registry of choice
+func (m *Metrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.Retries, m.Attempts, m.Backoff}
+}
+
+// Register registers the collectors with reg. A collector already registered,
+// e.g. by another client, is shared rather than reported as an error.
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	var errRetries, errAttempts, errBackoff error
+	m.Retries, errRetries = register(reg, m.Retries)
+	m.Attempts, errAttempts = register(reg, m.Attempts)
+	m.Backoff, errBackoff = register(reg, m.Backoff)
+
+	return errors.Join(errRetries, errAttempts, errBackoff)
+}
+
+// register registers c with reg and returns the collector to use, which is
+// the one registered before if there is one
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) (C, error) {
+	err := reg.Register(c)
+
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		if existing, ok := already.ExistingCollector.(C); ok {
+			return existing, nil
+		}
+	}
+
+	return c, err
+}
+
+// Option returns an httpretrier.Option feeding the collectors with the
+// retries and requests of the client it configures
+func (m *Metrics) Option() httpretrier.Option {
+	return func(b *httpretrier.ClientBuilder) {
+		b.WithOnRetry(func(httpretrier.RetryEvent) {
+			m.Retries.Inc()
+		})
+		b.WithOnRequestDone(func(stats httpretrier.Stats) {
+			// Requests that never got to an attempt have nothing to report
+			if stats.Attempts == 0 {
+				return
+			}
+			m.Attempts.Observe(float64(stats.Attempts))
+			m.Backoff.Observe(stats.TotalDelay.Seconds())
+		})
+	}
+}
+
+// WithPrometheus returns an httpretrier.Option that registers the retry
+// metrics with reg and feeds them with the client's requests, e.g.
+//
+//	client := httpretrier.New(httpretriermetrics.WithPrometheus(reg))
+//
+// or httpretriermetrics.WithPrometheus(reg)(builder) with a ClientBuilder.
+// Clients registering with the same registry share the metrics. If the metrics
+// can't be registered, a warning is logged and they are left unregistered.
+func WithPrometheus(reg *prometheus.Registry) httpretrier.Option {
+	m := NewMetrics()
+	if err := m.Register(reg); err != nil {
+		slog.Default().Warn("Failed to register retry metrics", "error", err)
+	}
+
+	return m.Option()
+}
@@ -0,0 +1,113 @@
+package httpretriermetrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/p2p-b2b/httpretrier"
+	"github.com/p2p-b2b/httpretrier/httpretriertest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// histogram returns the sample count and sum of a histogram
+func histogram(t *testing.T, h prometheus.Histogram) (uint64, float64) {
+	t.Helper()
+
+	var m dto.Metric
+	if err := h.Write(&m); err != nil {
+		t.Fatalf("Failed to read histogram: %v", err)
+	}
+
+	return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+}
+
+func TestWithPrometheus(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first request fails twice before succeeding, the others succeed
+		if n := atomic.AddInt32(&calls, 1); n <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	reg := prometheus.NewRegistry()
+	client := httpretrier.New(
+		httpretrier.WithRetries(3),
+		httpretrier.WithStrategy(httpretrier.FixedDelayStrategy),
+		httpretrier.WithBaseDelay(300*time.Millisecond),
+		httpretrier.WithClock(httpretriertest.NewInstantClock(time.Now())),
+		WithPrometheus(reg),
+	)
+
+	for range 2 {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// A second client on the same registry shares the metrics
+	other := httpretrier.New(WithPrometheus(reg))
+	resp, err := other.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if count, err := testutil.GatherAndCount(reg); err != nil || count != 3 {
+		t.Fatalf("Expected 3 registered metrics, got %d (%v)", count, err)
+	}
+
+	m := NewMetrics()
+	if err := m.Register(reg); err != nil {
+		t.Fatalf("Expected registering again to share the metrics, got %v", err)
+	}
+
+	if got := testutil.ToFloat64(m.Retries); got != 2 {
+		t.Errorf("Expected 2 retries, got %v", got)
+	}
+
+	count, sum := histogram(t, m.Attempts)
+	if count != 3 || sum != 5 {
+		t.Errorf("Expected 3 requests taking 5 attempts in total, got %d requests and %v attempts", count, sum)
+	}
+
+	count, sum = histogram(t, m.Backoff)
+	if count != 3 || sum != 0.6 {
+		t.Errorf("Expected 3 requests waiting 600ms in total, got %d requests and %vs", count, sum)
+	}
+}
+
+func TestMetrics_Option(t *testing.T) {
+	m := NewMetrics()
+	builder := httpretrier.NewClientBuilder()
+	m.Option()(builder)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := builder.Build().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	// Unregistered metrics are still fed
+	if got := testutil.ToFloat64(m.Retries); got != 0 {
+		t.Errorf("Expected no retries, got %v", got)
+	}
+	if count, sum := histogram(t, m.Attempts); count != 1 || sum != 1 {
+		t.Errorf("Expected a single attempt, got %d requests and %v attempts", count, sum)
+	}
+}
@@ -0,0 +1,192 @@
+// Package httpretrierotel traces the requests of httpretrier clients with
+// OpenTelemetry. It lives in its own package so that only programs using it
+// depend on OpenTelemetry.
+//
+// Each request gets a span covering all of its attempts, with a child span per
+// attempt, e.g.
+//
+//	client := httpretrier.New(httpretrierotel.WithTracing())
+//
+// The trace context of the attempt span is injected into the outgoing headers.
+package httpretrierotel
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/p2p-b2b/httpretrier"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies this package as the tracer's instrumentation scope
+const instrumentationName = "github.com/p2p-b2b/httpretrier/httpretrierotel"
+
+// Attribute keys set on the spans, besides the standard HTTP ones
+const (
+	// AttemptKey is the number of an attempt, starting at 1
+	AttemptKey = attribute.Key("httpretrier.attempt")
+
+	// DelayKey is the backoff delay waited before an attempt, in milliseconds
+	DelayKey = attribute.Key("httpretrier.delay_ms")
+
+	// AttemptsKey is the number of attempts a request took
+	AttemptsKey = attribute.Key("httpretrier.attempts")
+
+	// OutcomeKey classifies the final result of a request, see httpretrier.Outcome
+	OutcomeKey = attribute.Key("httpretrier.outcome")
+)
+
+// config holds the settings of the tracing
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+}
+
+// TracingOption configures the tracing set up by WithTracing
+type TracingOption func(*config)
+
+// WithTracerProvider sets the TracerProvider creating the spans.
+// If not set, the global TracerProvider is used.
+func WithTracerProvider(tracerProvider trace.TracerProvider) TracingOption {
+	return func(c *config) {
+		c.tracerProvider = tracerProvider
+	}
+}
+
+// WithPropagators sets the propagators injecting the trace context into the
+// attempts' headers. If not set, the global TextMapPropagator is used.
+func WithPropagators(propagators propagation.TextMapPropagator) TracingOption {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithTracing returns an httpretrier.Option tracing the client's requests:
+// a span per request, ended once the retries are over with the final outcome
+// as its status, and a child span per attempt carrying the attempt number,
+// the backoff delay waited before it and the status code it got.
+func WithTracing(opts ...TracingOption) httpretrier.Option {
+	cfg := config{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.tracerProvider == nil {
+		cfg.tracerProvider = otel.GetTracerProvider()
+	}
+	if cfg.propagators == nil {
+		cfg.propagators = otel.GetTextMapPropagator()
+	}
+
+	tracer := cfg.tracerProvider.Tracer(instrumentationName)
+
+	return func(b *httpretrier.ClientBuilder) {
+		b.WithRequestMiddleware(requestMiddleware(tracer))
+		b.WithAttemptMiddleware(attemptMiddleware(tracer, cfg.propagators))
+	}
+}
+
+// roundTripperFunc adapts a function to the http.RoundTripper interface
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// requestMiddleware starts a span covering all the attempts of a request
+func requestMiddleware(tracer trace.Tracer) httpretrier.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx, span := tracer.Start(req.Context(), "HTTP "+req.Method,
+				trace.WithSpanKind(trace.SpanKindInternal),
+				trace.WithAttributes(
+					attribute.String("http.request.method", req.Method),
+					attribute.String("url.full", req.URL.String()),
+				),
+			)
+			defer span.End()
+
+			resp, err := next.RoundTrip(req.WithContext(ctx))
+			endRequestSpan(span, resp, err)
+
+			return resp, err
+		})
+	}
+}
+
+// endRequestSpan records the final outcome of a request on its span
+func endRequestSpan(span trace.Span, resp *http.Response, err error) {
+	outcome := httpretrier.ClassifyOutcome(resp, err)
+	span.SetAttributes(OutcomeKey.String(outcome.String()))
+
+	var retryErr *httpretrier.RetryError
+	switch {
+	case errors.As(err, &retryErr):
+		span.SetAttributes(AttemptsKey.Int(retryErr.Attempts))
+	case resp != nil:
+		span.SetAttributes(
+			AttemptsKey.Int(httpretrier.AttemptsFromResponse(resp)),
+			attribute.Int("http.response.status_code", resp.StatusCode),
+		)
+	}
+
+	switch {
+	case errors.Is(err, httpretrier.ErrAllRetriesFailed):
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "retries exhausted")
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case outcome == httpretrier.OutcomeSuccess:
+		span.SetStatus(codes.Ok, "")
+	default:
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+}
+
+// attemptMiddleware starts a child span for each attempt and injects its
+// trace context into the attempt's headers
+func attemptMiddleware(tracer trace.Tracer, propagators propagation.TextMapPropagator) httpretrier.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			info, _ := httpretrier.AttemptInfoFromContext(req.Context())
+
+			ctx, span := tracer.Start(req.Context(), "HTTP "+req.Method+" attempt",
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					AttemptKey.Int(info.Attempt+1),
+					DelayKey.Int64(info.Delay.Milliseconds()),
+					attribute.String("http.request.method", req.Method),
+					attribute.String("url.full", req.URL.String()),
+				),
+			)
+			defer span.End()
+
+			// Hedged copies of an attempt may share its headers, so inject
+			// into a copy of them
+			req = req.Clone(ctx)
+			if req.Header == nil {
+				req.Header = make(http.Header)
+			}
+			propagators.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+			resp, err := next.RoundTrip(req)
+			switch {
+			case err != nil:
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			case resp.StatusCode >= http.StatusBadRequest:
+				span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+				span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+			default:
+				span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+			}
+
+			return resp, err
+		})
+	}
+}
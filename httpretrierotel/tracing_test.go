@@ -0,0 +1,133 @@
+package httpretrierotel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/p2p-b2b/httpretrier"
+	"github.com/p2p-b2b/httpretrier/httpretriertest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// attr returns the value of the attribute key in attrs
+func attr(attrs []attribute.KeyValue, key attribute.Key) attribute.Value {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+// newClient returns a client traced into a new span recorder
+func newClient(t *testing.T, opts ...httpretrier.Option) (*http.Client, *tracetest.SpanRecorder) {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+
+	opts = append([]httpretrier.Option{
+		httpretrier.WithRetries(2),
+		httpretrier.WithStrategy(httpretrier.FixedDelayStrategy),
+		httpretrier.WithBaseDelay(300 * time.Millisecond),
+		httpretrier.WithClock(httpretriertest.NewInstantClock(time.Now())),
+		WithTracing(WithTracerProvider(provider), WithPropagators(propagation.TraceContext{})),
+	}, opts...)
+
+	return httpretrier.New(opts...), recorder
+}
+
+func TestWithTracing_Success(t *testing.T) {
+	var calls int32
+	var traceparents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, recorder := newClient(t)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 2 attempt spans and a request span, got %d spans", len(spans))
+	}
+	first, second, request := spans[0], spans[1], spans[2]
+
+	if request.Name() != "HTTP GET" || request.Status().Code != codes.Ok {
+		t.Errorf("Expected an Ok request span, got %q with status %v", request.Name(), request.Status())
+	}
+	if got := attr(request.Attributes(), AttemptsKey).AsInt64(); got != 2 {
+		t.Errorf("Expected 2 attempts on the request span, got %d", got)
+	}
+	if got := attr(request.Attributes(), OutcomeKey).AsString(); got != "success" {
+		t.Errorf("Expected success outcome, got %q", got)
+	}
+
+	for i, span := range []sdktrace.ReadOnlySpan{first, second} {
+		if span.Parent().SpanID() != request.SpanContext().SpanID() {
+			t.Errorf("Expected attempt %d to be a child of the request span", i+1)
+		}
+		if got := attr(span.Attributes(), AttemptKey).AsInt64(); got != int64(i+1) {
+			t.Errorf("Expected attempt number %d, got %d", i+1, got)
+		}
+
+		// Each attempt carries its own span in the trace context
+		if want := "00-" + span.SpanContext().TraceID().String() + "-" + span.SpanContext().SpanID().String() + "-01"; traceparents[i] != want {
+			t.Errorf("Expected traceparent %q on attempt %d, got %q", want, i+1, traceparents[i])
+		}
+	}
+
+	if got := attr(first.Attributes(), DelayKey).AsInt64(); got != 0 {
+		t.Errorf("Expected no delay before the first attempt, got %dms", got)
+	}
+	if got := attr(second.Attributes(), DelayKey).AsInt64(); got != 300 {
+		t.Errorf("Expected a 300ms delay before the second attempt, got %dms", got)
+	}
+	if first.Status().Code != codes.Error || second.Status().Code == codes.Error {
+		t.Errorf("Expected only the first attempt to fail, got %v and %v", first.Status(), second.Status())
+	}
+}
+
+func TestWithTracing_Exhausted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client, recorder := newClient(t)
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatal("Expected an error once the retries are exhausted")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("Expected 3 attempt spans and a request span, got %d spans", len(spans))
+	}
+
+	request := spans[3]
+	if status := request.Status(); status.Code != codes.Error || status.Description != "retries exhausted" {
+		t.Errorf("Expected the request span to report exhausted retries, got %v", status)
+	}
+	if got := attr(request.Attributes(), AttemptsKey).AsInt64(); got != 3 {
+		t.Errorf("Expected 3 attempts on the request span, got %d", got)
+	}
+}
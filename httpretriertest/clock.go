@@ -0,0 +1,176 @@
+// Package httpretriertest provides utilities for testing code that uses httpretrier
+package httpretriertest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/p2p-b2b/httpretrier"
+)
+
+// ManualClock is a httpretrier.Clock whose time only moves when Advance is called.
+// Sleepers block until the clock has been advanced past their wake-up time,
+// which lets tests drive backoff delays deterministically without real sleeps.
+// It is safe for concurrent use.
+type ManualClock struct {
+	mu       sync.Mutex
+	now      time.Time
+	sleepers []*sleeper
+
+	// changed is closed and replaced whenever the set of sleepers changes
+	changed chan struct{}
+}
+
+// sleeper is a pending Sleep call
+type sleeper struct {
+	until time.Time
+	done  chan struct{}
+}
+
+var _ httpretrier.Clock = (*ManualClock)(nil)
+
+// NewManualClock creates a ManualClock set to the given time
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{
+		now:     now,
+		changed: make(chan struct{}),
+	}
+}
+
+// Now returns the clock's current time
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Sleep blocks until the clock has been advanced by d or ctx is done.
+// A non-positive duration returns immediately.
+func (c *ManualClock) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	s := &sleeper{until: c.now.Add(d), done: make(chan struct{})}
+	c.sleepers = append(c.sleepers, s)
+	c.notifyLocked()
+	c.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		c.removeLocked(s)
+		c.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Advance moves the clock forward by d, waking up every sleeper whose
+// wake-up time has been reached
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	pending := c.sleepers[:0]
+	for _, s := range c.sleepers {
+		if !s.until.After(c.now) {
+			close(s.done)
+			continue
+		}
+		pending = append(pending, s)
+	}
+	c.sleepers = pending
+	c.notifyLocked()
+}
+
+// BlockUntilSleepers blocks until at least n goroutines are sleeping on the clock
+func (c *ManualClock) BlockUntilSleepers(n int) {
+	for {
+		c.mu.Lock()
+		if len(c.sleepers) >= n {
+			c.mu.Unlock()
+			return
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		<-changed
+	}
+}
+
+// removeLocked drops a sleeper that gave up waiting. c.mu must be held.
+func (c *ManualClock) removeLocked(s *sleeper) {
+	for i, pending := range c.sleepers {
+		if pending == s {
+			c.sleepers = append(c.sleepers[:i], c.sleepers[i+1:]...)
+			c.notifyLocked()
+			return
+		}
+	}
+}
+
+// notifyLocked wakes up BlockUntilSleepers callers. c.mu must be held.
+func (c *ManualClock) notifyLocked() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// InstantClock is a httpretrier.Clock whose Sleep returns immediately after
+// advancing the clock by the requested duration, and records that duration.
+// It lets tests assert the exact backoff sequence of a client without waiting
+// or driving the clock from another goroutine. It is safe for concurrent use.
+type InstantClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+var _ httpretrier.Clock = (*InstantClock)(nil)
+
+// NewInstantClock creates an InstantClock set to the given time
+func NewInstantClock(now time.Time) *InstantClock {
+	return &InstantClock{now: now}
+}
+
+// Now returns the clock's current time
+func (c *InstantClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Sleep records d and advances the clock by it, unless ctx is already done.
+// A non-positive duration returns immediately without being recorded.
+func (c *InstantClock) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+
+	return nil
+}
+
+// Sleeps returns the durations passed to Sleep so far, in call order
+func (c *InstantClock) Sleeps() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.sleeps)
+}
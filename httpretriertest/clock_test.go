@@ -0,0 +1,158 @@
+package httpretriertest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/p2p-b2b/httpretrier"
+)
+
+func TestManualClock_SleepAndAdvance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- clock.Sleep(context.Background(), 2*time.Second)
+	}()
+
+	clock.BlockUntilSleepers(1)
+
+	clock.Advance(1 * time.Second)
+	select {
+	case <-done:
+		t.Fatal("Expected sleeper to keep waiting before its wake-up time")
+	default:
+	}
+
+	clock.Advance(1 * time.Second)
+	if err := <-done; err != nil {
+		t.Errorf("Expected sleep to complete, got %v", err)
+	}
+	if got := clock.Now(); !got.Equal(start.Add(2 * time.Second)) {
+		t.Errorf("Expected clock at %v, got %v", start.Add(2*time.Second), got)
+	}
+}
+
+func TestManualClock_SleepContextCanceled(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- clock.Sleep(ctx, time.Hour)
+	}()
+
+	clock.BlockUntilSleepers(1)
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestManualClock_ClientBackoffSequence(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	clock := NewManualClock(time.Now())
+	client := httpretrier.NewClientBuilder().
+		WithMaxRetries(3).
+		WithRetryStrategy(httpretrier.ExponentialBackoffStrategy).
+		WithRetryBaseDelay(1 * time.Second).
+		WithRetryMaxDelay(30 * time.Second).
+		WithClock(clock).
+		Build()
+
+	done := make(chan error, 1)
+	go func() {
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+
+	for i, delay := range []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second} {
+		clock.BlockUntilSleepers(1)
+
+		// One nanosecond short of the delay, the next attempt must not start
+		clock.Advance(delay - time.Nanosecond)
+		if got := atomic.LoadInt32(&attempts); got != int32(i+1) {
+			t.Fatalf("Expected %d attempts before delay %v elapsed, got %d", i+1, delay, got)
+		}
+		clock.Advance(time.Nanosecond)
+	}
+
+	if err := <-done; err == nil {
+		t.Error("Expected an error after all retries failed")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 4 {
+		t.Errorf("Expected 4 attempts, got %d", got)
+	}
+}
+
+func TestInstantClock_RecordsSleeps(t *testing.T) {
+	start := time.Now()
+	clock := NewInstantClock(start)
+
+	for _, d := range []time.Duration{time.Second, 0, 2 * time.Second} {
+		if err := clock.Sleep(context.Background(), d); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if got := clock.Now().Sub(start); got != 3*time.Second {
+		t.Errorf("Expected the clock to advance by 3s, got %v", got)
+	}
+	if got := clock.Sleeps(); !slices.Equal(got, []time.Duration{time.Second, 2 * time.Second}) {
+		t.Errorf("Expected sleeps [1s 2s], got %v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := clock.Sleep(ctx, time.Second); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if got := len(clock.Sleeps()); got != 2 {
+		t.Errorf("Expected the canceled sleep not to be recorded, got %d sleeps", got)
+	}
+}
+
+func TestInstantClock_ClientBackoffSequence(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	clock := NewInstantClock(time.Now())
+	client := httpretrier.NewClientBuilder().
+		WithMaxRetries(4).
+		WithRetryStrategy(httpretrier.ExponentialBackoffStrategy).
+		WithRetryBaseDelay(1 * time.Second).
+		WithRetryMaxDelay(5 * time.Second).
+		WithTimeout(30 * time.Second). // Leave room for the 12s of delays before the client deadline
+		WithClock(clock).
+		Build()
+
+	resp, err := client.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected an error after all retries failed")
+	}
+
+	expected := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	if got := clock.Sleeps(); !slices.Equal(got, expected) {
+		t.Errorf("Expected backoff sequence %v, got %v", expected, got)
+	}
+}
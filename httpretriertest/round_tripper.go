@@ -0,0 +1,145 @@
+package httpretriertest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/p2p-b2b/httpretrier"
+)
+
+// Response is the scripted outcome of a call to a RecordingRoundTripper:
+// either Err, or a response built from the other fields
+type Response struct {
+	StatusCode int         // Status of the response, 200 if zero
+	Header     http.Header // Headers of the response
+	Body       string      // Body of the response
+	Err        error       // Error returned instead of a response
+}
+
+// Call is a request received by a RecordingRoundTripper
+type Call struct {
+	Request *http.Request // Request as received, its body already read
+	Body    []byte        // Body of the request, nil if it had none
+	Time    time.Time     // Time of the call, according to the round tripper's Clock
+}
+
+// RecordingRoundTripper is an http.RoundTripper returning scripted responses
+// and recording the requests it receives. Used as the base transport of a
+// client, see httpretrier.ClientBuilder.WithBaseTransport, it records every
+// attempt of the requests, e.g.
+//
+//	rt := httpretriertest.NewRecordingRoundTripper(
+//		httpretriertest.Response{StatusCode: http.StatusServiceUnavailable},
+//		httpretriertest.Response{StatusCode: http.StatusOK},
+//	)
+//	client := httpretrier.NewClientBuilder().WithBaseTransport(rt).Build()
+//
+// It is safe for concurrent use.
+type RecordingRoundTripper struct {
+	// Clock stamps the calls, the real clock if nil. Sharing the client's
+	// clock, e.g. a ManualClock, makes the delays between attempts exact.
+	Clock httpretrier.Clock
+
+	mu        sync.Mutex
+	responses []Response
+	calls     []Call
+}
+
+var _ http.RoundTripper = (*RecordingRoundTripper)(nil)
+
+// NewRecordingRoundTripper creates a RecordingRoundTripper answering the nth
+// call with the nth response. Once they are used up, the last response is
+// repeated, and without responses every call gets a 200 OK.
+func NewRecordingRoundTripper(responses ...Response) *RecordingRoundTripper {
+	return &RecordingRoundTripper{responses: responses}
+}
+
+// RoundTrip records req, reading its body, and returns the next scripted response
+func (rt *RecordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	call := Call{Request: req}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		call.Body = body
+	}
+
+	rt.mu.Lock()
+	if rt.Clock != nil {
+		call.Time = rt.Clock.Now()
+	} else {
+		call.Time = time.Now()
+	}
+	rt.calls = append(rt.calls, call)
+	scripted := Response{}
+	if len(rt.responses) > 0 {
+		scripted = rt.responses[min(len(rt.calls), len(rt.responses))-1]
+	}
+	rt.mu.Unlock()
+
+	if scripted.Err != nil {
+		return nil, scripted.Err
+	}
+
+	statusCode := scripted.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	header := scripted.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(scripted.Body))),
+		ContentLength: int64(len(scripted.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Calls returns the calls received so far, in order
+func (rt *RecordingRoundTripper) Calls() []Call {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	calls := make([]Call, len(rt.calls))
+	copy(calls, rt.calls)
+	return calls
+}
+
+// Attempts returns the number of calls received so far
+func (rt *RecordingRoundTripper) Attempts() int {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	return len(rt.calls)
+}
+
+// Delays returns the time elapsed between each call and the previous one,
+// i.e. the delays waited before the retries
+func (rt *RecordingRoundTripper) Delays() []time.Duration {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if len(rt.calls) < 2 {
+		return nil
+	}
+
+	delays := make([]time.Duration, len(rt.calls)-1)
+	for i := 1; i < len(rt.calls); i++ {
+		delays[i-1] = rt.calls[i].Time.Sub(rt.calls[i-1].Time)
+	}
+	return delays
+}
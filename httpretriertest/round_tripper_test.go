@@ -0,0 +1,80 @@
+package httpretriertest
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/p2p-b2b/httpretrier"
+)
+
+func TestRecordingRoundTripper_ClientAttempts(t *testing.T) {
+	clock := NewInstantClock(time.Now())
+	rt := NewRecordingRoundTripper(
+		Response{StatusCode: http.StatusServiceUnavailable},
+		Response{Err: errors.New("connection reset")},
+		Response{StatusCode: http.StatusOK, Header: http.Header{"X-Test": {"ok"}}, Body: "done"},
+	)
+	rt.Clock = clock
+
+	client := httpretrier.NewClientBuilder().
+		WithBaseTransport(rt).
+		WithMaxRetries(3).
+		WithRetryStrategy(httpretrier.ExponentialBackoffStrategy).
+		WithRetryBaseDelay(1 * time.Second).
+		WithRetryMaxDelay(30 * time.Second).
+		WithClock(clock).
+		Build()
+
+	resp, err := client.Post("http://example.com/items", "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "done" || resp.Header.Get("X-Test") != "ok" {
+		t.Errorf("Expected the scripted response, got %d %q %v", resp.StatusCode, body, resp.Header)
+	}
+
+	if got := rt.Attempts(); got != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", got)
+	}
+	if got := rt.Delays(); !slices.Equal(got, []time.Duration{1 * time.Second, 2 * time.Second}) {
+		t.Errorf("Expected delays [1s 2s], got %v", got)
+	}
+	for i, call := range rt.Calls() {
+		if call.Request.Method != http.MethodPost || call.Request.URL.Path != "/items" {
+			t.Errorf("Call %d: expected POST /items, got %s %s", i, call.Request.Method, call.Request.URL.Path)
+		}
+		if string(call.Body) != "payload" {
+			t.Errorf("Call %d: expected body %q, got %q", i, "payload", call.Body)
+		}
+	}
+}
+
+func TestRecordingRoundTripper_RepeatsLastResponse(t *testing.T) {
+	rt := NewRecordingRoundTripper()
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a 200 OK without scripted responses, got %v, %v", resp, err)
+	}
+	if rt.Calls()[0].Body != nil {
+		t.Errorf("Expected no body, got %q", rt.Calls()[0].Body)
+	}
+
+	rt = NewRecordingRoundTripper(Response{StatusCode: http.StatusOK}, Response{StatusCode: http.StatusTeapot})
+	for _, expected := range []int{http.StatusOK, http.StatusTeapot, http.StatusTeapot} {
+		resp, err := rt.RoundTrip(req)
+		if err != nil || resp.StatusCode != expected {
+			t.Errorf("Expected %d, got %v, %v", expected, resp, err)
+		}
+	}
+	if got := rt.Delays(); len(got) != 2 {
+		t.Errorf("Expected 2 delays, got %v", got)
+	}
+}
@@ -0,0 +1,47 @@
+package httpretrier
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// isIdempotentMethod reports whether sending a request with method several
+// times has the same effect as sending it once
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// newIdempotencyKey returns a random (version 4) UUID
+func newIdempotencyKey() string {
+	var b [16]byte
+	// crypto/rand.Read never fails
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// withIdempotencyKey returns req with a new idempotency key in the
+// IdempotencyKeyHeader, so every attempt of the request sends the same key.
+// Idempotent requests and requests already carrying a key are returned as is.
+// The key is set on a clone; the caller's request is left untouched.
+func (r *retryTransport) withIdempotencyKey(req *http.Request) *http.Request {
+	if r.IdempotencyKeyHeader == "" || isIdempotentMethod(req.Method) || req.Header.Get(r.IdempotencyKeyHeader) != "" {
+		return req
+	}
+
+	req = req.Clone(req.Context())
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	req.Header.Set(r.IdempotencyKeyHeader, newIdempotencyKey())
+
+	return req
+}
@@ -0,0 +1,114 @@
+package httpretrier
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewIdempotencyKey(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 100 {
+		key := newIdempotencyKey()
+		if !uuidPattern.MatchString(key) {
+			t.Fatalf("Expected a version 4 UUID, got %q", key)
+		}
+		if seen[key] {
+			t.Fatalf("Expected unique keys, got %q twice", key)
+		}
+		seen[key] = true
+	}
+}
+
+func TestRetryTransport_IdempotencyKey(t *testing.T) {
+	var keys []string
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			keys = append(keys, req.Header.Get(DefaultIdempotencyKeyHeader))
+			status := http.StatusOK
+			if len(keys)%2 == 1 {
+				status = http.StatusServiceUnavailable
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader("body")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:            mockRT,
+		MaxRetries:           2,
+		RetryStrategy:        FixedDelay(1 * time.Millisecond),
+		IdempotencyKeyHeader: DefaultIdempotencyKeyHeader,
+	}
+
+	send := func(req *http.Request) {
+		t.Helper()
+		resp, err := retryRT.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// Two POSTs, each failing once: the key is the same across the attempts
+	// of a request but differs between requests
+	first := httptest.NewRequest("POST", "http://example.com", strings.NewReader("payload"))
+	send(first)
+	send(httptest.NewRequest("POST", "http://example.com", strings.NewReader("payload")))
+
+	if got := first.Header.Get(DefaultIdempotencyKeyHeader); got != "" {
+		t.Errorf("Expected the caller's request to be left untouched, got key %q", got)
+	}
+
+	if len(keys) != 4 {
+		t.Fatalf("Expected 4 attempts, got %d", len(keys))
+	}
+	if !uuidPattern.MatchString(keys[0]) || keys[0] != keys[1] {
+		t.Errorf("Expected the same key on both attempts of the first request, got %q and %q", keys[0], keys[1])
+	}
+	if keys[2] != keys[3] || keys[2] == keys[0] {
+		t.Errorf("Expected a new key for the second request, got %q after %q", keys[2], keys[0])
+	}
+
+	// A key set by the caller is kept
+	keys = nil
+	req := httptest.NewRequest("PATCH", "http://example.com", nil)
+	req.Header.Set(DefaultIdempotencyKeyHeader, "caller-key")
+	send(req)
+	if keys[0] != "caller-key" || keys[1] != "caller-key" {
+		t.Errorf("Expected the caller's key on every attempt, got %v", keys)
+	}
+
+	// Idempotent requests get no key
+	keys = nil
+	send(httptest.NewRequest("GET", "http://example.com", nil))
+	if keys[0] != "" {
+		t.Errorf("Expected no key on a GET, got %q", keys[0])
+	}
+}
+
+func TestClientBuilder_WithIdempotencyKey(t *testing.T) {
+	rt := NewClientBuilder().Build().Transport.(*retryTransport)
+	if rt.IdempotencyKeyHeader != "" {
+		t.Errorf("Expected no idempotency key by default, got %q", rt.IdempotencyKeyHeader)
+	}
+
+	rt = NewClientBuilder().WithIdempotencyKey("").Build().Transport.(*retryTransport)
+	if rt.IdempotencyKeyHeader != DefaultIdempotencyKeyHeader {
+		t.Errorf("Expected the default header, got %q", rt.IdempotencyKeyHeader)
+	}
+
+	rt = NewClientBuilder().WithIdempotencyKey("X-Request-Key").Build().Transport.(*retryTransport)
+	if rt.IdempotencyKeyHeader != "X-Request-Key" {
+		t.Errorf("Expected X-Request-Key, got %q", rt.IdempotencyKeyHeader)
+	}
+}
@@ -0,0 +1,42 @@
+package httpretrier
+
+import "net/http"
+
+// Middleware wraps an http.RoundTripper, e.g. to trace or measure the
+// requests going through it
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// roundTripperFunc adapts a function to the http.RoundTripper interface
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// wrapMiddlewares wraps rt with middlewares, the first one being the outermost
+func wrapMiddlewares(rt http.RoundTripper, middlewares []Middleware) http.RoundTripper {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		rt = middlewares[i](rt)
+	}
+
+	return rt
+}
+
+// retryTransportOf returns the retry transport rt is or wraps, seeing through
+// the RoundTrippers wrapping another one that have an Unwrap method returning
+// it, like errors do for errors.Unwrap. It returns false if there is none.
+func retryTransportOf(rt http.RoundTripper) (*retryTransport, bool) {
+	for rt != nil {
+		switch t := rt.(type) {
+		case *retryTransport:
+			return t, true
+		case interface{ Unwrap() http.RoundTripper }:
+			rt = t.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
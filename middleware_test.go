@@ -0,0 +1,162 @@
+package httpretrier
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientBuilder_Middlewares(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var trace []string
+	var infos []AttemptInfo
+	tag := func(name string, attempts bool) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				trace = append(trace, name)
+				if attempts && name == "attempt-outer" {
+					info, ok := AttemptInfoFromContext(req.Context())
+					if !ok {
+						t.Error("Expected attempt info in the attempt's context")
+					}
+					infos = append(infos, info)
+				}
+				return next.RoundTrip(req)
+			})
+		}
+	}
+
+	client := NewClientBuilder().
+		WithMaxRetries(3).
+		WithRetryStrategy(FixedDelayStrategy).
+		WithRetryBaseDelay(300 * time.Millisecond).
+		WithClock(&fakeClock{now: time.Now()}).
+		WithAttemptMiddleware(tag("attempt-outer", true)).
+		WithAttemptMiddleware(tag("attempt-inner", true)).
+		WithAttemptMiddleware(nil).
+		WithRequestMiddleware(tag("request-outer", false)).
+		WithRequestMiddleware(tag("request-inner", false)).
+		Build()
+
+	// Request middlewares leave the retry transport in place
+	if _, ok := client.Transport.(*retryTransport); !ok {
+		t.Fatalf("Client transport is not of type *retryTransport, got %T", client.Transport)
+	}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	want := "request-outer,request-inner," +
+		"attempt-outer,attempt-inner,attempt-outer,attempt-inner,attempt-outer,attempt-inner"
+	if got := strings.Join(trace, ","); got != want {
+		t.Errorf("Expected middlewares called as %s, got %s", want, got)
+	}
+
+	if len(infos) != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", len(infos))
+	}
+	for i, info := range infos {
+		if info.Attempt != i {
+			t.Errorf("Expected attempt %d, got %d", i, info.Attempt)
+		}
+		if wantDelay := min(time.Duration(i), 1) * 300 * time.Millisecond; info.Delay != wantDelay {
+			t.Errorf("Expected a delay of %v before attempt %d, got %v", wantDelay, i+1, info.Delay)
+		}
+	}
+}
+
+func TestRetryTransport_RequestMiddlewareSeesOutcome(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("simulated transport error")
+		},
+	}
+
+	var outcome error
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    2,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+	}
+	retryRT.requestRoundTripper = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := retryRT.roundTrip(req)
+		outcome = err
+		return resp, err
+	})
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	if _, err := retryRT.RoundTrip(req); !errors.Is(err, ErrAllRetriesFailed) {
+		t.Fatalf("Expected ErrAllRetriesFailed, got %v", err)
+	}
+	if !errors.Is(outcome, ErrAllRetriesFailed) {
+		t.Errorf("Expected the middleware to see the final error, got %v", outcome)
+	}
+}
+
+func TestAttemptInfoFromContext_OutsideAttempt(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	if info, ok := AttemptInfoFromContext(req.Context()); ok {
+		t.Errorf("Expected no attempt info, got %+v", info)
+	}
+}
+
+func TestAttemptFromContext(t *testing.T) {
+	var seen []int
+	var keyed []AttemptInfo
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			// The base transport sees the attempt, like a logging round tripper would
+			seen = append(seen, AttemptFromContext(req.Context()))
+			info, _ := req.Context().Value(AttemptInfoKey{}).(AttemptInfo)
+			keyed = append(keyed, info)
+			status := http.StatusServiceUnavailable
+			if len(seen) == 3 {
+				status = http.StatusOK
+			}
+			return &http.Response{StatusCode: status, Body: http.NoBody, Header: make(http.Header)}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if !slices.Equal(seen, []int{0, 1, 2}) {
+		t.Errorf("Expected attempts [0 1 2], got %v", seen)
+	}
+	for i, info := range keyed {
+		if info.Attempt != i {
+			t.Errorf("Attempt %d: expected the info under AttemptInfoKey, got %+v", i, info)
+		}
+	}
+
+	if attempt := AttemptFromContext(req.Context()); attempt != 0 {
+		t.Errorf("Expected 0 outside an attempt, got %d", attempt)
+	}
+}
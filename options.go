@@ -0,0 +1,680 @@
+package httpretrier
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Option configures a client created by New.
+// Each Option applies the ClientBuilder method of the same setting,
+// so values are validated the same way as with the builder.
+type Option func(*ClientBuilder)
+
+// New creates an HTTP client with retry capabilities, starting from the
+// builder's defaults and applying opts in order, e.g.
+//
+//	client := httpretrier.New(
+//		httpretrier.WithRetries(5),
+//		httpretrier.WithStrategy(httpretrier.JitterBackoffStrategy),
+//		httpretrier.WithClientTimeout(10*time.Second),
+//	)
+//
+// Invalid values are handled like ClientBuilder.Build: a warning is logged and
+// the default value is used.
+func New(opts ...Option) *http.Client {
+	b := NewClientBuilder()
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b.Build()
+}
+
+// WithRetries returns an Option that sets the maximum number of retries, see ClientBuilder.WithMaxRetries
+func WithRetries(maxRetries int) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxRetries(maxRetries)
+	}
+}
+
+// WithMaxAttempts returns an Option that sets the maximum number of attempts, including the first one, see ClientBuilder.WithMaxAttempts
+func WithMaxAttempts(maxAttempts int) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxAttempts(maxAttempts)
+	}
+}
+
+// WithRetryableStatusRange returns an Option that makes a range of statuses retryable, see ClientBuilder.WithRetryableStatusRange
+func WithRetryableStatusRange(min, max int) Option {
+	return func(b *ClientBuilder) {
+		b.WithRetryableStatusRange(min, max)
+	}
+}
+
+// WithNoRetryOnStatus returns an Option that makes statuses never retried, see ClientBuilder.WithNoRetryOnStatus
+func WithNoRetryOnStatus(statuses ...int) Option {
+	return func(b *ClientBuilder) {
+		b.WithNoRetryOnStatus(statuses...)
+	}
+}
+
+// WithStatusDelay returns an Option that sets the delays before retrying given statuses, see ClientBuilder.WithStatusDelay
+func WithStatusDelay(delays map[int]time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithStatusDelay(delays)
+	}
+}
+
+// WithMaxElapsedTime returns an Option that sets the time budget across all attempts, see ClientBuilder.WithMaxElapsedTime
+func WithMaxElapsedTime(maxElapsedTime time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxElapsedTime(maxElapsedTime)
+	}
+}
+
+// WithMinAttemptBudget returns an Option that sets the time an attempt needs before the deadline, see ClientBuilder.WithMinAttemptBudget
+func WithMinAttemptBudget(minAttemptBudget time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithMinAttemptBudget(minAttemptBudget)
+	}
+}
+
+// WithStrategy returns an Option that sets the retry strategy, see ClientBuilder.WithRetryStrategy
+func WithStrategy(retryStrategy Strategy) Option {
+	return func(b *ClientBuilder) {
+		b.WithRetryStrategy(retryStrategy)
+	}
+}
+
+// WithStrategyName returns an Option that sets the retry strategy from its name, see ClientBuilder.WithRetryStrategyAsString
+func WithStrategyName(retryStrategy string) Option {
+	return func(b *ClientBuilder) {
+		b.WithRetryStrategyAsString(retryStrategy)
+	}
+}
+
+// WithBaseDelay returns an Option that sets the base delay of the retry strategy, see ClientBuilder.WithRetryBaseDelay
+func WithBaseDelay(baseDelay time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithRetryBaseDelay(baseDelay)
+	}
+}
+
+// WithValidationRanges returns an Option that sets the ranges of values accepted for the settings, see ClientBuilder.WithValidationRanges
+func WithValidationRanges(ranges ValidationRanges) Option {
+	return func(b *ClientBuilder) {
+		b.WithValidationRanges(ranges)
+	}
+}
+
+// WithMaxDelay returns an Option that sets the maximum delay of the retry strategy, see ClientBuilder.WithRetryMaxDelay
+func WithMaxDelay(maxDelay time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithRetryMaxDelay(maxDelay)
+	}
+}
+
+// WithMinDelay returns an Option that sets the floor of the retry delays, see ClientBuilder.WithMinDelay
+func WithMinDelay(minDelay time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithMinDelay(minDelay)
+	}
+}
+
+// WithMultiplier returns an Option that sets the growth factor of exponential backoff strategies, see ClientBuilder.WithRetryMultiplier
+func WithMultiplier(multiplier float64) Option {
+	return func(b *ClientBuilder) {
+		b.WithRetryMultiplier(multiplier)
+	}
+}
+
+// WithStrictMaxDelay returns an Option that makes the max delay bound the jitter too, see ClientBuilder.WithStrictMaxDelay
+func WithStrictMaxDelay(strict bool) Option {
+	return func(b *ClientBuilder) {
+		b.WithStrictMaxDelay(strict)
+	}
+}
+
+// WithMaxJitter returns an Option that bounds the jitter of the jitter strategies, see ClientBuilder.WithMaxJitter
+func WithMaxJitter(maxJitter time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxJitter(maxJitter)
+	}
+}
+
+// WithFixedJitter returns an Option that sets the jitter fraction of the fixed delay strategy, see ClientBuilder.WithFixedJitter
+func WithFixedJitter(fraction float64) Option {
+	return func(b *ClientBuilder) {
+		b.WithFixedJitter(fraction)
+	}
+}
+
+// WithConnectTimeoutBackoff returns an Option that sets the retry strategy used after connect timeouts, see ClientBuilder.WithConnectTimeoutBackoff
+func WithConnectTimeoutBackoff(strategy RetryStrategy) Option {
+	return func(b *ClientBuilder) {
+		b.WithConnectTimeoutBackoff(strategy)
+	}
+}
+
+// WithRequestSeededJitter returns an Option that sets the function seeding the jitter of each request, see ClientBuilder.WithRequestSeededJitter
+func WithRequestSeededJitter(seedFunc func(req *http.Request) int64) Option {
+	return func(b *ClientBuilder) {
+		b.WithRequestSeededJitter(seedFunc)
+	}
+}
+
+// WithJitterSeed returns an Option that sets the seed of the jitter random number generator, see ClientBuilder.WithJitterSeed
+func WithJitterSeed(seed int64) Option {
+	return func(b *ClientBuilder) {
+		b.WithJitterSeed(seed)
+	}
+}
+
+// WithRandomizedMaxDelay returns an Option that sets the mean of the randomized maximum delay, see ClientBuilder.WithRandomizedMaxDelay
+func WithRandomizedMaxDelay(mean time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithRandomizedMaxDelay(mean)
+	}
+}
+
+// WithMaxDelayDistribution returns an Option that sets the distribution of the randomized maximum delay, see ClientBuilder.WithMaxDelayDistribution
+func WithMaxDelayDistribution(distribution DelayDistribution) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxDelayDistribution(distribution)
+	}
+}
+
+// WithRetryCondition returns an Option that sets the condition deciding whether an attempt is retried, see ClientBuilder.WithRetryCondition
+func WithRetryCondition(condition RetryCondition) Option {
+	return func(b *ClientBuilder) {
+		b.WithRetryCondition(condition)
+	}
+}
+
+// WithRespectRetryAfter returns an Option that sets whether Retry-After headers are honored, see ClientBuilder.WithRespectRetryAfter
+func WithRespectRetryAfter(respect bool) Option {
+	return func(b *ClientBuilder) {
+		b.WithRespectRetryAfter(respect)
+	}
+}
+
+// WithDryRun returns an Option that sets whether the delays between attempts are skipped, see ClientBuilder.WithDryRun
+func WithDryRun(dryRun bool) Option {
+	return func(b *ClientBuilder) {
+		b.WithDryRun(dryRun)
+	}
+}
+
+// WithExpectedContentType returns an Option that makes 2xx responses with another Content-Type retryable, see ClientBuilder.WithExpectedContentType
+func WithExpectedContentType(contentType string) Option {
+	return func(b *ClientBuilder) {
+		b.WithExpectedContentType(contentType)
+	}
+}
+
+// WithResponseValidator returns an Option that sets a function deciding from its body whether a response is a success, see ClientBuilder.WithResponseValidator
+func WithResponseValidator(validator func(resp *http.Response, body []byte) bool) Option {
+	return func(b *ClientBuilder) {
+		b.WithResponseValidator(validator)
+	}
+}
+
+// WithMaxValidateBodySize returns an Option that sets the maximum size of the response bodies passed to the validator, see ClientBuilder.WithMaxValidateBodySize
+func WithMaxValidateBodySize(maxValidateBodySize int64) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxValidateBodySize(maxValidateBodySize)
+	}
+}
+
+// WithRetryIfMissingHeader returns an Option that sets a header whose absence from a response triggers a retry, see ClientBuilder.WithRetryIfMissingHeader
+func WithRetryIfMissingHeader(name string) Option {
+	return func(b *ClientBuilder) {
+		b.WithRetryIfMissingHeader(name)
+	}
+}
+
+// WithReturnLastResponse returns an Option that sets whether the last failed response is returned instead of an error, see ClientBuilder.WithReturnLastResponse
+func WithReturnLastResponse(returnLastResponse bool) Option {
+	return func(b *ClientBuilder) {
+		b.WithReturnLastResponse(returnLastResponse)
+	}
+}
+
+// WithPerAttemptTimeout returns an Option that sets the timeout of each individual attempt, see ClientBuilder.WithPerAttemptTimeout
+func WithPerAttemptTimeout(perAttemptTimeout time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithPerAttemptTimeout(perAttemptTimeout)
+	}
+}
+
+// WithIdempotencyKey returns an Option that sets the header carrying an idempotency key on non-idempotent requests, see ClientBuilder.WithIdempotencyKey
+func WithIdempotencyKey(headerName string) Option {
+	return func(b *ClientBuilder) {
+		b.WithIdempotencyKey(headerName)
+	}
+}
+
+// WithAuthRefresh returns an Option that sets the function refreshing the credentials on 401 responses, see ClientBuilder.WithAuthRefresh
+func WithAuthRefresh(refresh func(ctx context.Context) (string, error)) Option {
+	return func(b *ClientBuilder) {
+		b.WithAuthRefresh(refresh)
+	}
+}
+
+// WithAuthHeader returns an Option that sets the header carrying the refreshed credentials, see ClientBuilder.WithAuthHeader
+func WithAuthHeader(name string) Option {
+	return func(b *ClientBuilder) {
+		b.WithAuthHeader(name)
+	}
+}
+
+// WithDefaultHeaders returns an Option that sets headers added to every request, see ClientBuilder.WithDefaultHeaders
+func WithDefaultHeaders(headers http.Header) Option {
+	return func(b *ClientBuilder) {
+		b.WithDefaultHeaders(headers)
+	}
+}
+
+// WithUserAgent returns an Option that sets the User-Agent header of the requests, see ClientBuilder.WithUserAgent
+func WithUserAgent(userAgent string) Option {
+	return func(b *ClientBuilder) {
+		b.WithUserAgent(userAgent)
+	}
+}
+
+// WithAttemptHeader returns an Option that sets the name of the header carrying the attempt number, see ClientBuilder.WithAttemptHeader
+func WithAttemptHeader(name string) Option {
+	return func(b *ClientBuilder) {
+		b.WithAttemptHeader(name)
+	}
+}
+
+// WithRetryEvents returns an Option that sets the channel receiving an event for each retry, see ClientBuilder.WithRetryEvents
+func WithRetryEvents(events chan<- RetryEvent) Option {
+	return func(b *ClientBuilder) {
+		b.WithRetryEvents(events)
+	}
+}
+
+// WithRetryEventChannel returns an Option that sets the channel receiving an event for each retry
+//
+// Deprecated: use WithRetryEvents, which it is an alias of
+func WithRetryEventChannel(events chan<- RetryEvent) Option {
+	return WithRetryEvents(events)
+}
+
+// WithOnRetry returns an Option that adds a callback called for every retry, see ClientBuilder.WithOnRetry
+func WithOnRetry(onRetry func(RetryEvent)) Option {
+	return func(b *ClientBuilder) {
+		b.WithOnRetry(onRetry)
+	}
+}
+
+// WithOnRequestDone returns an Option that adds a callback called with the stats of every request, see ClientBuilder.WithOnRequestDone
+func WithOnRequestDone(onRequestDone func(Stats)) Option {
+	return func(b *ClientBuilder) {
+		b.WithOnRequestDone(onRequestDone)
+	}
+}
+
+// WithAttemptMiddleware returns an Option that adds a middleware wrapping the transport that sends each attempt, see ClientBuilder.WithAttemptMiddleware
+func WithAttemptMiddleware(middleware Middleware) Option {
+	return func(b *ClientBuilder) {
+		b.WithAttemptMiddleware(middleware)
+	}
+}
+
+// WithRequestMiddleware returns an Option that adds a middleware wrapping the retry logic as a whole, see ClientBuilder.WithRequestMiddleware
+func WithRequestMiddleware(middleware Middleware) Option {
+	return func(b *ClientBuilder) {
+		b.WithRequestMiddleware(middleware)
+	}
+}
+
+// WithAutoBufferBody returns an Option that sets whether request bodies are buffered so they can be replayed, see ClientBuilder.WithAutoBufferBody
+func WithAutoBufferBody(autoBufferBody bool) Option {
+	return func(b *ClientBuilder) {
+		b.WithAutoBufferBody(autoBufferBody)
+	}
+}
+
+// WithMaxBufferSize returns an Option that sets the maximum size of a buffered request body, see ClientBuilder.WithMaxBufferSize
+func WithMaxBufferSize(maxBufferSize int64) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxBufferSize(maxBufferSize)
+	}
+}
+
+// WithMaxTotalBufferBytes returns an Option that sets the maximum number of bytes buffered across concurrent requests, see ClientBuilder.WithMaxTotalBufferBytes
+func WithMaxTotalBufferBytes(maxTotalBufferBytes int64) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxTotalBufferBytes(maxTotalBufferBytes)
+	}
+}
+
+// WithWaitForBufferBudget returns an Option that sets whether requests wait for buffer budget to be available, see ClientBuilder.WithWaitForBufferBudget
+func WithWaitForBufferBudget(wait bool) Option {
+	return func(b *ClientBuilder) {
+		b.WithWaitForBufferBudget(wait)
+	}
+}
+
+// WithOnBufferTruncated returns an Option that sets the hook called when a request body is too large to buffer, see ClientBuilder.WithOnBufferTruncated
+func WithOnBufferTruncated(hook func(size int64)) Option {
+	return func(b *ClientBuilder) {
+		b.WithOnBufferTruncated(hook)
+	}
+}
+
+// WithMaxDrainSize returns an Option that sets the maximum number of bytes drained from failed responses, see ClientBuilder.WithMaxDrainSize
+func WithMaxDrainSize(maxDrainSize int64) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxDrainSize(maxDrainSize)
+	}
+}
+
+// WithDrainBufferSize returns an Option that sets the size of the buffers draining failed responses, see ClientBuilder.WithDrainBufferSize
+func WithDrainBufferSize(size int) Option {
+	return func(b *ClientBuilder) {
+		b.WithDrainBufferSize(size)
+	}
+}
+
+// WithRetryBudget returns an Option that limits retries to a share of the requests, see ClientBuilder.WithRetryBudget
+func WithRetryBudget(ratio float64, minRetriesPerSec float64) Option {
+	return func(b *ClientBuilder) {
+		b.WithRetryBudget(ratio, minRetriesPerSec)
+	}
+}
+
+// WithRateLimit returns an Option that sets the maximum rate of requests, see ClientBuilder.WithRateLimit
+func WithRateLimit(rps float64, burst int) Option {
+	return func(b *ClientBuilder) {
+		b.WithRateLimit(rps, burst)
+	}
+}
+
+// WithFallbackHosts returns an Option that sets the hosts to fall back on when the retries fail, see ClientBuilder.WithFallbackHosts
+func WithFallbackHosts(hosts []string) Option {
+	return func(b *ClientBuilder) {
+		b.WithFallbackHosts(hosts)
+	}
+}
+
+// WithHedging returns an Option that enables request hedging for idempotent requests, see ClientBuilder.WithHedging
+func WithHedging(delay time.Duration, maxHedges int) Option {
+	return func(b *ClientBuilder) {
+		b.WithHedging(delay, maxHedges)
+	}
+}
+
+// WithAdaptiveBackoff returns an Option that lengthens the retry delays of hosts failing recently, see ClientBuilder.WithAdaptiveBackoff
+func WithAdaptiveBackoff() Option {
+	return func(b *ClientBuilder) {
+		b.WithAdaptiveBackoff()
+	}
+}
+
+// WithCircuitBreaker returns an Option that enables a circuit breaker for each host, see ClientBuilder.WithCircuitBreaker
+func WithCircuitBreaker(failureThreshold int, openDuration time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithCircuitBreaker(failureThreshold, openDuration)
+	}
+}
+
+// WithMaxConcurrent returns an Option that sets the maximum number of requests in flight, see ClientBuilder.WithMaxConcurrent
+func WithMaxConcurrent(maxConcurrent int) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxConcurrent(maxConcurrent)
+	}
+}
+
+// WithMaxConcurrentPerHost returns an Option that sets the maximum number of concurrent attempts per host, see ClientBuilder.WithMaxConcurrentPerHost
+func WithMaxConcurrentPerHost(maxConcurrentPerHost int) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxConcurrentPerHost(maxConcurrentPerHost)
+	}
+}
+
+// WithLogger returns an Option that sets the logger for retry messages, see ClientBuilder.WithLogger
+func WithLogger(logger *slog.Logger) Option {
+	return func(b *ClientBuilder) {
+		b.WithLogger(logger)
+	}
+}
+
+// WithClock returns an Option that sets the clock used for backoff delays and elapsed time, see ClientBuilder.WithClock
+func WithClock(clock Clock) Option {
+	return func(b *ClientBuilder) {
+		b.WithClock(clock)
+	}
+}
+
+// WithPreset returns an Option that applies the settings of a preset, see ClientBuilder.WithPreset
+func WithPreset(preset Preset) Option {
+	return func(b *ClientBuilder) {
+		b.WithPreset(preset)
+	}
+}
+
+// WithSignalAwareShutdown returns an Option that sets the signals stopping retries, see ClientBuilder.WithSignalAwareShutdown
+func WithSignalAwareShutdown(signals ...os.Signal) Option {
+	return func(b *ClientBuilder) {
+		b.WithSignalAwareShutdown(signals...)
+	}
+}
+
+// WithClientTimeout returns an Option that sets the timeout of the HTTP client, see ClientBuilder.WithTimeout
+func WithClientTimeout(timeout time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithTimeout(timeout)
+	}
+}
+
+// WithCookieJar returns an Option that sets the cookie jar of the HTTP client, see ClientBuilder.WithCookieJar
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(b *ClientBuilder) {
+		b.WithCookieJar(jar)
+	}
+}
+
+// WithDefaultCookieJar returns an Option that installs an in-memory cookie jar on the HTTP client, see ClientBuilder.WithDefaultCookieJar
+func WithDefaultCookieJar() Option {
+	return func(b *ClientBuilder) {
+		b.WithDefaultCookieJar()
+	}
+}
+
+// WithCheckRedirect returns an Option that sets the redirect policy of the HTTP client, see ClientBuilder.WithCheckRedirect
+func WithCheckRedirect(checkRedirect func(req *http.Request, via []*http.Request) error) Option {
+	return func(b *ClientBuilder) {
+		b.WithCheckRedirect(checkRedirect)
+	}
+}
+
+// WithMaxRedirects returns an Option that sets the maximum number of redirects followed, see ClientBuilder.WithMaxRedirects
+func WithMaxRedirects(maxRedirects int) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxRedirects(maxRedirects)
+	}
+}
+
+// WithBaseTransport returns an Option that sets the transport the retry logic wraps, see ClientBuilder.WithBaseTransport
+func WithBaseTransport(transport http.RoundTripper) Option {
+	return func(b *ClientBuilder) {
+		b.WithBaseTransport(transport)
+	}
+}
+
+// WithMaxIdleConns returns an Option that sets the maximum number of idle connections, see ClientBuilder.WithMaxIdleConns
+func WithMaxIdleConns(maxIdleConns int) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxIdleConns(maxIdleConns)
+	}
+}
+
+// WithMaxIdleConnsPerHost returns an Option that sets the maximum number of idle connections per host, see ClientBuilder.WithMaxIdleConnsPerHost
+func WithMaxIdleConnsPerHost(maxIdleConnsPerHost int) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxIdleConnsPerHost(maxIdleConnsPerHost)
+	}
+}
+
+// WithMaxConnsPerHost returns an Option that sets the maximum number of connections per host, see ClientBuilder.WithMaxConnsPerHost
+func WithMaxConnsPerHost(maxConnsPerHost int) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxConnsPerHost(maxConnsPerHost)
+	}
+}
+
+// WithWriteBufferSize returns an Option that sets the size of the transport's write buffer, see ClientBuilder.WithWriteBufferSize
+func WithWriteBufferSize(writeBufferSize int) Option {
+	return func(b *ClientBuilder) {
+		b.WithWriteBufferSize(writeBufferSize)
+	}
+}
+
+// WithReadBufferSize returns an Option that sets the size of the transport's read buffer, see ClientBuilder.WithReadBufferSize
+func WithReadBufferSize(readBufferSize int) Option {
+	return func(b *ClientBuilder) {
+		b.WithReadBufferSize(readBufferSize)
+	}
+}
+
+// WithMaxResponseHeaderBytes returns an Option that sets the limit on the size of the response headers, see ClientBuilder.WithMaxResponseHeaderBytes
+func WithMaxResponseHeaderBytes(maxResponseHeaderBytes int64) Option {
+	return func(b *ClientBuilder) {
+		b.WithMaxResponseHeaderBytes(maxResponseHeaderBytes)
+	}
+}
+
+// WithIdleConnTimeout returns an Option that sets the idle connection timeout, see ClientBuilder.WithIdleConnTimeout
+func WithIdleConnTimeout(idleConnTimeout time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithIdleConnTimeout(idleConnTimeout)
+	}
+}
+
+// WithTLSHandshakeTimeout returns an Option that sets the TLS handshake timeout, see ClientBuilder.WithTLSHandshakeTimeout
+func WithTLSHandshakeTimeout(tlsHandshakeTimeout time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithTLSHandshakeTimeout(tlsHandshakeTimeout)
+	}
+}
+
+// WithExpectContinueTimeout returns an Option that sets the expect continue timeout, see ClientBuilder.WithExpectContinueTimeout
+func WithExpectContinueTimeout(expectContinueTimeout time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithExpectContinueTimeout(expectContinueTimeout)
+	}
+}
+
+// WithResponseHeaderTimeout returns an Option that sets the time to wait for the response headers, see ClientBuilder.WithResponseHeaderTimeout
+func WithResponseHeaderTimeout(responseHeaderTimeout time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithResponseHeaderTimeout(responseHeaderTimeout)
+	}
+}
+
+// WithDialTimeout returns an Option that sets the TCP connect timeout, see ClientBuilder.WithDialTimeout
+func WithDialTimeout(dialTimeout time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithDialTimeout(dialTimeout)
+	}
+}
+
+// WithDialKeepAlive returns an Option that sets the interval between TCP keep-alive probes, see ClientBuilder.WithDialKeepAlive
+func WithDialKeepAlive(dialKeepAlive time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithDialKeepAlive(dialKeepAlive)
+	}
+}
+
+// WithConnMaxLifetime returns an Option that sets the maximum age of a connection, see ClientBuilder.WithConnMaxLifetime
+func WithConnMaxLifetime(connMaxLifetime time.Duration) Option {
+	return func(b *ClientBuilder) {
+		b.WithConnMaxLifetime(connMaxLifetime)
+	}
+}
+
+// WithDisableKeepAlives returns an Option that sets whether keep-alives are disabled, see ClientBuilder.WithDisableKeepAlives
+func WithDisableKeepAlives(disableKeepAlives bool) Option {
+	return func(b *ClientBuilder) {
+		b.WithDisableKeepAlives(disableKeepAlives)
+	}
+}
+
+// WithForceAttemptHTTP2 returns an Option that sets whether HTTP/2 is attempted, see ClientBuilder.WithForceAttemptHTTP2
+func WithForceAttemptHTTP2(forceAttemptHTTP2 bool) Option {
+	return func(b *ClientBuilder) {
+		b.WithForceAttemptHTTP2(forceAttemptHTTP2)
+	}
+}
+
+// WithFreshConnOnError returns an Option that sets whether idle connections are dropped after a connection error, see ClientBuilder.WithFreshConnOnError
+func WithFreshConnOnError(freshConnOnError bool) Option {
+	return func(b *ClientBuilder) {
+		b.WithFreshConnOnError(freshConnOnError)
+	}
+}
+
+// WithDisableHTTP2 returns an Option that sets whether HTTP/2 is disabled, see ClientBuilder.WithDisableHTTP2
+func WithDisableHTTP2(disableHTTP2 bool) Option {
+	return func(b *ClientBuilder) {
+		b.WithDisableHTTP2(disableHTTP2)
+	}
+}
+
+// WithTLSConfig returns an Option that sets the TLS configuration of the transport, see ClientBuilder.WithTLSConfig
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(b *ClientBuilder) {
+		b.WithTLSConfig(tlsConfig)
+	}
+}
+
+// WithProxy returns an Option that sets the function selecting the proxy of each request, see ClientBuilder.WithProxy
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(b *ClientBuilder) {
+		b.WithProxy(proxy)
+	}
+}
+
+// WithUnixSocket returns an Option that makes the transport dial a Unix domain socket for every request, see ClientBuilder.WithUnixSocket
+func WithUnixSocket(path string) Option {
+	return func(b *ClientBuilder) {
+		b.WithUnixSocket(path)
+	}
+}
+
+// WithMinTLSVersion returns an Option that sets the minimum TLS version accepted by the transport, see ClientBuilder.WithMinTLSVersion
+func WithMinTLSVersion(version uint16) Option {
+	return func(b *ClientBuilder) {
+		b.WithMinTLSVersion(version)
+	}
+}
+
+// WithClientCertificate returns an Option that sets the files of the client certificate for mTLS, see ClientBuilder.WithClientCertificate
+func WithClientCertificate(certFile, keyFile string) Option {
+	return func(b *ClientBuilder) {
+		b.WithClientCertificate(certFile, keyFile)
+	}
+}
+
+// WithRootCAFile returns an Option that sets the file of the trusted root CAs, see ClientBuilder.WithRootCAFile
+func WithRootCAFile(caFile string) Option {
+	return func(b *ClientBuilder) {
+		b.WithRootCAFile(caFile)
+	}
+}
+
+// WithProxyURL returns an Option that sets the URL of the proxy used for all requests, see ClientBuilder.WithProxyURL
+func WithProxyURL(proxyURL string) Option {
+	return func(b *ClientBuilder) {
+		b.WithProxyURL(proxyURL)
+	}
+}
@@ -0,0 +1,74 @@
+package httpretrier
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNew_Defaults(t *testing.T) {
+	httpClient := New()
+
+	rt, ok := httpClient.Transport.(*retryTransport)
+	if !ok {
+		t.Fatalf("Client transport is not of type *retryTransport, got %T", httpClient.Transport)
+	}
+	if got := DescribeClient(httpClient); got != DescribeClient(NewClientBuilder().Build()) {
+		t.Errorf("Expected the builder's defaults, got %q", got)
+	}
+	if rt.MaxRetries != DefaultMaxRetries {
+		t.Errorf("Expected %d retries, got %d", DefaultMaxRetries, rt.MaxRetries)
+	}
+}
+
+func TestNew_Options(t *testing.T) {
+	httpClient := New(
+		WithRetries(5),
+		WithStrategy(FixedDelayStrategy),
+		WithBaseDelay(time.Second),
+		WithMaxDelay(20*time.Second),
+		WithClientTimeout(15*time.Second),
+		WithMaxConnsPerHost(10),
+		WithAttemptHeader(""),
+		WithMaxRedirects(0),
+	)
+
+	expected := "retries=5 strategy=fixed base=1s max=20s timeout=15s"
+	if got := DescribeClient(httpClient); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	rt, _ := httpClient.Transport.(*retryTransport)
+	if rt.AttemptHeader != "" {
+		t.Errorf("Expected the attempt header to be disabled, got %q", rt.AttemptHeader)
+	}
+	transport, _ := rt.Transport.(*http.Transport)
+	if transport.MaxConnsPerHost != 10 {
+		t.Errorf("Expected MaxConnsPerHost 10, got %d", transport.MaxConnsPerHost)
+	}
+	if httpClient.CheckRedirect == nil {
+		t.Error("Expected a redirect policy to be set")
+	}
+}
+
+func TestNew_InvalidOptionsUseDefaults(t *testing.T) {
+	httpClient := New(
+		WithRetries(-1),
+		WithStrategyName("unknown"),
+		WithClientTimeout(time.Hour),
+	)
+
+	expected := NewClientBuilder().Build()
+	if got, want := DescribeClient(httpClient), DescribeClient(expected); got != want {
+		t.Errorf("Expected invalid options to fall back to %q, got %q", want, got)
+	}
+}
+
+func TestNew_OptionsApplyInOrder(t *testing.T) {
+	httpClient := New(WithRetries(2), WithRetries(4))
+
+	rt, _ := httpClient.Transport.(*retryTransport)
+	if rt.MaxRetries != 4 {
+		t.Errorf("Expected the last option to win, got %d retries", rt.MaxRetries)
+	}
+}
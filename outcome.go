@@ -0,0 +1,78 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// Outcome classifies the result of an HTTP request
+// It is a string type so it can be used directly in logs and metrics labels
+type Outcome string
+
+const (
+	// OutcomeSuccess is a response with a status code below 400
+	OutcomeSuccess Outcome = "success"
+
+	// OutcomeClientError is a response with a 4xx status code
+	OutcomeClientError Outcome = "client_error"
+
+	// OutcomeServerError is a response with a 5xx status code
+	OutcomeServerError Outcome = "server_error"
+
+	// OutcomeTimeout is an error caused by a timeout or an expired deadline
+	OutcomeTimeout Outcome = "timeout"
+
+	// OutcomeCanceled is an error caused by a canceled context
+	OutcomeCanceled Outcome = "canceled"
+
+	// OutcomeNetworkError is any other transport-level error
+	OutcomeNetworkError Outcome = "network_error"
+
+	// OutcomeUnknown is used when there is neither a response nor an error
+	OutcomeUnknown Outcome = "unknown"
+)
+
+func (o Outcome) String() string {
+	return string(o)
+}
+
+// ClassifyOutcome maps the result of a request to an Outcome.
+// If err wraps a *RetryError, the outcome recorded for its last attempt is used.
+func ClassifyOutcome(resp *http.Response, err error) Outcome {
+	if err != nil {
+		var retryErr *RetryError
+		if errors.As(err, &retryErr) && retryErr.Outcome != "" {
+			return retryErr.Outcome
+		}
+
+		if errors.Is(err, context.Canceled) {
+			return OutcomeCanceled
+		}
+
+		if errors.Is(err, context.DeadlineExceeded) {
+			return OutcomeTimeout
+		}
+
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return OutcomeTimeout
+		}
+
+		return OutcomeNetworkError
+	}
+
+	if resp == nil {
+		return OutcomeUnknown
+	}
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return OutcomeServerError
+	case resp.StatusCode >= http.StatusBadRequest:
+		return OutcomeClientError
+	default:
+		return OutcomeSuccess
+	}
+}
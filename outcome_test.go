@@ -0,0 +1,107 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// timeoutError is a net.Error that reports a timeout
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyOutcome(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     *http.Response
+		err      error
+		expected Outcome
+	}{
+		{
+			name:     "OK",
+			resp:     &http.Response{StatusCode: http.StatusOK},
+			expected: OutcomeSuccess,
+		},
+		{
+			name:     "Redirect",
+			resp:     &http.Response{StatusCode: http.StatusFound},
+			expected: OutcomeSuccess,
+		},
+		{
+			name:     "Not Found",
+			resp:     &http.Response{StatusCode: http.StatusNotFound},
+			expected: OutcomeClientError,
+		},
+		{
+			name:     "Too Many Requests",
+			resp:     &http.Response{StatusCode: http.StatusTooManyRequests},
+			expected: OutcomeClientError,
+		},
+		{
+			name:     "Internal Server Error",
+			resp:     &http.Response{StatusCode: http.StatusInternalServerError},
+			expected: OutcomeServerError,
+		},
+		{
+			name:     "Service Unavailable",
+			resp:     &http.Response{StatusCode: http.StatusServiceUnavailable},
+			expected: OutcomeServerError,
+		},
+		{
+			name:     "Context Canceled",
+			err:      fmt.Errorf("request failed: %w", context.Canceled),
+			expected: OutcomeCanceled,
+		},
+		{
+			name:     "Context Deadline Exceeded",
+			err:      context.DeadlineExceeded,
+			expected: OutcomeTimeout,
+		},
+		{
+			name:     "Net Timeout",
+			err:      &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}},
+			expected: OutcomeTimeout,
+		},
+		{
+			name:     "Connection Refused",
+			err:      &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			expected: OutcomeNetworkError,
+		},
+		{
+			name:     "Retry Error",
+			err:      &url.Error{Op: "Get", URL: "http://example.com", Err: &RetryError{StatusCode: 503, Outcome: OutcomeServerError}},
+			expected: OutcomeServerError,
+		},
+		{
+			name:     "Error Takes Precedence Over Response",
+			resp:     &http.Response{StatusCode: http.StatusOK},
+			err:      errors.New("boom"),
+			expected: OutcomeNetworkError,
+		},
+		{
+			name:     "Nothing",
+			expected: OutcomeUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := ClassifyOutcome(tt.resp, tt.err); actual != tt.expected {
+				t.Errorf("Expected outcome %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestOutcomeString(t *testing.T) {
+	if OutcomeServerError.String() != "server_error" {
+		t.Errorf("Expected 'server_error', got '%s'", OutcomeServerError.String())
+	}
+}
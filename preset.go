@@ -0,0 +1,52 @@
+package httpretrier
+
+import (
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Preset is a named bundle of ClientBuilder settings codifying the
+// recommended defaults for an environment.
+// Apply it with ClientBuilder.WithPreset; settings made afterward with
+// individual With... calls take precedence.
+type Preset struct {
+	Name       string
+	MaxRetries int
+	Strategy   Strategy
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	Timeout    time.Duration
+
+	// Logger, when set, receives the retry log messages
+	Logger *slog.Logger
+}
+
+// PresetProduction returns the preset for production traffic: a moderate
+// number of retries with jittered exponential backoff, so that many clients
+// failing at once don't retry in lockstep
+func PresetProduction() Preset {
+	return Preset{
+		Name:       "production",
+		MaxRetries: 3,
+		Strategy:   JitterBackoffStrategy,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   10 * time.Second,
+		Timeout:    10 * time.Second,
+	}
+}
+
+// PresetDevelopment returns the preset for local development: few retries
+// with a short fixed delay, so failures surface quickly, and debug-level
+// logging of every attempt to standard error
+func PresetDevelopment() Preset {
+	return Preset{
+		Name:       "development",
+		MaxRetries: 1,
+		Strategy:   FixedDelayStrategy,
+		BaseDelay:  300 * time.Millisecond,
+		MaxDelay:   300 * time.Millisecond,
+		Timeout:    5 * time.Second,
+		Logger:     slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+}
@@ -0,0 +1,120 @@
+package httpretrier
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientBuilder_WithPreset(t *testing.T) {
+	tests := []struct {
+		name           string
+		preset         Preset
+		expectRetries  int
+		expectStrategy Strategy
+		expectBase     time.Duration
+		expectMax      time.Duration
+		expectTimeout  time.Duration
+		expectLogger   bool
+	}{
+		{
+			name:           "production",
+			preset:         PresetProduction(),
+			expectRetries:  3,
+			expectStrategy: JitterBackoffStrategy,
+			expectBase:     500 * time.Millisecond,
+			expectMax:      10 * time.Second,
+			expectTimeout:  10 * time.Second,
+		},
+		{
+			name:           "development",
+			preset:         PresetDevelopment(),
+			expectRetries:  1,
+			expectStrategy: FixedDelayStrategy,
+			expectBase:     300 * time.Millisecond,
+			expectMax:      300 * time.Millisecond,
+			expectTimeout:  5 * time.Second,
+			expectLogger:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.name, tt.preset.Name)
+
+			httpClient := NewClientBuilder().WithPreset(tt.preset).Build()
+			rt, ok := httpClient.Transport.(*retryTransport)
+			assert.True(t, ok)
+
+			assert.Equal(t, tt.expectTimeout, httpClient.Timeout)
+			assert.Equal(t, tt.expectRetries, rt.MaxRetries)
+			assert.Equal(t, tt.expectStrategy, rt.config.Strategy)
+			assert.Equal(t, tt.expectBase, rt.config.BaseDelay)
+			assert.Equal(t, tt.expectMax, rt.config.MaxDelay)
+			assert.Equal(t, tt.expectLogger, rt.Logger != nil)
+		})
+	}
+}
+
+func TestClientBuilder_WithPresetOverrides(t *testing.T) {
+	httpClient := NewClientBuilder().
+		WithPreset(PresetProduction()).
+		WithMaxRetries(7).
+		WithRetryStrategy(FixedDelayStrategy).
+		WithTimeout(20 * time.Second).
+		Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+
+	// Later calls win over the preset
+	assert.Equal(t, 7, rt.MaxRetries)
+	assert.Equal(t, FixedDelayStrategy, rt.config.Strategy)
+	assert.Equal(t, 20*time.Second, httpClient.Timeout)
+
+	// Settings that weren't overridden keep the preset's values
+	assert.Equal(t, 500*time.Millisecond, rt.config.BaseDelay)
+	assert.Equal(t, 10*time.Second, rt.config.MaxDelay)
+
+	// A preset applied last overrides earlier calls
+	httpClient = NewClientBuilder().WithMaxRetries(7).WithPreset(PresetDevelopment()).Build()
+	rt, _ = httpClient.Transport.(*retryTransport)
+	assert.Equal(t, 1, rt.MaxRetries)
+}
+
+func TestRetryTransport_Logger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("simulated transport error")
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    1,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		Logger:        logger,
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	_, _ = retryRT.RoundTrip(req)
+
+	output := buf.String()
+	if strings.Count(output, "Sending request") != 2 {
+		t.Errorf("Expected a debug message per attempt, got %q", output)
+	}
+	if strings.Count(output, "Attempt failed, retrying") != 1 {
+		t.Errorf("Expected a single retry message, got %q", output)
+	}
+	if !strings.Contains(output, "delay=1ms") {
+		t.Errorf("Expected the retry message to include the delay, got %q", output)
+	}
+}
@@ -0,0 +1,69 @@
+package httpretrier
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket capping the rate of attempts sent through a
+// transport. It holds up to burst tokens, refilled at rps tokens per second,
+// and each attempt takes one.
+type rateLimiter struct {
+	rps   float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a rateLimiter allowing rps attempts per second with
+// bursts of up to burst attempts. The bucket starts full.
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rps:    rps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// wait blocks until a token is available or ctx is done, sleeping on clock.
+// Tokens are handed out in call order: a caller reserves its token right away,
+// letting the bucket go negative, and sleeps until the bucket has refilled.
+func (l *rateLimiter) wait(ctx context.Context, clock Clock) error {
+	delay := l.reserve(clock.Now())
+	if delay <= 0 {
+		return nil
+	}
+
+	if err := clock.Sleep(ctx, delay); err != nil {
+		// Give the token back to the callers still waiting
+		l.mu.Lock()
+		l.tokens = min(l.burst, l.tokens+1)
+		l.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// reserve takes a token and returns how long to wait before using it
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() && now.After(l.last) {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rps)
+	}
+	if now.After(l.last) {
+		l.last = now
+	}
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.rps * float64(time.Second))
+}
@@ -0,0 +1,171 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose Sleep advances the time instantly, recording the durations
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func TestRateLimiter_Burst(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	limiter := newRateLimiter(2, 3)
+
+	// The burst goes through without waiting
+	for range 3 {
+		if err := limiter.wait(context.Background(), clock); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if len(clock.sleeps) != 0 {
+		t.Fatalf("Expected no waiting within the burst, got %v", clock.sleeps)
+	}
+
+	// Then one token every 500ms
+	for range 2 {
+		if err := limiter.wait(context.Background(), clock); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	for i, d := range clock.sleeps {
+		if d != 500*time.Millisecond {
+			t.Errorf("Wait %d: expected 500ms, got %v", i, d)
+		}
+	}
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	limiter := newRateLimiter(10, 2)
+
+	limiter.wait(context.Background(), clock)
+	limiter.wait(context.Background(), clock)
+
+	// Idle time refills the bucket, up to the burst
+	clock.now = clock.now.Add(time.Hour)
+	for range 2 {
+		limiter.wait(context.Background(), clock)
+	}
+	if len(clock.sleeps) != 0 {
+		t.Errorf("Expected a refilled bucket, got waits %v", clock.sleeps)
+	}
+
+	if delay := limiter.reserve(clock.Now()); delay != 100*time.Millisecond {
+		t.Errorf("Expected a 100ms wait once the burst is used, got %v", delay)
+	}
+}
+
+func TestRateLimiter_ContextCanceled(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	limiter := newRateLimiter(1, 1)
+	limiter.wait(context.Background(), clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.wait(ctx, clock); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	// The canceled caller's token was given back
+	if delay := limiter.reserve(clock.Now()); delay != time.Second {
+		t.Errorf("Expected a 1s wait, got %v", delay)
+	}
+}
+
+func TestRetryTransport_RateLimitCountsRetries(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	var attempts int
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       io.NopCloser(strings.NewReader("Unavailable")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    2,
+		RetryStrategy: FixedDelay(0),
+		Clock:         clock,
+		rateLimiter:   newRateLimiter(1, 1),
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	if _, err := retryRT.RoundTrip(req); err == nil {
+		t.Fatal("Expected an error after all retries failed")
+	}
+
+	if attempts != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", attempts)
+	}
+	// The first attempt uses the burst, each retry waits for a new token
+	if len(clock.sleeps) != 2 || clock.sleeps[0] != time.Second || clock.sleeps[1] != time.Second {
+		t.Errorf("Expected two 1s waits for the retries, got %v", clock.sleeps)
+	}
+}
+
+func TestClientBuilder_WithRateLimit(t *testing.T) {
+	builder := NewClientBuilder().WithRateLimit(5, 10)
+	rt, _ := builder.Build().Transport.(*retryTransport)
+	if rt.rateLimiter == nil {
+		t.Fatal("Expected a rate limiter to be installed")
+	}
+	if len(builder.Warnings()) != 0 {
+		t.Errorf("Expected no warnings, got %v", builder.Warnings())
+	}
+
+	rt, _ = NewClientBuilder().Build().Transport.(*retryTransport)
+	if rt.rateLimiter != nil {
+		t.Error("Expected no rate limiter by default")
+	}
+
+	// An invalid rate disables the limit, an invalid burst falls back to 1
+	builder = NewClientBuilder().WithRateLimit(0, 10)
+	rt, _ = builder.Build().Transport.(*retryTransport)
+	if rt.rateLimiter != nil || len(builder.Warnings()) != 1 {
+		t.Errorf("Expected no rate limiter and 1 warning, got %v and %v", rt.rateLimiter, builder.Warnings())
+	}
+
+	builder = NewClientBuilder().WithRateLimit(5, 0)
+	rt, _ = builder.Build().Transport.(*retryTransport)
+	if rt.rateLimiter == nil || rt.rateLimiter.burst != 1 || len(builder.Warnings()) != 1 {
+		t.Errorf("Expected a burst of 1 and 1 warning, got %v and %v", rt.rateLimiter, builder.Warnings())
+	}
+}
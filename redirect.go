@@ -0,0 +1,20 @@
+package httpretrier
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// maxRedirectsPolicy returns a redirect policy following at most maxRedirects
+// redirects. With 0, the redirect response itself is returned
+func maxRedirectsPolicy(maxRedirects int) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if maxRedirects == 0 {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,136 @@
+package httpretrier
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// newRedirectServer returns a server redirecting /n to /n-1 until /0, which answers 200
+func newRedirectServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Path[1:])
+		if n == 0 {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Redirect(w, r, "/"+strconv.Itoa(n-1), http.StatusFound)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientBuilder_WithMaxRedirects(t *testing.T) {
+	server := newRedirectServer(t)
+
+	tests := []struct {
+		name         string
+		maxRedirects int
+		path         string
+		wantStatus   int
+		wantErr      bool
+	}{
+		{name: "Disabled", maxRedirects: 0, path: "/1", wantStatus: http.StatusFound},
+		{name: "Within Limit", maxRedirects: 2, path: "/2", wantStatus: http.StatusOK},
+		{name: "Over Limit", maxRedirects: 2, path: "/3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := NewClientBuilder().WithMaxRedirects(tt.maxRedirects).Build()
+
+			resp, err := httpClient.Get(server.URL + tt.path)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("Expected an error once the redirect limit is exceeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestClientBuilder_WithMaxRedirectsNegative(t *testing.T) {
+	builder := NewClientBuilder().WithMaxRedirects(-1)
+	httpClient := builder.Build()
+
+	if httpClient.CheckRedirect != nil {
+		t.Error("Expected the default redirect policy for a negative limit")
+	}
+	if len(builder.Warnings()) != 1 {
+		t.Errorf("Expected 1 warning, got %v", builder.Warnings())
+	}
+}
+
+func TestClientBuilder_WithCheckRedirect(t *testing.T) {
+	server := newRedirectServer(t)
+
+	var checks int32
+	httpClient := NewClientBuilder().
+		WithMaxRedirects(0). // Replaced by the custom policy
+		WithCheckRedirect(func(req *http.Request, via []*http.Request) error {
+			atomic.AddInt32(&checks, 1)
+			return nil
+		}).
+		Build()
+
+	resp, err := httpClient.Get(server.URL + "/2")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(&checks); got != 2 {
+		t.Errorf("Expected the policy to be checked for 2 redirects, got %d", got)
+	}
+}
+
+func TestClientBuilder_RedirectsAreRetried(t *testing.T) {
+	var targetCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/start" {
+			http.Redirect(w, r, "/target", http.StatusFound)
+			return
+		}
+		// The redirect target fails once before succeeding
+		if atomic.AddInt32(&targetCalls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	httpClient := NewClientBuilder().
+		WithMaxRedirects(1).
+		WithRetryStrategy(FixedDelayStrategy).
+		WithRetryBaseDelay(ValidMinBaseDelay).
+		Build()
+
+	resp, err := httpClient.Get(server.URL + "/start")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(&targetCalls); got != 2 {
+		t.Errorf("Expected the redirected request to be retried, got %d calls", got)
+	}
+}
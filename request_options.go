@@ -0,0 +1,60 @@
+package httpretrier
+
+import "context"
+
+// RequestRetryOption overrides a retry setting of the transport for the
+// requests sent with a context from WithRequestRetryOptions
+type RequestRetryOption func(*requestRetryOptions)
+
+// requestRetryOptions holds the settings overridden for a request
+type requestRetryOptions struct {
+	maxRetries    int
+	maxRetriesSet bool
+	strategy      RetryStrategy
+}
+
+// requestRetryOptionsKey is the context key for a request's requestRetryOptions
+type requestRetryOptionsKey struct{}
+
+// WithRequestRetryOptions returns a copy of ctx carrying opts.
+// Requests sent with the returned context use the overridden settings instead
+// of the transport's, so one client can retry some endpoints more than others.
+// The settings that aren't overridden keep the transport's values. Options
+// already carried by ctx are kept, and opts take precedence over them.
+func WithRequestRetryOptions(ctx context.Context, opts ...RequestRetryOption) context.Context {
+	var options requestRetryOptions
+	if parent := requestRetryOptionsFromContext(ctx); parent != nil {
+		options = *parent
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return context.WithValue(ctx, requestRetryOptionsKey{}, &options)
+}
+
+// RequestMaxRetries overrides the maximum number of retries of a request,
+// 0 meaning it is sent once. Negative values are treated as 0.
+// A request body that can't be replayed still disables retries.
+func RequestMaxRetries(maxRetries int) RequestRetryOption {
+	return func(o *requestRetryOptions) {
+		o.maxRetries = max(maxRetries, 0)
+		o.maxRetriesSet = true
+	}
+}
+
+// RequestRetryStrategy overrides the retry strategy of a request, and with it
+// the delays, e.g. ExponentialBackoff(100*time.Millisecond, 2*time.Second).
+// It takes precedence over the transport's strategy and backoff.
+// A nil strategy keeps the transport's.
+func RequestRetryStrategy(strategy RetryStrategy) RequestRetryOption {
+	return func(o *requestRetryOptions) {
+		o.strategy = strategy
+	}
+}
+
+// requestRetryOptionsFromContext returns the requestRetryOptions carried by ctx, if any
+func requestRetryOptionsFromContext(ctx context.Context) *requestRetryOptions {
+	options, _ := ctx.Value(requestRetryOptionsKey{}).(*requestRetryOptions)
+	return options
+}
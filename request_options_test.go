@@ -0,0 +1,81 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport_RequestRetryOptions(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []RequestRetryOption
+		expectAttempts int32
+	}{
+		{name: "No Options", expectAttempts: 3},
+		{name: "No Retries", opts: []RequestRetryOption{RequestMaxRetries(0)}, expectAttempts: 1},
+		{name: "More Retries", opts: []RequestRetryOption{RequestMaxRetries(5)}, expectAttempts: 6},
+		{name: "Negative Retries", opts: []RequestRetryOption{RequestMaxRetries(-1)}, expectAttempts: 1},
+		{name: "Last Option Wins", opts: []RequestRetryOption{RequestMaxRetries(0), RequestMaxRetries(1)}, expectAttempts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			retryRT := &retryTransport{
+				Transport: &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						atomic.AddInt32(&attempts, 1)
+						return nil, errors.New("simulated transport error")
+					},
+				},
+				MaxRetries:    2,
+				RetryStrategy: FixedDelay(1 * time.Millisecond),
+			}
+
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			if tt.opts != nil {
+				req = req.WithContext(WithRequestRetryOptions(req.Context(), tt.opts...))
+			}
+			_, _ = retryRT.RoundTrip(req)
+
+			if got := atomic.LoadInt32(&attempts); got != tt.expectAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectAttempts, got)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_RequestRetryStrategy(t *testing.T) {
+	var attempts int32
+	retryRT := &retryTransport{
+		Transport: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				atomic.AddInt32(&attempts, 1)
+				return nil, errors.New("simulated transport error")
+			},
+		},
+		MaxRetries:    2,
+		RetryStrategy: FixedDelay(1 * time.Hour), // Would block the test if used
+		Backoff:       StrategyBackoff(FixedDelay(1 * time.Hour)),
+	}
+
+	// Options carried by the parent context are kept
+	ctx := WithRequestRetryOptions(context.Background(), RequestRetryStrategy(FixedDelay(1*time.Millisecond)))
+	ctx = WithRequestRetryOptions(ctx, RequestMaxRetries(1))
+	req := httptest.NewRequest("GET", "http://example.com", nil).WithContext(ctx)
+
+	start := time.Now()
+	_, _ = retryRT.RoundTrip(req)
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the request's strategy to be used, took %v", elapsed)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+}
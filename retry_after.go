@@ -0,0 +1,46 @@
+package httpretrier
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseRetryAfter parses a Retry-After header value, either a number of
+// seconds or an HTTP date, into the time to wait from now. A date in the past
+// means no wait. It reports false if the value is missing or malformed.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		// Saturate rather than overflow on absurdly long waits
+		if seconds > int64(time.Duration(1<<63-1)/time.Second) {
+			return time.Duration(1<<63 - 1), true
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(date.Sub(now), 0), true
+}
+
+// retryAfter returns the wait requested by the Retry-After header of resp,
+// if RespectRetryAfter is set and the header is valid
+func (r *retryTransport) retryAfter(resp *http.Response) (time.Duration, bool) {
+	if !r.RespectRetryAfter || resp == nil {
+		return 0, false
+	}
+
+	return parseRetryAfter(resp.Header.Get("Retry-After"), r.clock().Now())
+}
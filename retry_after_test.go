@@ -0,0 +1,217 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{name: "Seconds", value: "30", expected: 30 * time.Second, ok: true},
+		{name: "Zero Seconds", value: "0", expected: 0, ok: true},
+		{name: "Padded Seconds", value: " 5 ", expected: 5 * time.Second, ok: true},
+		{name: "HTTP Date", value: "Mon, 01 Jan 2024 12:01:30 GMT", expected: 90 * time.Second, ok: true},
+		{name: "Past HTTP Date", value: "Mon, 01 Jan 2024 11:00:00 GMT", expected: 0, ok: true},
+		{name: "Missing", value: "", ok: false},
+		{name: "Negative Seconds", value: "-1", ok: false},
+		{name: "Malformed", value: "soon", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := parseRetryAfter(tt.value, now)
+			if ok != tt.ok || delay != tt.expected {
+				t.Errorf("Expected (%v, %v), got (%v, %v)", tt.expected, tt.ok, delay, ok)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_RetryAfter(t *testing.T) {
+	var attempts int32
+	retryRT := &retryTransport{
+		Transport: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				status := http.StatusServiceUnavailable
+				if atomic.AddInt32(&attempts, 1) == 2 {
+					status = http.StatusOK
+				}
+				return &http.Response{
+					StatusCode: status,
+					Body:       io.NopCloser(strings.NewReader("")),
+					Header:     http.Header{"Retry-After": {"0"}},
+				}, nil
+			},
+		},
+		MaxRetries:        2,
+		RetryStrategy:     FixedDelay(1 * time.Hour), // Would block the test if used
+		RespectRetryAfter: true,
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || atomic.LoadInt32(&attempts) != 2 {
+		t.Errorf("Expected a success on the second attempt, got status %d after %d attempts", resp.StatusCode, atomic.LoadInt32(&attempts))
+	}
+}
+
+func TestRetryTransport_RetryAfterPastDeadline(t *testing.T) {
+	tests := []struct {
+		name           string
+		retryAfter     func() string
+		maxElapsedTime time.Duration
+		ctxTimeout     time.Duration
+	}{
+		{
+			name:       "Seconds Past Context Deadline",
+			retryAfter: func() string { return "30" },
+			ctxTimeout: 5 * time.Second,
+		},
+		{
+			name:           "Seconds Past MaxElapsedTime",
+			retryAfter:     func() string { return "30" },
+			maxElapsedTime: 5 * time.Second,
+		},
+		{
+			name:       "HTTP Date Past Context Deadline",
+			retryAfter: func() string { return time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat) },
+			ctxTimeout: 5 * time.Second,
+		},
+		{
+			name:           "HTTP Date Past MaxElapsedTime",
+			retryAfter:     func() string { return time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat) },
+			maxElapsedTime: 5 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			retryRT := &retryTransport{
+				Transport: &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						atomic.AddInt32(&attempts, 1)
+						return &http.Response{
+							StatusCode: http.StatusTooManyRequests,
+							Body:       io.NopCloser(strings.NewReader("")),
+							Header:     http.Header{"Retry-After": {tt.retryAfter()}},
+						}, nil
+					},
+				},
+				MaxRetries:        3,
+				RetryStrategy:     FixedDelay(1 * time.Millisecond),
+				RetryableStatuses: []StatusRange{{429, 429}},
+				RespectRetryAfter: true,
+				MaxElapsedTime:    tt.maxElapsedTime,
+			}
+
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			if tt.ctxTimeout > 0 {
+				ctx, cancel := context.WithTimeout(req.Context(), tt.ctxTimeout)
+				defer cancel()
+				req = req.WithContext(ctx)
+			}
+
+			start := time.Now()
+			_, err := retryRT.RoundTrip(req)
+
+			// The last result comes back at once instead of after a 30s sleep
+			var retryErr *RetryError
+			if !errors.As(err, &retryErr) || retryErr.StatusCode != http.StatusTooManyRequests {
+				t.Errorf("Expected a RetryError with status 429, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 1*time.Second {
+				t.Errorf("Expected to give up without waiting, took %v", elapsed)
+			}
+			if got := atomic.LoadInt32(&attempts); got != 1 {
+				t.Errorf("Expected 1 attempt, got %d", got)
+			}
+		})
+	}
+}
+
+func TestClientBuilder_WithRespectRetryAfter(t *testing.T) {
+	rt, _ := NewClientBuilder().Build().Transport.(*retryTransport)
+	if rt.RespectRetryAfter {
+		t.Error("Expected Retry-After to be ignored by default")
+	}
+
+	rt, _ = NewClientBuilder().WithRespectRetryAfter(true).Build().Transport.(*retryTransport)
+	if !rt.RespectRetryAfter {
+		t.Error("Expected Retry-After to be honored")
+	}
+}
+
+func TestRetryTransport_RetryAfterPastMaxDelay(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	tests := []struct {
+		name       string
+		retryAfter string
+	}{
+		{name: "Seconds", retryAfter: "60"},
+		{name: "HTTP Date", retryAfter: now.Add(time.Minute).UTC().Format(http.TimeFormat)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			clock := &fakeClock{now: now}
+			retryRT := &retryTransport{
+				Transport: &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						status := http.StatusTooManyRequests
+						if atomic.AddInt32(&attempts, 1) == 2 {
+							status = http.StatusOK
+						}
+						return &http.Response{
+							StatusCode: status,
+							Body:       io.NopCloser(strings.NewReader("")),
+							Header:     http.Header{"Retry-After": {tt.retryAfter}},
+						}, nil
+					},
+				},
+				MaxRetries:        3,
+				RetryStrategy:     FixedDelay(1 * time.Millisecond),
+				RetryableStatuses: []StatusRange{{429, 429}},
+				RespectRetryAfter: true,
+				Clock:             clock,
+				// The max delay bounds the strategy's delays, not the server's
+				config: RetryConfig{MaxDelay: 10 * time.Second},
+			}
+
+			// Without a deadline, the server's wait is honored past the max delay
+			resp, err := retryRT.RoundTrip(httptest.NewRequest("GET", "http://example.com", nil))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			resp.Body.Close()
+
+			if got := atomic.LoadInt32(&attempts); got != 2 {
+				t.Errorf("Expected 2 attempts, got %d", got)
+			}
+			if expected := []time.Duration{time.Minute}; !slices.Equal(clock.sleeps, expected) {
+				t.Errorf("Expected waits %v, got %v", expected, clock.sleeps)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package httpretrier
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// retryBudgetWindow is the time constant of the retry budget's counters:
+// requests and retries older than that weigh less than 37% of recent ones
+const retryBudgetWindow = 10 * time.Second
+
+// retryBudget limits retries to a share of the traffic of a transport, as
+// a token bucket fed by requests. Each request deposits ratio tokens and each
+// retry withdraws one, on top of a reserve of minPerSec retries per second so
+// that low traffic can still retry. Both counts decay exponentially over
+// retryBudgetWindow, so the budget follows the recent traffic.
+type retryBudget struct {
+	ratio     float64
+	minPerSec float64
+
+	mu       sync.Mutex
+	requests float64 // Decayed number of requests
+	retries  float64 // Decayed number of retries
+	last     time.Time
+}
+
+// newRetryBudget creates a retryBudget allowing retries for ratio of the
+// requests, plus minPerSec retries per second
+func newRetryBudget(ratio, minPerSec float64) *retryBudget {
+	return &retryBudget{
+		ratio:     ratio,
+		minPerSec: minPerSec,
+	}
+}
+
+// decayLocked ages the counts to now. b.mu must be held.
+func (b *retryBudget) decayLocked(now time.Time) {
+	if !b.last.IsZero() && now.After(b.last) {
+		factor := math.Exp(-float64(now.Sub(b.last)) / float64(retryBudgetWindow))
+		b.requests *= factor
+		b.retries *= factor
+	}
+	if now.After(b.last) {
+		b.last = now
+	}
+}
+
+// recordRequest counts a request, whatever its number of attempts
+func (b *retryBudget) recordRequest(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.decayLocked(now)
+	b.requests++
+}
+
+// tryRetry withdraws a retry from the budget, reporting false without
+// withdrawing anything if the budget is spent
+func (b *retryBudget) tryRetry(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.decayLocked(now)
+	reserve := b.minPerSec * retryBudgetWindow.Seconds()
+	if b.retries+1 > b.ratio*b.requests+reserve {
+		return false
+	}
+	b.retries++
+
+	return true
+}
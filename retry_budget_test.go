@@ -0,0 +1,131 @@
+package httpretrier
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBudget_Ratio(t *testing.T) {
+	now := time.Now()
+	budget := newRetryBudget(0.1, 0)
+
+	for range 20 {
+		budget.recordRequest(now)
+	}
+
+	// 20 requests at 10% make room for 2 retries
+	for i := range 2 {
+		if !budget.tryRetry(now) {
+			t.Fatalf("Expected retry %d to be allowed", i+1)
+		}
+	}
+	if budget.tryRetry(now) {
+		t.Error("Expected the budget to be spent")
+	}
+
+	// New requests refill it
+	for range 10 {
+		budget.recordRequest(now)
+	}
+	if !budget.tryRetry(now) {
+		t.Error("Expected a retry to be allowed after more requests")
+	}
+}
+
+func TestRetryBudget_MinRetriesPerSec(t *testing.T) {
+	now := time.Now()
+	budget := newRetryBudget(0, 0.2) // 2 retries over the 10s window
+
+	for i := range 2 {
+		if !budget.tryRetry(now) {
+			t.Fatalf("Expected reserve retry %d to be allowed without traffic", i+1)
+		}
+	}
+	if budget.tryRetry(now) {
+		t.Error("Expected the reserve to be spent")
+	}
+
+	// The spent retries fade away over the window
+	if !budget.tryRetry(now.Add(2 * retryBudgetWindow)) {
+		t.Error("Expected the reserve to recover over time")
+	}
+}
+
+func TestRetryBudget_Decay(t *testing.T) {
+	now := time.Now()
+	budget := newRetryBudget(0.5, 0)
+
+	for range 4 {
+		budget.recordRequest(now)
+	}
+
+	// Old traffic no longer pays for retries
+	later := now.Add(10 * retryBudgetWindow)
+	if budget.tryRetry(later) {
+		t.Error("Expected old requests to have decayed")
+	}
+
+	// A clock going backwards doesn't inflate the counts
+	budget.recordRequest(now)
+	if math.Abs(budget.requests-1) > 0.01 {
+		t.Errorf("Expected about 1 request, got %v", budget.requests)
+	}
+}
+
+func TestRetryTransport_RetryBudget(t *testing.T) {
+	var attempts int32
+	retryRT := &retryTransport{
+		Transport: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				atomic.AddInt32(&attempts, 1)
+				return nil, errors.New("simulated transport error")
+			},
+		},
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		retryBudget:   newRetryBudget(0.6, 0),
+	}
+
+	// The first request alone doesn't earn a whole retry, the second one does
+	for range 2 {
+		req := httptest.NewRequest("GET", "http://example.com", nil)
+		_, err := retryRT.RoundTrip(req)
+
+		var retryErr *RetryError
+		if !errors.As(err, &retryErr) {
+			t.Errorf("Expected a RetryError, got %v", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("Expected 3 attempts for 2 requests, got %d", got)
+	}
+}
+
+func TestClientBuilder_WithRetryBudget(t *testing.T) {
+	rt, _ := NewClientBuilder().WithRetryBudget(0.1, 1).Build().Transport.(*retryTransport)
+	if rt.retryBudget == nil || rt.retryBudget.ratio != 0.1 || rt.retryBudget.minPerSec != 1 {
+		t.Errorf("Expected a retry budget of 0.1 and 1/s, got %+v", rt.retryBudget)
+	}
+
+	rt, _ = NewClientBuilder().Build().Transport.(*retryTransport)
+	if rt.retryBudget != nil {
+		t.Error("Expected no retry budget by default")
+	}
+
+	for _, tt := range []struct{ ratio, minPerSec float64 }{{-0.1, 1}, {1.5, 1}, {math.NaN(), 1}, {0.1, -1}, {0.1, math.Inf(1)}} {
+		builder := NewClientBuilder().WithRetryBudget(tt.ratio, tt.minPerSec)
+		rt, _ = builder.Build().Transport.(*retryTransport)
+		if rt.retryBudget != nil {
+			t.Errorf("Expected no retry budget for ratio %v and %v/s", tt.ratio, tt.minPerSec)
+		}
+		if len(builder.Warnings()) != 1 {
+			t.Errorf("Expected a warning for ratio %v and %v/s, got %v", tt.ratio, tt.minPerSec, builder.Warnings())
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package httpretrier
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+// RetryCondition decides whether the result of an attempt warrants a retry.
+// It receives either the response or the transport error of the attempt.
+type RetryCondition func(resp *http.Response, err error) bool
+
+// attemptTimeoutError reports that an attempt exceeded the per-attempt
+// timeout while the request itself was still live
+type attemptTimeoutError struct {
+	err error
+}
+
+func (e *attemptTimeoutError) Error() string {
+	return "attempt timed out: " + e.err.Error()
+}
+
+func (e *attemptTimeoutError) Unwrap() error {
+	return e.err
+}
+
+// Timeout reports that the error is a timeout, making it a net.Error
+func (e *attemptTimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary reports that the error is transient, making it a net.Error
+func (e *attemptTimeoutError) Temporary() bool {
+	return true
+}
+
+// permanentRequestErrors are messages of transport errors caused by the
+// request itself, which no retry can fix
+var permanentRequestErrors = []string{
+	"unsupported protocol scheme",
+	"no Host in request URL",
+	"invalid URL",
+	"nil Request.URL",
+}
+
+// isRetryable reports whether a transport error is worth retrying.
+// Timeouts, refused or reset connections and truncated responses are
+// transient. Cancellation, malformed requests, TLS certificate verification
+// failures, oversized response headers and failed credential refreshes are
+// permanent. Other errors are retried.
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// A per-attempt timeout is expected to be retried, unlike the request's
+	// own deadline
+	var attemptTimeout *attemptTimeoutError
+	if errors.As(err, &attemptTimeout) {
+		return true
+	}
+
+	if isContextError(err) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	if isCertificateError(err) {
+		return false
+	}
+
+	// The server sent more headers than allowed, it will do it again
+	if isResponseHeaderTooLarge(err) {
+		return false
+	}
+
+	if errors.Is(err, ErrAuthRefresh) {
+		return false
+	}
+
+	for _, msg := range permanentRequestErrors {
+		if strings.Contains(err.Error(), msg) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isContextError reports whether err comes from the request's context being
+// canceled or past its deadline. Per-attempt and response header timeouts
+// don't count, though the latter matches context.DeadlineExceeded too.
+func isContextError(err error) bool {
+	var attemptTimeout *attemptTimeoutError
+	if errors.As(err, &attemptTimeout) || isResponseHeaderTimeout(err) {
+		return false
+	}
+
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// isCertificateError reports whether err is a TLS certificate verification failure
+func isCertificateError(err error) bool {
+	var verificationErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	return errors.As(err, &verificationErr) ||
+		errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr)
+}
+
+// isConnectionError reports whether err means the attempt's connection broke,
+// e.g. reset by the peer or closed by the server while idle in the pool.
+// Timeouts and failures to connect don't count.
+func isConnectionError(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed)
+}
+
+// isConnectTimeout reports whether err is a timeout while establishing a
+// connection, e.g. a TCP SYN that was never answered. The host may be down
+// or unreachable, which usually warrants backing off harder.
+func isConnectTimeout(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout()
+}
+
+// responseHeaderTimeoutMessage is the message of the error net/http returns when
+// the transport's ResponseHeaderTimeout runs out
+const responseHeaderTimeoutMessage = "timeout awaiting response headers"
+
+// isResponseHeaderTimeout reports whether err is the transport giving up
+// waiting for the response headers, see WithResponseHeaderTimeout
+func isResponseHeaderTimeout(err error) bool {
+	return err != nil && strings.Contains(err.Error(), responseHeaderTimeoutMessage)
+}
+
+// responseHeaderTooLargeMessage is part of the message of the error net/http
+// returns when the response headers exceed the transport's MaxResponseHeaderBytes
+const responseHeaderTooLargeMessage = "server response headers exceeded"
+
+// isResponseHeaderTooLarge reports whether err is the transport rejecting a
+// response whose headers are too large, see WithMaxResponseHeaderBytes
+func isResponseHeaderTooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), responseHeaderTooLargeMessage)
+}
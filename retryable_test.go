@@ -0,0 +1,255 @@
+package httpretrier
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsConnectTimeout(t *testing.T) {
+	connectTimeout := &url.Error{Op: "Get", URL: "http://example.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}}
+	readTimeout := &url.Error{Op: "Get", URL: "http://example.com", Err: &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}}
+	connectRefused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name           string
+		err            error
+		connectTimeout bool
+	}{
+		{name: "Connect Timeout", err: connectTimeout, connectTimeout: true},
+		{name: "Wrapped Connect Timeout", err: fmt.Errorf("attempt failed: %w", connectTimeout), connectTimeout: true},
+		// Read timeouts fall back to the general strategy
+		{name: "Read Timeout", err: readTimeout},
+		{name: "Connect Refused", err: connectRefused},
+		{name: "Generic Error", err: errors.New("boom")},
+		{name: "Nil", err: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := isConnectTimeout(tt.err); actual != tt.connectTimeout {
+				t.Errorf("Expected %v, got %v", tt.connectTimeout, actual)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "Nil", err: nil, expected: false},
+		{name: "Timeout", err: &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, expected: true},
+		{name: "Connection Refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, expected: true},
+		{name: "Connection Reset", err: &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, expected: true},
+		{name: "Unexpected EOF", err: fmt.Errorf("reading response: %w", io.ErrUnexpectedEOF), expected: true},
+		{name: "Attempt Timeout", err: &attemptTimeoutError{err: context.DeadlineExceeded}, expected: true},
+		{name: "Response Header Timeout", err: &url.Error{Op: "Get", URL: "http://example.com", Err: errors.New("net/http: timeout awaiting response headers")}, expected: true},
+		{name: "Generic Error", err: errors.New("boom"), expected: true},
+		{name: "Context Canceled", err: context.Canceled, expected: false},
+		{name: "Context Deadline", err: &url.Error{Op: "Get", URL: "http://example.com", Err: context.DeadlineExceeded}, expected: false},
+		{name: "Unsupported Scheme", err: &url.Error{Op: "Get", URL: "ftp://example.com", Err: errors.New(`unsupported protocol scheme "ftp"`)}, expected: false},
+		{name: "No Host", err: errors.New("http: no Host in request URL"), expected: false},
+		{name: "Response Headers Too Large", err: errors.New("net/http: server response headers exceeded 1024 bytes; aborted"), expected: false},
+		{name: "Unknown Authority", err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}, expected: false},
+		{name: "Hostname Mismatch", err: x509.HostnameError{Host: "example.com", Certificate: &x509.Certificate{}}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := isRetryable(tt.err); actual != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "Nil", err: nil, expected: false},
+		{name: "Connection Reset", err: &url.Error{Op: "Get", URL: "http://example.com", Err: &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}, expected: true},
+		{name: "Broken Pipe", err: &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}, expected: true},
+		{name: "Server Closed Idle Connection", err: &url.Error{Op: "Get", URL: "http://example.com", Err: io.EOF}, expected: true},
+		{name: "Unexpected EOF", err: fmt.Errorf("reading response: %w", io.ErrUnexpectedEOF), expected: true},
+		{name: "Connection Refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, expected: false},
+		{name: "Timeout", err: &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, expected: false},
+		{name: "Generic Error", err: errors.New("boom"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := isConnectionError(tt.err); actual != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+// idleConnsRoundTripper is a mockRoundTripper counting the calls to CloseIdleConnections
+type idleConnsRoundTripper struct {
+	mockRoundTripper
+	closed int
+}
+
+func (rt *idleConnsRoundTripper) CloseIdleConnections() {
+	rt.closed++
+}
+
+func TestRetryTransport_FreshConnOnError(t *testing.T) {
+	connReset := &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+
+	tests := []struct {
+		name             string
+		freshConnOnError bool
+		resp             *http.Response
+		err              error
+		expectedClosed   int
+	}{
+		{name: "Connection Error", freshConnOnError: true, err: connReset, expectedClosed: 2},
+		{name: "Disabled", freshConnOnError: false, err: connReset, expectedClosed: 0},
+		{name: "Status Code", freshConnOnError: true, resp: &http.Response{StatusCode: http.StatusServiceUnavailable}, expectedClosed: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRT := &idleConnsRoundTripper{}
+			mockRT.roundTripFunc = func(req *http.Request) (*http.Response, error) {
+				if tt.resp != nil {
+					return &http.Response{
+						StatusCode: tt.resp.StatusCode,
+						Body:       io.NopCloser(strings.NewReader("body")),
+						Header:     make(http.Header),
+					}, nil
+				}
+				return nil, tt.err
+			}
+
+			retryRT := &retryTransport{
+				Transport:        mockRT,
+				MaxRetries:       2,
+				RetryStrategy:    FixedDelay(1 * time.Millisecond),
+				FreshConnOnError: tt.freshConnOnError,
+			}
+
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			if _, err := retryRT.RoundTrip(req); !errors.Is(err, ErrAllRetriesFailed) {
+				t.Fatalf("Expected ErrAllRetriesFailed, got %v", err)
+			}
+
+			// The last failure is not followed by a retry, so it keeps the pool
+			if mockRT.closed != tt.expectedClosed {
+				t.Errorf("Expected idle connections closed %d times, got %d", tt.expectedClosed, mockRT.closed)
+			}
+		})
+	}
+}
+
+func TestClientBuilder_WithFreshConnOnError(t *testing.T) {
+	rt := NewClientBuilder().Build().Transport.(*retryTransport)
+	if !rt.FreshConnOnError || rt.idleConns == nil {
+		t.Errorf("Expected fresh connections on error by default, with the builder's transport, got %v and %v", rt.FreshConnOnError, rt.idleConns)
+	}
+
+	// The underlying transport is found through attempt middlewares
+	base := &idleConnsRoundTripper{}
+	rt = NewClientBuilder().
+		WithBaseTransport(base).
+		WithAttemptMiddleware(func(next http.RoundTripper) http.RoundTripper { return roundTripperFunc(next.RoundTrip) }).
+		Build().Transport.(*retryTransport)
+	rt.closeIdleConnections(rt.Transport)
+	if base.closed != 1 {
+		t.Errorf("Expected the base transport's idle connections to be closed, got %d calls", base.closed)
+	}
+
+	rt = NewClientBuilder().WithFreshConnOnError(false).Build().Transport.(*retryTransport)
+	if rt.FreshConnOnError {
+		t.Error("Expected fresh connections on error to be disabled")
+	}
+}
+
+func TestRetryTransport_PermanentErrorNotRetried(t *testing.T) {
+	var attempts int
+	permanentErr := errors.New(`unsupported protocol scheme "ftp"`)
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			return nil, permanentErr
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+	}
+
+	req := httptest.NewRequest("GET", "ftp://example.com", nil)
+	_, err := retryRT.RoundTrip(req)
+
+	if !errors.Is(err, permanentErr) {
+		t.Errorf("Expected the permanent error to be returned, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("Expected a single attempt, got %d", attempts)
+	}
+}
+
+func TestRetryTransport_RetryCondition(t *testing.T) {
+	var attempts int
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			status := http.StatusTooManyRequests
+			if attempts == 3 {
+				status = http.StatusOK
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	// Retry 429s, which the default decision doesn't
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		RetryCondition: func(resp *http.Response, err error) bool {
+			return err != nil || resp.StatusCode == http.StatusTooManyRequests
+		},
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+}
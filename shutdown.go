@@ -0,0 +1,122 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// ErrStopped is returned when retries are abandoned because the process
+// received a shutdown signal
+var ErrStopped = errors.New("retries stopped due to shutdown")
+
+// signalStop tracks whether the retry transport has been asked to stop
+// retrying, either by an OS signal or programmatically
+type signalStop struct {
+	// ctx is canceled once retries are stopped
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	signals     chan os.Signal
+	quit        chan struct{}
+	releaseOnce sync.Once
+}
+
+// newSignalStop creates a signalStop and, when signals are given, registers
+// a handler that stops retries as soon as one of them is received.
+// The handler is registered exactly once and removed by release.
+func newSignalStop(signals ...os.Signal) *signalStop {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &signalStop{
+		ctx:    ctx,
+		cancel: cancel,
+		quit:   make(chan struct{}),
+	}
+
+	if len(signals) > 0 {
+		s.signals = make(chan os.Signal, 1)
+		signal.Notify(s.signals, signals...)
+		go s.watch()
+	}
+
+	return s
+}
+
+// watch waits for a registered signal and stops retries when it arrives
+func (s *signalStop) watch() {
+	select {
+	case <-s.signals:
+		s.stop()
+	case <-s.quit:
+	}
+}
+
+// stop sets the stop flag, waking up any retry waiting on a backoff delay
+func (s *signalStop) stop() {
+	s.cancel()
+}
+
+// isStopped reports whether retries have been stopped
+func (s *signalStop) isStopped() bool {
+	return s.ctx.Err() != nil
+}
+
+// release unregisters the signal handler. It is safe to call more than once.
+func (s *signalStop) release() {
+	s.releaseOnce.Do(func() {
+		if s.signals != nil {
+			signal.Stop(s.signals)
+		}
+		close(s.quit)
+	})
+}
+
+// StopSignalHandling removes the signal handler installed by
+// WithSignalAwareShutdown from a client built by ClientBuilder, or whose
+// transport was built by BuildTransport. The transport may be wrapped by
+// RoundTrippers having an Unwrap() http.RoundTripper method returning the
+// transport they wrap.
+// It returns false if the client has no signal handler installed.
+// Calling it more than once is safe.
+func StopSignalHandling(client *http.Client) bool {
+	if client == nil {
+		return false
+	}
+
+	rt, ok := retryTransportOf(client.Transport)
+	if !ok || rt.stop == nil {
+		return false
+	}
+
+	rt.stop.release()
+
+	return true
+}
+
+// Shutdown releases the resources held by a client: it removes the signal
+// handler installed by WithSignalAwareShutdown, if any, and closes the idle
+// connections of the client's transport, which http.Client.CloseIdleConnections
+// does too for clients built by this package. Like StopSignalHandling, it
+// finds the retry transport built by BuildTransport through the RoundTrippers
+// wrapping it that have an Unwrap() http.RoundTripper method, closing its idle
+// connections even if they don't have a CloseIdleConnections method.
+//
+// Call it once the client is no longer used, typically last in a graceful
+// shutdown sequence, after http.Server.Shutdown has returned and the
+// background workers using the client have stopped. Requests still in flight
+// are not interrupted, and the client keeps working afterward, opening new
+// connections as needed. Calling it more than once is safe.
+func Shutdown(client *http.Client) {
+	if client == nil {
+		return
+	}
+
+	StopSignalHandling(client)
+	client.CloseIdleConnections()
+	if rt, ok := retryTransportOf(client.Transport); ok && rt != client.Transport {
+		rt.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,179 @@
+package httpretrier
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport_StopMidRetry(t *testing.T) {
+	var attempts int32
+	stop := newSignalStop()
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			// Simulate the shutdown signal arriving during the second attempt
+			if atomic.AddInt32(&attempts, 1) == 2 {
+				stop.stop()
+			}
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       io.NopCloser(strings.NewReader("Unavailable")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    5,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		stop:          stop,
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+
+	if !errors.Is(err, ErrStopped) {
+		t.Fatalf("Expected ErrStopped, got %v", err)
+	}
+	if resp != nil {
+		t.Errorf("Expected nil response when stopped, got %v", resp)
+	}
+	if atomic.LoadInt32(&attempts) != 2 {
+		t.Errorf("Expected 2 attempts before stopping, got %d", atomic.LoadInt32(&attempts))
+	}
+}
+
+func TestRetryTransport_StopInterruptsBackoff(t *testing.T) {
+	var attempts int32
+	stop := newSignalStop()
+
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&attempts, 1)
+			return nil, errors.New("simulated transport error")
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Hour), // Would block the test if not interrupted
+		stop:          stop,
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		stop.stop()
+	}()
+
+	start := time.Now()
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	_, err := retryRT.RoundTrip(req)
+
+	if !errors.Is(err, ErrStopped) {
+		t.Fatalf("Expected ErrStopped, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected stop to interrupt the backoff delay, took %v", elapsed)
+	}
+	if atomic.LoadInt32(&attempts) != 1 {
+		t.Errorf("Expected 1 attempt, got %d", atomic.LoadInt32(&attempts))
+	}
+}
+
+func TestClientBuilder_WithSignalAwareShutdown(t *testing.T) {
+	httpClient := NewClientBuilder().WithSignalAwareShutdown(os.Interrupt).Build()
+
+	rt, ok := httpClient.Transport.(*retryTransport)
+	if !ok {
+		t.Fatalf("Client transport is not of type *retryTransport, got %T", httpClient.Transport)
+	}
+	if rt.stop == nil {
+		t.Fatal("Expected signal handler to be installed")
+	}
+	if rt.stop.isStopped() {
+		t.Error("Expected retries not to be stopped before a signal is received")
+	}
+
+	// Removing the handler is idempotent
+	if !StopSignalHandling(httpClient) {
+		t.Error("Expected StopSignalHandling to report an installed handler")
+	}
+	if !StopSignalHandling(httpClient) {
+		t.Error("Expected second StopSignalHandling call to be a no-op")
+	}
+
+	// Clients without the option have nothing to remove
+	if StopSignalHandling(NewClientBuilder().Build()) {
+		t.Error("Expected StopSignalHandling to return false without a handler")
+	}
+	if StopSignalHandling(http.DefaultClient) {
+		t.Error("Expected StopSignalHandling to return false for a non-retry client")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	base := &idleConnsRoundTripper{}
+	httpClient := NewClientBuilder().
+		WithBaseTransport(base).
+		WithAttemptMiddleware(func(next http.RoundTripper) http.RoundTripper { return next }).
+		WithSignalAwareShutdown(os.Interrupt).
+		Build()
+
+	// The standard method reaches the wrapped transport
+	httpClient.CloseIdleConnections()
+	if base.closed != 1 {
+		t.Errorf("Expected CloseIdleConnections to reach the base transport, got %d calls", base.closed)
+	}
+
+	Shutdown(httpClient)
+	if base.closed != 2 {
+		t.Errorf("Expected Shutdown to close the idle connections, got %d calls", base.closed)
+	}
+	select {
+	case <-httpClient.Transport.(*retryTransport).stop.quit:
+	default:
+		t.Error("Expected Shutdown to remove the signal handler")
+	}
+
+	// Shutting down again, or a client of another package, is safe
+	Shutdown(httpClient)
+	Shutdown(http.DefaultClient)
+	Shutdown(nil)
+}
+
+func TestNewClientBuilderFrom_KeepsSignalHandler(t *testing.T) {
+	oldClient := NewClientBuilder().WithSignalAwareShutdown(os.Interrupt).Build()
+	oldStop := oldClient.Transport.(*retryTransport).stop
+
+	// Rewrapping the client leaves the handler of the old one alone
+	newClient := NewClientBuilderFrom(oldClient).WithSignalAwareShutdown(os.Interrupt).Build()
+	select {
+	case <-oldStop.quit:
+		t.Fatal("Expected the old client's signal handler to be kept")
+	default:
+	}
+
+	// Each client owns its own handler, removed by its own Shutdown
+	newStop := newClient.Transport.(*retryTransport).stop
+	Shutdown(oldClient)
+	select {
+	case <-oldStop.quit:
+	default:
+		t.Error("Expected Shutdown to remove the old client's signal handler")
+	}
+	select {
+	case <-newStop.quit:
+		t.Error("Expected the new client's signal handler to be kept")
+	default:
+	}
+	Shutdown(newClient)
+}
@@ -0,0 +1,87 @@
+package httpretrier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrNotRetryClient is returned by DoWithStats when the client doesn't use
+// the retry transport of this package
+var ErrNotRetryClient = errors.New("client transport is not a retry transport")
+
+// Stats describes the attempts made by the retry transport for a request
+type Stats struct {
+	Attempts       int           // Number of attempts, including the first one
+	TotalDelay     time.Duration // Sum of the backoff delays waited between attempts
+	LastStatusCode int           // Status code of the last attempt, 0 if it got no response
+}
+
+// statsKey is the context key for the Stats collected for a request
+type statsKey struct{}
+
+// statsFromContext returns the Stats to update for the request, or nil if
+// nobody asked for them
+func statsFromContext(ctx context.Context) *Stats {
+	stats, _ := ctx.Value(statsKey{}).(*Stats)
+	return stats
+}
+
+// recordAttempt counts an attempt and its outcome. It is a no-op on nil Stats
+func (s *Stats) recordAttempt(resp *http.Response) {
+	if s == nil {
+		return
+	}
+
+	s.Attempts++
+	s.LastStatusCode = 0
+	if resp != nil {
+		s.LastStatusCode = resp.StatusCode
+	}
+}
+
+// recordDelay adds a backoff delay. It is a no-op on nil Stats
+func (s *Stats) recordDelay(delay time.Duration) {
+	if s == nil {
+		return
+	}
+
+	s.TotalDelay += delay
+}
+
+// since returns the stats collected since before was taken
+func (s *Stats) since(before Stats) Stats {
+	delta := Stats{
+		Attempts:   s.Attempts - before.Attempts,
+		TotalDelay: s.TotalDelay - before.TotalDelay,
+	}
+	if delta.Attempts > 0 {
+		delta.LastStatusCode = s.LastStatusCode
+	}
+
+	return delta
+}
+
+// DoWithStats sends req with client, like client.Do, and also returns how
+// many attempts were made, the backoff delay waited between them and the
+// status code of the last attempt.
+// The stats are returned whether the request succeeded or not. When the client
+// follows redirects, they add up the attempts of every request in the chain.
+// It returns ErrNotRetryClient if the client doesn't use the retry transport,
+// possibly wrapped, see StopSignalHandling.
+func DoWithStats(client *http.Client, req *http.Request) (*http.Response, Stats, error) {
+	if client == nil {
+		return nil, Stats{}, ErrNotRetryClient
+	}
+	if _, ok := retryTransportOf(client.Transport); !ok {
+		return nil, Stats{}, ErrNotRetryClient
+	}
+
+	stats := &Stats{}
+	req = req.WithContext(context.WithValue(req.Context(), statsKey{}, stats))
+
+	resp, err := client.Do(req)
+
+	return resp, *stats, err
+}
@@ -0,0 +1,162 @@
+package httpretrier
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDoWithStats(t *testing.T) {
+	var attempts int
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			switch attempts {
+			case 1:
+				return nil, errors.New("connection reset")
+			case 2:
+				return &http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Body:       io.NopCloser(strings.NewReader("Unavailable")),
+					Header:     make(http.Header),
+				}, nil
+			default:
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("OK")),
+					Header:     make(http.Header),
+				}, nil
+			}
+		},
+	}
+
+	client := &http.Client{
+		Transport: &retryTransport{
+			Transport:     mockRT,
+			MaxRetries:    3,
+			RetryStrategy: FixedDelay(2 * time.Millisecond),
+		},
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.RequestURI = ""
+	resp, stats, err := DoWithStats(client, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if stats.Attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", stats.Attempts)
+	}
+	if stats.TotalDelay != 4*time.Millisecond {
+		t.Errorf("Expected a total delay of 4ms, got %v", stats.TotalDelay)
+	}
+	if stats.LastStatusCode != http.StatusOK {
+		t.Errorf("Expected last status code 200, got %d", stats.LastStatusCode)
+	}
+}
+
+func TestDoWithStats_AllRetriesFailed(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusBadGateway,
+				Body:       io.NopCloser(strings.NewReader("Bad Gateway")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	client := &http.Client{
+		Transport: &retryTransport{
+			Transport:     mockRT,
+			MaxRetries:    2,
+			RetryStrategy: FixedDelay(1 * time.Millisecond),
+		},
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.RequestURI = ""
+	_, stats, err := DoWithStats(client, req)
+	if err == nil {
+		t.Fatal("Expected an error after all retries failed")
+	}
+
+	if stats.Attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", stats.Attempts)
+	}
+	if stats.TotalDelay != 2*time.Millisecond {
+		t.Errorf("Expected a total delay of 2ms, got %v", stats.TotalDelay)
+	}
+	if stats.LastStatusCode != http.StatusBadGateway {
+		t.Errorf("Expected last status code 502, got %d", stats.LastStatusCode)
+	}
+}
+
+func TestDoWithStats_NotRetryClient(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+
+	for _, client := range []*http.Client{nil, http.DefaultClient} {
+		_, _, err := DoWithStats(client, req)
+		if !errors.Is(err, ErrNotRetryClient) {
+			t.Errorf("Expected ErrNotRetryClient, got %v", err)
+		}
+	}
+}
+
+func TestRetryTransport_OnRequestDone(t *testing.T) {
+	var attempts int
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			status := http.StatusOK
+			if attempts == 1 {
+				status = http.StatusBadGateway
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader("body")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	var done []Stats
+	client := &http.Client{
+		Transport: &retryTransport{
+			Transport:     mockRT,
+			MaxRetries:    3,
+			RetryStrategy: FixedDelay(2 * time.Millisecond),
+			OnRequestDone: func(stats Stats) { done = append(done, stats) },
+		},
+	}
+
+	// The callback reports each request on its own, even under DoWithStats
+	for range 2 {
+		req := httptest.NewRequest("GET", "http://example.com", nil)
+		req.RequestURI = ""
+		resp, _, err := DoWithStats(client, req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	want := []Stats{
+		{Attempts: 2, TotalDelay: 2 * time.Millisecond, LastStatusCode: http.StatusOK},
+		{Attempts: 1, LastStatusCode: http.StatusOK},
+	}
+	if len(done) != len(want) {
+		t.Fatalf("Expected %d calls, got %d", len(want), len(done))
+	}
+	for i := range want {
+		if done[i] != want[i] {
+			t.Errorf("Expected stats %+v for request %d, got %+v", want[i], i, done[i])
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package httpretrier
+
+import (
+	"net/http"
+	"slices"
+	"time"
+)
+
+// StatusRange is an inclusive range of HTTP status codes, such as [400, 599]
+type StatusRange struct {
+	Min int `json:"min" yaml:"min"`
+	Max int `json:"max" yaml:"max"`
+}
+
+// contains reports whether status is within the range
+func (s StatusRange) contains(status int) bool {
+	return s.Min <= status && status <= s.Max
+}
+
+// valid reports whether the range is non-empty and made of HTTP status codes
+func (s StatusRange) valid() bool {
+	return s.Min >= 100 && s.Max <= 599 && s.Min <= s.Max
+}
+
+// retryableStatus reports whether a response status warrants a retry. When
+// ranges are set, only the statuses within one of them do; otherwise 5xx do.
+func retryableStatus(ranges []StatusRange, status int) bool {
+	if len(ranges) == 0 {
+		return status >= http.StatusInternalServerError
+	}
+
+	for _, r := range ranges {
+		if r.contains(status) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// noRetryStatus reports whether resp has one of the statuses never retried,
+// whatever the retry rule says
+func noRetryStatus(statuses []int, resp *http.Response) bool {
+	return resp != nil && slices.Contains(statuses, resp.StatusCode)
+}
+
+// statusDelay returns the delay to wait after resp set for its status, if any
+func statusDelay(delays map[int]time.Duration, resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	delay, ok := delays[resp.StatusCode]
+	return delay, ok
+}
@@ -0,0 +1,287 @@
+package httpretrier
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryableStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		ranges    []StatusRange
+		status    int
+		retryable bool
+	}{
+		{name: "Default 5xx", status: http.StatusBadGateway, retryable: true},
+		{name: "Default 4xx", status: http.StatusNotFound, retryable: false},
+		{name: "Default 2xx", status: http.StatusOK, retryable: false},
+		{name: "In Range", ranges: []StatusRange{{400, 599}}, status: http.StatusNotFound, retryable: true},
+		{name: "Range Bounds", ranges: []StatusRange{{400, 599}}, status: 599, retryable: true},
+		{name: "Below Range", ranges: []StatusRange{{400, 599}}, status: http.StatusOK, retryable: false},
+		{name: "Range Replaces 5xx", ranges: []StatusRange{{429, 429}}, status: http.StatusInternalServerError, retryable: false},
+		{name: "Second Range", ranges: []StatusRange{{429, 429}, {502, 504}}, status: http.StatusServiceUnavailable, retryable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := retryableStatus(tt.ranges, tt.status); actual != tt.retryable {
+				t.Errorf("Expected %v, got %v", tt.retryable, actual)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_RetryableStatuses(t *testing.T) {
+	var attempts int
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			status := http.StatusNotFound
+			if attempts == 3 {
+				status = http.StatusOK
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:         mockRT,
+		MaxRetries:        3,
+		RetryStrategy:     FixedDelay(1 * time.Millisecond),
+		RetryableStatuses: []StatusRange{{400, 599}},
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected the 404 responses to be retried, got %d attempts", attempts)
+	}
+}
+
+func TestClientBuilder_WithRetryableStatusRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  *ClientBuilder
+		expected []StatusRange
+	}{
+		{
+			name:    "Not Set",
+			builder: NewClientBuilder(),
+		},
+		{
+			name:     "Ranges Add Up",
+			builder:  NewClientBuilder().WithRetryableStatusRange(400, 499).WithRetryableStatusRange(500, 599),
+			expected: []StatusRange{{400, 499}, {500, 599}},
+		},
+		{
+			name:     "Invalid Ranges Are Ignored",
+			builder:  NewClientBuilder().WithRetryableStatusRange(500, 400).WithRetryableStatusRange(0, 1000).WithRetryableStatusRange(429, 429),
+			expected: []StatusRange{{429, 429}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt, _ := tt.builder.Build().Transport.(*retryTransport)
+			if len(rt.RetryableStatuses) != len(tt.expected) {
+				t.Fatalf("Expected ranges %v, got %v", tt.expected, rt.RetryableStatuses)
+			}
+			for i, s := range tt.expected {
+				if rt.RetryableStatuses[i] != s {
+					t.Errorf("Expected ranges %v, got %v", tt.expected, rt.RetryableStatuses)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransport_NoRetryStatuses(t *testing.T) {
+	tests := []struct {
+		name              string
+		retryableStatuses []StatusRange
+		retryCondition    RetryCondition
+		status            int
+		expectedAttempts  int
+	}{
+		{name: "Excluded 5xx", status: http.StatusNotImplemented, expectedAttempts: 1},
+		{name: "Other 5xx", status: http.StatusServiceUnavailable, expectedAttempts: 4},
+		{name: "Excluded Within Range", retryableStatuses: []StatusRange{{400, 599}}, status: http.StatusNotImplemented, expectedAttempts: 1},
+		{
+			name:             "Excluded Despite Retry Condition",
+			retryCondition:   func(resp *http.Response, err error) bool { return true },
+			status:           http.StatusNotImplemented,
+			expectedAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			mockRT := &mockRoundTripper{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					attempts++
+					return &http.Response{
+						StatusCode: tt.status,
+						Body:       io.NopCloser(strings.NewReader("")),
+						Header:     make(http.Header),
+					}, nil
+				},
+			}
+
+			retryRT := &retryTransport{
+				Transport:         mockRT,
+				MaxRetries:        3,
+				RetryStrategy:     FixedDelay(1 * time.Millisecond),
+				RetryableStatuses: tt.retryableStatuses,
+				RetryCondition:    tt.retryCondition,
+				NoRetryStatuses:   []int{http.StatusNotImplemented, http.StatusHTTPVersionNotSupported},
+			}
+
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			resp, err := retryRT.RoundTrip(req)
+			if attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, attempts)
+			}
+			if tt.expectedAttempts > 1 {
+				return
+			}
+
+			// An excluded status is returned as is
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestClientBuilder_WithNoRetryOnStatus(t *testing.T) {
+	builder := NewClientBuilder().
+		WithNoRetryOnStatus(http.StatusNotImplemented).
+		WithNoRetryOnStatus(0, http.StatusHTTPVersionNotSupported, 600)
+	rt, _ := builder.Build().Transport.(*retryTransport)
+
+	expected := []int{http.StatusNotImplemented, http.StatusHTTPVersionNotSupported}
+	if len(rt.NoRetryStatuses) != len(expected) {
+		t.Fatalf("Expected statuses %v, got %v", expected, rt.NoRetryStatuses)
+	}
+	for i, status := range expected {
+		if rt.NoRetryStatuses[i] != status {
+			t.Errorf("Expected statuses %v, got %v", expected, rt.NoRetryStatuses)
+		}
+	}
+	if len(builder.Warnings()) != 2 {
+		t.Errorf("Expected 2 warnings, got %v", builder.Warnings())
+	}
+}
+
+func TestRetryTransport_StatusDelays(t *testing.T) {
+	responses := []struct {
+		status     int
+		retryAfter string
+	}{
+		{status: http.StatusTooManyRequests},
+		{status: http.StatusServiceUnavailable},
+		{status: http.StatusTooManyRequests, retryAfter: "2"},
+		{status: http.StatusOK},
+	}
+	var attempts int
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			r := responses[attempts]
+			attempts++
+			header := make(http.Header)
+			if r.retryAfter != "" {
+				header.Set("Retry-After", r.retryAfter)
+			}
+			return &http.Response{
+				StatusCode: r.status,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     header,
+			}, nil
+		},
+	}
+
+	clock := &fakeClock{now: time.Now()}
+	retryRT := &retryTransport{
+		Transport:         mockRT,
+		MaxRetries:        3,
+		RetryStrategy:     FixedDelay(1 * time.Second),
+		RetryableStatuses: []StatusRange{{429, 429}, {500, 599}},
+		RespectRetryAfter: true,
+		StatusDelays:      map[int]time.Duration{http.StatusTooManyRequests: 30 * time.Second},
+		Clock:             clock,
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The 429 waits its own delay, the 503 the strategy's, and Retry-After
+	// takes precedence over both
+	expected := []time.Duration{30 * time.Second, 1 * time.Second, 2 * time.Second}
+	if len(clock.sleeps) != len(expected) {
+		t.Fatalf("Expected delays %v, got %v", expected, clock.sleeps)
+	}
+	for i, delay := range expected {
+		if clock.sleeps[i] != delay {
+			t.Errorf("Expected delays %v, got %v", expected, clock.sleeps)
+		}
+	}
+}
+
+func TestClientBuilder_WithStatusDelay(t *testing.T) {
+	builder := NewClientBuilder().
+		WithStatusDelay(map[int]time.Duration{http.StatusTooManyRequests: 10 * time.Second, 0: time.Second}).
+		WithStatusDelay(map[int]time.Duration{http.StatusTooManyRequests: 30 * time.Second, http.StatusServiceUnavailable: -time.Second})
+	rt, _ := builder.Build().Transport.(*retryTransport)
+
+	if len(rt.StatusDelays) != 1 || rt.StatusDelays[http.StatusTooManyRequests] != 30*time.Second {
+		t.Errorf("Expected only the 30s delay of 429, got %v", rt.StatusDelays)
+	}
+	if len(builder.Warnings()) != 2 {
+		t.Errorf("Expected 2 warnings, got %v", builder.Warnings())
+	}
+}
+
+func TestClientBuilder_WithStatusDelayValidateKeepsBuilder(t *testing.T) {
+	delays := map[int]time.Duration{http.StatusServiceUnavailable: time.Second, 0: time.Second, http.StatusTooManyRequests: -time.Second}
+	builder := NewClientBuilder().WithStatusDelay(delays)
+
+	// Validating or describing the builder doesn't drop its invalid delays
+	if err := builder.Validate(); err == nil {
+		t.Error("Expected the invalid delays to be reported")
+	}
+	_ = builder.String()
+	if len(builder.client.statusDelays) != 3 {
+		t.Errorf("Expected the builder to keep its 3 delays, got %v", builder.client.statusDelays)
+	}
+	if _, err := builder.BuildStrict(); err == nil {
+		t.Error("Expected BuildStrict to reject the invalid delays")
+	}
+	if len(builder.client.statusDelays) != 3 {
+		t.Errorf("Expected the builder to keep its 3 delays, got %v", builder.client.statusDelays)
+	}
+}
@@ -0,0 +1,51 @@
+package httpretrier
+
+import (
+	"sync"
+	"time"
+)
+
+// StrategyFactory creates the strategy function of a registered strategy from
+// the client's base and max delays
+type StrategyFactory func(base, maxDelay time.Duration) RetryStrategy
+
+// strategies holds the strategies registered with RegisterStrategy
+var strategies = struct {
+	sync.RWMutex
+	factories map[Strategy]StrategyFactory
+}{factories: make(map[Strategy]StrategyFactory)}
+
+// RegisterStrategy makes a custom strategy selectable by name, with
+// WithRetryStrategy, WithRetryStrategyAsString or in presets, without forking
+// the package. Build calls factory with the validated base and max delays.
+// Names must be unique: RegisterStrategy panics if name is empty, is a built-in
+// strategy or was already registered, or if factory is nil. It is meant to be
+// called from an init function, but is safe for concurrent use.
+func RegisterStrategy(name Strategy, factory func(base, maxDelay time.Duration) RetryStrategy) {
+	if name == "" {
+		panic("httpretrier: RegisterStrategy with an empty name")
+	}
+	if factory == nil {
+		panic("httpretrier: RegisterStrategy factory is nil for " + string(name))
+	}
+	if name.isBuiltin() {
+		panic("httpretrier: RegisterStrategy called for built-in strategy " + string(name))
+	}
+
+	strategies.Lock()
+	defer strategies.Unlock()
+
+	if _, dup := strategies.factories[name]; dup {
+		panic("httpretrier: RegisterStrategy called twice for strategy " + string(name))
+	}
+	strategies.factories[name] = factory
+}
+
+// registeredStrategy returns the factory registered for name, if any
+func registeredStrategy(name Strategy) (StrategyFactory, bool) {
+	strategies.RLock()
+	defer strategies.RUnlock()
+
+	factory, ok := strategies.factories[name]
+	return factory, ok
+}
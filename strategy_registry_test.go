@@ -0,0 +1,111 @@
+package httpretrier
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// unregisterStrategies removes the strategies registered by a test once it is done
+func unregisterStrategies(t *testing.T, names ...Strategy) {
+	t.Cleanup(func() {
+		strategies.Lock()
+		defer strategies.Unlock()
+
+		for _, name := range names {
+			delete(strategies.factories, name)
+		}
+	})
+}
+
+func TestRegisterStrategy(t *testing.T) {
+	const name Strategy = "test-linear"
+	unregisterStrategies(t, name)
+	RegisterStrategy(name, func(base, maxDelay time.Duration) RetryStrategy {
+		return func(attempt int) time.Duration {
+			return min(base*time.Duration(attempt+1), maxDelay)
+		}
+	})
+
+	if !name.IsValid() {
+		t.Fatalf("Expected the registered strategy %q to be valid", name)
+	}
+	if Strategy("test-unregistered").IsValid() {
+		t.Error("Expected an unregistered strategy to be invalid")
+	}
+
+	for _, builder := range []*ClientBuilder{
+		NewClientBuilder().WithRetryStrategy(name),
+		NewClientBuilder().WithRetryStrategyAsString(string(name)),
+	} {
+		rt := builder.
+			WithRetryBaseDelay(400 * time.Millisecond).
+			WithRetryMaxDelay(1 * time.Second).
+			Build().Transport.(*retryTransport)
+
+		if rt.config.Strategy != name {
+			t.Errorf("Expected strategy %q, got %q", name, rt.config.Strategy)
+		}
+		for attempt, want := range []time.Duration{400 * time.Millisecond, 800 * time.Millisecond, 1 * time.Second} {
+			if delay := rt.RetryStrategy(attempt); delay != want {
+				t.Errorf("Expected delay %v after attempt %d, got %v", want, attempt, delay)
+			}
+		}
+	}
+}
+
+func TestRegisterStrategy_Panics(t *testing.T) {
+	factory := func(base, maxDelay time.Duration) RetryStrategy { return FixedDelay(base) }
+	unregisterStrategies(t, "test-duplicate")
+	RegisterStrategy("test-duplicate", factory)
+
+	tests := []struct {
+		name     string
+		strategy Strategy
+		factory  func(base, maxDelay time.Duration) RetryStrategy
+	}{
+		{"Empty name", "", factory},
+		{"Nil factory", "test-nil", nil},
+		{"Built-in name", ExponentialBackoffStrategy, factory},
+		{"Duplicate name", "test-duplicate", factory},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected RegisterStrategy(%q) to panic", tt.strategy)
+				}
+			}()
+			RegisterStrategy(tt.strategy, tt.factory)
+		})
+	}
+}
+
+func TestRegisterStrategy_Concurrent(t *testing.T) {
+	names := make([]Strategy, 10)
+	for i := range names {
+		names[i] = Strategy("test-concurrent-" + string(rune('a'+i)))
+	}
+	unregisterStrategies(t, names...)
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterStrategy(names[i], func(base, maxDelay time.Duration) RetryStrategy {
+				return FixedDelay(base)
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			Strategy("test-concurrent-a").IsValid()
+		}()
+	}
+	wg.Wait()
+
+	if !Strategy("test-concurrent-j").IsValid() {
+		t.Error("Expected the concurrently registered strategies to be valid")
+	}
+}
@@ -0,0 +1,89 @@
+package httpretrier
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// validateTLSFiles loads the client certificate and root CAs set with
+// WithClientCertificate and WithRootCAFile, recording a violation for the
+// files that can't be loaded, which are then not used
+func (c *Client) validateTLSFiles(vs *violations) {
+	c.clientCert = nil
+	if c.clientCertFile != "" || c.clientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.clientCertFile, c.clientKeyFile)
+		if err != nil {
+			*vs = append(*vs, violation{
+				field:    "client certificate",
+				rule:     fmt.Sprintf("must be loadable (%v)", err),
+				value:    fmt.Sprintf("%q, %q", c.clientCertFile, c.clientKeyFile),
+				fallback: "no client certificate",
+				message:  "Invalid client certificate, not using one",
+			})
+			c.clientCertFile, c.clientKeyFile = "", ""
+		} else {
+			c.clientCert = &cert
+		}
+	}
+
+	c.rootCAs = nil
+	if c.rootCAFile != "" {
+		roots, err := loadCertPool(c.rootCAFile)
+		if err != nil {
+			*vs = append(*vs, violation{
+				field:    "root CA file",
+				rule:     fmt.Sprintf("must be loadable (%v)", err),
+				value:    fmt.Sprintf("%q", c.rootCAFile),
+				fallback: "the system root CAs",
+				message:  "Invalid root CA file, using the system root CAs",
+			})
+			c.rootCAFile = ""
+		} else {
+			c.rootCAs = roots
+		}
+	}
+}
+
+// loadCertPool returns a pool of the PEM encoded certificates in the file
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+
+	return pool, nil
+}
+
+// transportTLSConfig returns the TLS configuration of the transport: the one
+// set with WithTLSConfig, completed with the min TLS version, unless it has
+// one, and the client certificate and root CAs loaded from files. The
+// configuration set with WithTLSConfig is cloned rather than modified.
+func (c *Client) transportTLSConfig() *tls.Config {
+	if c.tlsConfig != nil && c.tlsConfig.MinVersion != 0 && c.clientCert == nil && c.rootCAs == nil {
+		return c.tlsConfig
+	}
+
+	config := &tls.Config{}
+	if c.tlsConfig != nil {
+		config = c.tlsConfig.Clone()
+	}
+	if config.MinVersion == 0 {
+		config.MinVersion = c.minTLSVersion
+	}
+	if c.clientCert != nil {
+		config.Certificates = append(config.Certificates, *c.clientCert)
+	}
+	if c.rootCAs != nil {
+		config.RootCAs = c.rootCAs
+	}
+
+	return config
+}
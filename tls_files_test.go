@@ -0,0 +1,219 @@
+package httpretrier
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA is a temporary certificate authority issuing certificates for tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a certificate signed by the CA and its key, PEM encoded
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile writes data to a file named name in dir and returns its path
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestClientBuilder_WithClientCertificateAndRootCAFile(t *testing.T) {
+	ca := newTestCA(t)
+	serverCertPEM, serverKeyPEM := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	clientCertPEM, clientKeyPEM := ca.issue(t, 3, x509.ExtKeyUsageClientAuth)
+
+	serverCert, err := tls.X509KeyPair(serverCertPEM, serverKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].SerialNumber.String()))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := writeFile(t, dir, "ca.pem", ca.pem)
+	certFile := writeFile(t, dir, "client.pem", clientCertPEM)
+	keyFile := writeFile(t, dir, "client-key.pem", clientKeyPEM)
+
+	// The configuration set with WithTLSConfig is completed, not modified
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	builder := NewClientBuilder().
+		WithTLSConfig(tlsConfig).
+		WithClientCertificate(certFile, keyFile).
+		WithRootCAFile(caFile)
+	httpClient := builder.Build()
+	if warnings := builder.Warnings(); len(warnings) != 0 {
+		t.Fatalf("Expected no warnings, got %v", warnings)
+	}
+	if tlsConfig.RootCAs != nil || len(tlsConfig.Certificates) != 0 {
+		t.Error("Expected the TLS configuration set with WithTLSConfig to be left unmodified")
+	}
+
+	resp, err := httpClient.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the mTLS request to succeed, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "3" {
+		t.Errorf("Expected the server to see the client certificate, got %q", body)
+	}
+}
+
+func TestClientBuilder_TLSFilesInvalid(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.pem")
+	notPEM := writeFile(t, dir, "not.pem", []byte("not a certificate"))
+
+	builder := NewClientBuilder().
+		WithClientCertificate(missing, missing).
+		WithRootCAFile(notPEM)
+	httpClient := builder.Build()
+	if warnings := builder.Warnings(); len(warnings) != 2 {
+		t.Errorf("Expected 2 warnings, got %v", warnings)
+	}
+
+	rt, _ := httpClient.Transport.(*retryTransport)
+	transport, _ := rt.Transport.(*http.Transport)
+	if config := transport.TLSClientConfig; config.RootCAs != nil || len(config.Certificates) != 0 {
+		t.Errorf("Expected no root CAs nor client certificate, got %v", config)
+	}
+
+	_, err := NewClientBuilder().WithRootCAFile(missing).BuildStrict()
+	if err == nil {
+		t.Error("Expected BuildStrict to fail on a missing root CA file")
+	}
+}
+
+func TestClientBuilder_WithMinTLSVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS10}
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	// The TLS 1.0 only server is refused by default
+	httpClient := NewClientBuilder().
+		WithTLSConfig(&tls.Config{RootCAs: roots}).
+		WithMaxRetries(0).
+		Build()
+	if _, err := httpClient.Get(server.URL); err == nil {
+		t.Error("Expected a TLS 1.0 only server to be refused")
+	}
+
+	// It is accepted once allowed
+	httpClient = NewClientBuilder().
+		WithTLSConfig(&tls.Config{RootCAs: roots}).
+		WithMinTLSVersion(tls.VersionTLS10).
+		Build()
+	resp, err := httpClient.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected a TLS 1.0 server to be accepted with WithMinTLSVersion(tls.VersionTLS10), got %v", err)
+	}
+	resp.Body.Close()
+
+	// A version set in the TLS configuration wins
+	httpClient = NewClientBuilder().
+		WithTLSConfig(&tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS13}).
+		WithMinTLSVersion(tls.VersionTLS10).
+		Build()
+	rt, _ := httpClient.Transport.(*retryTransport)
+	transport, _ := rt.Transport.(*http.Transport)
+	if transport.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("Expected the configuration's min version to be kept, got %#04x", transport.TLSClientConfig.MinVersion)
+	}
+
+	// Invalid versions fall back to the default
+	builder := NewClientBuilder().WithMinTLSVersion(0x0200)
+	builder.Build()
+	if builder.MinTLSVersion() != DefaultMinTLSVersion || len(builder.Warnings()) != 1 {
+		t.Errorf("Expected the default version and 1 warning, got %#04x and %v", builder.MinTLSVersion(), builder.Warnings())
+	}
+}
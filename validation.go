@@ -0,0 +1,503 @@
+package httpretrier
+
+import (
+	"crypto/tls"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// violation describes a builder setting that failed validation
+type violation struct {
+	field    string // Name of the setting
+	rule     string // Constraint the value broke
+	value    any    // Invalid value
+	fallback any    // Value used instead
+	message  string // Log message, if it differs from the standard one
+}
+
+// logMessage returns the warning logged when the setting is replaced by its fallback
+func (v violation) logMessage() string {
+	if v.message != "" {
+		return v.message
+	}
+
+	return "Invalid " + v.field + ", using default value"
+}
+
+// String describes the violation, e.g. "max retries 0 must be between 1 and 10"
+func (v violation) String() string {
+	return fmt.Sprintf("%s %v %s", v.field, v.value, v.rule)
+}
+
+// warning describes how the violation was handled by Build, e.g.
+// "max retries: invalid value 0 (must be between 1 and 10), using 3"
+func (v violation) warning() string {
+	return fmt.Sprintf("%s: invalid value %v (%s), using %v", v.field, v.value, v.rule, v.fallback)
+}
+
+// violations collects the violations found while validating a Client
+type violations []violation
+
+// outOfRange records a value outside [min, max]
+func (vs *violations) outOfRange(field string, value, min, max, fallback any) {
+	*vs = append(*vs, violation{
+		field:    field,
+		rule:     fmt.Sprintf("must be between %v and %v", min, max),
+		value:    value,
+		fallback: fallback,
+	})
+}
+
+// add records a value breaking the given rule
+func (vs *violations) add(field, rule string, value, fallback any) {
+	*vs = append(*vs, violation{field: field, rule: rule, value: value, fallback: fallback})
+}
+
+// err aggregates all violations into a single ClientError, or returns nil if there are none
+func (vs violations) err() error {
+	if len(vs) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, len(vs))
+	for i, v := range vs {
+		descriptions[i] = v.String()
+	}
+
+	return &ClientError{Message: "invalid client configuration: " + strings.Join(descriptions, "; ")}
+}
+
+// validate checks every setting of the Client, replacing each invalid one with
+// its default value, and returns the violations found
+func (c *Client) validate() violations {
+	var vs violations
+
+	c.ranges.validate(&vs)
+
+	if !c.ranges.MaxIdleConns.contains(c.maxIdleConns) {
+		vs.outOfRange("max idle connections", c.maxIdleConns, c.ranges.MaxIdleConns.Min, c.ranges.MaxIdleConns.Max, DefaultMaxIdleConns)
+		c.maxIdleConns = DefaultMaxIdleConns
+	}
+
+	if !c.ranges.IdleConnTimeout.contains(c.idleConnTimeout) {
+		vs.outOfRange("idle connection timeout", c.idleConnTimeout, c.ranges.IdleConnTimeout.Min, c.ranges.IdleConnTimeout.Max, DefaultIdleConnTimeout)
+		c.idleConnTimeout = DefaultIdleConnTimeout
+	}
+
+	if !c.ranges.TLSHandshakeTimeout.contains(c.tlsHandshakeTimeout) {
+		vs.outOfRange("TLS handshake timeout", c.tlsHandshakeTimeout, c.ranges.TLSHandshakeTimeout.Min, c.ranges.TLSHandshakeTimeout.Max, DefaultTLSHandshakeTimeout)
+		c.tlsHandshakeTimeout = DefaultTLSHandshakeTimeout
+	}
+
+	if !c.ranges.ExpectContinueTimeout.contains(c.expectContinueTimeout) {
+		vs.outOfRange("expect continue timeout", c.expectContinueTimeout, c.ranges.ExpectContinueTimeout.Min, c.ranges.ExpectContinueTimeout.Max, DefaultExpectContinueTimeout)
+		c.expectContinueTimeout = DefaultExpectContinueTimeout
+	}
+
+	// 0 means no timeout
+	if c.responseHeaderTimeout != 0 && !c.ranges.ResponseHeaderTimeout.contains(c.responseHeaderTimeout) {
+		vs.outOfRange("response header timeout", c.responseHeaderTimeout, c.ranges.ResponseHeaderTimeout.Min, c.ranges.ResponseHeaderTimeout.Max, DefaultResponseHeaderTimeout)
+		c.responseHeaderTimeout = DefaultResponseHeaderTimeout
+	}
+
+	if !c.ranges.DialTimeout.contains(c.dialTimeout) {
+		vs.outOfRange("dial timeout", c.dialTimeout, c.ranges.DialTimeout.Min, c.ranges.DialTimeout.Max, DefaultDialTimeout)
+		c.dialTimeout = DefaultDialTimeout
+	}
+
+	if !c.ranges.DialKeepAlive.contains(c.dialKeepAlive) {
+		vs.outOfRange("dial keep-alive", c.dialKeepAlive, c.ranges.DialKeepAlive.Min, c.ranges.DialKeepAlive.Max, DefaultDialKeepAlive)
+		c.dialKeepAlive = DefaultDialKeepAlive
+	}
+
+	if !c.ranges.MaxIdleConnsPerHost.contains(c.maxIdleConnsPerHost) {
+		vs.outOfRange("max idle connections per host", c.maxIdleConnsPerHost, c.ranges.MaxIdleConnsPerHost.Min, c.ranges.MaxIdleConnsPerHost.Max, DefaultMaxIdleConnsPerHost)
+		c.maxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+
+	// 0 means no limit
+	if c.maxConnsPerHost != 0 && !c.ranges.MaxConnsPerHost.contains(c.maxConnsPerHost) {
+		vs.outOfRange("max connections per host", c.maxConnsPerHost, c.ranges.MaxConnsPerHost.Min, c.ranges.MaxConnsPerHost.Max, DefaultMaxConnsPerHost)
+		c.maxConnsPerHost = DefaultMaxConnsPerHost
+	}
+
+	// 0 means net/http's default size
+	if c.writeBufferSize != 0 && !c.ranges.WriteBufferSize.contains(c.writeBufferSize) {
+		vs.outOfRange("write buffer size", c.writeBufferSize, c.ranges.WriteBufferSize.Min, c.ranges.WriteBufferSize.Max, DefaultWriteBufferSize)
+		c.writeBufferSize = DefaultWriteBufferSize
+	}
+
+	if c.readBufferSize != 0 && !c.ranges.ReadBufferSize.contains(c.readBufferSize) {
+		vs.outOfRange("read buffer size", c.readBufferSize, c.ranges.ReadBufferSize.Min, c.ranges.ReadBufferSize.Max, DefaultReadBufferSize)
+		c.readBufferSize = DefaultReadBufferSize
+	}
+
+	// 0 means net/http's default limit
+	if c.maxResponseHeaderBytes != 0 && !c.ranges.MaxResponseHeaderBytes.contains(c.maxResponseHeaderBytes) {
+		vs.outOfRange("max response header bytes", c.maxResponseHeaderBytes, c.ranges.MaxResponseHeaderBytes.Min, c.ranges.MaxResponseHeaderBytes.Max, DefaultMaxResponseHeaderBytes)
+		c.maxResponseHeaderBytes = DefaultMaxResponseHeaderBytes
+	}
+
+	if !c.ranges.Timeout.contains(c.timeout) {
+		vs.outOfRange("timeout", c.timeout, c.ranges.Timeout.Min, c.ranges.Timeout.Max, DefaultTimeout)
+		c.timeout = DefaultTimeout
+	}
+
+	if !c.ranges.MaxRetries.contains(c.maxRetries) {
+		vs.outOfRange("max retries", c.maxRetries, c.ranges.MaxRetries.Min, c.ranges.MaxRetries.Max, DefaultMaxRetries)
+		c.maxRetries = DefaultMaxRetries
+	}
+
+	if c.maxAttemptsSet {
+		if !c.ranges.MaxAttempts.contains(c.maxAttempts) {
+			vs.outOfRange("max attempts", c.maxAttempts, c.ranges.MaxAttempts.Min, c.ranges.MaxAttempts.Max, DefaultMaxRetries+1)
+			c.maxAttempts = DefaultMaxRetries + 1
+		}
+
+		if c.maxRetriesSet {
+			vs = append(vs, violation{
+				field:    "max retries",
+				rule:     "must not be set together with max attempts",
+				value:    c.maxRetries,
+				fallback: c.maxAttempts - 1,
+				message:  "Both max retries and max attempts set, using max attempts",
+			})
+		}
+
+		// Attempts include the first request, retries don't
+		c.maxRetries = c.maxAttempts - 1
+	}
+
+	if c.maxElapsedTime < 0 {
+		vs.add("max elapsed time", "must not be negative", c.maxElapsedTime, DefaultMaxElapsedTime)
+		c.maxElapsedTime = DefaultMaxElapsedTime
+	}
+
+	if c.maxJitter < 0 {
+		vs.add("max jitter", "must not be negative", c.maxJitter, DefaultMaxJitter)
+		c.maxJitter = DefaultMaxJitter
+	}
+
+	if c.minAttemptBudget < 0 {
+		vs.add("min attempt budget", "must not be negative", c.minAttemptBudget, DefaultMinAttemptBudget)
+		c.minAttemptBudget = DefaultMinAttemptBudget
+	}
+
+	if c.maxBufferSize <= 0 {
+		vs.add("max buffer size", "must be positive", c.maxBufferSize, DefaultMaxBufferSize)
+		c.maxBufferSize = DefaultMaxBufferSize
+	}
+
+	if c.maxTotalBufferBytes < 0 {
+		vs.add("max total buffer bytes", "must not be negative", c.maxTotalBufferBytes, DefaultMaxTotalBufferBytes)
+		c.maxTotalBufferBytes = DefaultMaxTotalBufferBytes
+	}
+
+	if c.maxDrainSize <= 0 {
+		vs.add("max drain size", "must be positive", c.maxDrainSize, DefaultMaxDrainSize)
+		c.maxDrainSize = DefaultMaxDrainSize
+	}
+
+	if !c.ranges.DrainBufferSize.contains(c.drainBufferSize) {
+		vs.outOfRange("drain buffer size", c.drainBufferSize, c.ranges.DrainBufferSize.Min, c.ranges.DrainBufferSize.Max, DefaultDrainBufferSize)
+		c.drainBufferSize = DefaultDrainBufferSize
+	}
+
+	if c.maxValidateBodySize <= 0 {
+		vs.add("max validate body size", "must be positive", c.maxValidateBodySize, DefaultMaxValidateBodySize)
+		c.maxValidateBodySize = DefaultMaxValidateBodySize
+	}
+
+	if c.connMaxLifetime < 0 {
+		vs.add("connection max lifetime", "must not be negative", c.connMaxLifetime, DefaultConnMaxLifetime)
+		c.connMaxLifetime = DefaultConnMaxLifetime
+	}
+
+	if c.perAttemptTimeout < 0 {
+		vs.add("per-attempt timeout", "must not be negative", c.perAttemptTimeout, DefaultPerAttemptTimeout)
+		c.perAttemptTimeout = DefaultPerAttemptTimeout
+	}
+
+	if c.maxConcurrent < 0 {
+		vs.add("max concurrent requests", "must not be negative", c.maxConcurrent, DefaultMaxConcurrent)
+		c.maxConcurrent = DefaultMaxConcurrent
+	}
+
+	if c.maxConcurrentPerHost < 0 {
+		vs.add("max concurrent requests per host", "must not be negative", c.maxConcurrentPerHost, DefaultMaxConcurrentPerHost)
+		c.maxConcurrentPerHost = DefaultMaxConcurrentPerHost
+	}
+
+	// Written so that NaN is rejected too
+	if c.retryBudgetSet && (!(c.retryBudgetRatio >= 0 && c.retryBudgetRatio <= 1) ||
+		!(c.retryBudgetMinPerSec >= 0) || math.IsInf(c.retryBudgetMinPerSec, 1)) {
+		vs = append(vs, violation{
+			field:    "retry budget",
+			rule:     "ratio must be between 0 and 1 and min retries per second non-negative and finite",
+			value:    fmt.Sprintf("ratio=%v minRetriesPerSec=%v", c.retryBudgetRatio, c.retryBudgetMinPerSec),
+			fallback: "no retry budget",
+			message:  "Invalid retry budget, not limiting retries",
+		})
+		c.retryBudgetSet = false
+	}
+
+	if c.rateLimitSet {
+		// Written so that NaN is rejected too
+		if !(c.rateLimit > 0) || math.IsInf(c.rateLimit, 1) {
+			vs = append(vs, violation{
+				field:    "rate limit",
+				rule:     "must be positive and finite",
+				value:    c.rateLimit,
+				fallback: "no rate limit",
+				message:  "Invalid rate limit, not limiting the request rate",
+			})
+			c.rateLimitSet = false
+		} else if c.rateLimitBurst < 1 {
+			vs.add("rate limit burst", "must be at least 1", c.rateLimitBurst, 1)
+			c.rateLimitBurst = 1
+		}
+	}
+
+	// Hedging is off unless both settings are given
+	if (c.maxHedges != 0 || c.hedgeDelay != 0) && (c.maxHedges < 1 || c.hedgeDelay <= 0) {
+		vs = append(vs, violation{
+			field:    "hedging",
+			rule:     "needs a positive delay and at least 1 hedge",
+			value:    fmt.Sprintf("delay=%v max=%d", c.hedgeDelay, c.maxHedges),
+			fallback: "no hedging",
+			message:  "Invalid hedging settings, not hedging requests",
+		})
+		c.maxHedges = 0
+		c.hedgeDelay = 0
+	}
+
+	if c.breakerSet {
+		if c.breakerThreshold < 1 {
+			vs = append(vs, violation{
+				field:    "circuit breaker failure threshold",
+				rule:     "must be at least 1",
+				value:    c.breakerThreshold,
+				fallback: "no circuit breaker",
+				message:  "Invalid circuit breaker failure threshold, not using a circuit breaker",
+			})
+			c.breakerSet = false
+		} else if c.breakerOpenDuration <= 0 {
+			vs = append(vs, violation{
+				field:    "circuit breaker open duration",
+				rule:     "must be positive",
+				value:    c.breakerOpenDuration,
+				fallback: "no circuit breaker",
+				message:  "Invalid circuit breaker open duration, not using a circuit breaker",
+			})
+			c.breakerSet = false
+		}
+	}
+
+	if !c.ranges.BaseDelay.contains(c.retryBaseDelay) {
+		vs.outOfRange("base delay", c.retryBaseDelay, c.ranges.BaseDelay.Min, c.ranges.BaseDelay.Max, DefaultBaseDelay)
+		c.retryBaseDelay = DefaultBaseDelay
+	}
+
+	if c.retryMaxDelay != NoMaxDelay && !c.ranges.MaxDelay.contains(c.retryMaxDelay) {
+		vs.outOfRange("max delay", c.retryMaxDelay, c.ranges.MaxDelay.Min, c.ranges.MaxDelay.Max, DefaultMaxDelay)
+		c.retryMaxDelay = DefaultMaxDelay
+	}
+
+	if c.minDelay < 0 {
+		vs = append(vs, violation{
+			field:    "min delay",
+			rule:     "must not be negative",
+			value:    c.minDelay,
+			fallback: "no min delay",
+			message:  "Invalid min delay, not using a min delay",
+		})
+		c.minDelay = 0
+	} else if c.retryMaxDelay != NoMaxDelay && c.minDelay > c.retryMaxDelay {
+		vs = append(vs, violation{
+			field:    "min delay",
+			rule:     fmt.Sprintf("must not exceed the max delay %v", c.retryMaxDelay),
+			value:    c.minDelay,
+			fallback: fmt.Sprintf("%v, swapped with the max delay", c.retryMaxDelay),
+			message:  "Min delay above the max delay, swapping them",
+		})
+		c.minDelay, c.retryMaxDelay = c.retryMaxDelay, c.minDelay
+	}
+
+	if !c.retryStrategyType.IsValid() {
+		vs = append(vs, violation{
+			field:    "retry strategy",
+			rule:     "is not a known strategy",
+			value:    fmt.Sprintf("%q", c.retryStrategyType),
+			fallback: ExponentialBackoffStrategy,
+			message:  "No valid retry strategy type set, using default (Exponential)",
+		})
+		c.retryStrategyType = ExponentialBackoffStrategy
+	}
+
+	if !c.ranges.RetryMultiplier.contains(c.retryMultiplier) {
+		vs.outOfRange("retry multiplier", c.retryMultiplier, c.ranges.RetryMultiplier.Min, c.ranges.RetryMultiplier.Max, DefaultRetryMultiplier)
+		c.retryMultiplier = DefaultRetryMultiplier
+	}
+
+	if !c.ranges.FixedJitter.contains(c.fixedJitter) {
+		vs.outOfRange("fixed jitter", c.fixedJitter, c.ranges.FixedJitter.Min, c.ranges.FixedJitter.Max, DefaultFixedJitter)
+		c.fixedJitter = DefaultFixedJitter
+	}
+
+	if c.randomMaxDelayMean != 0 && !c.ranges.MaxDelay.contains(c.randomMaxDelayMean) {
+		vs = append(vs, violation{
+			field:    "randomized max delay mean",
+			rule:     fmt.Sprintf("must be between %v and %v", c.ranges.MaxDelay.Min, c.ranges.MaxDelay.Max),
+			value:    c.randomMaxDelayMean,
+			fallback: c.retryMaxDelay,
+			message:  "Invalid randomized max delay mean, using the fixed max delay",
+		})
+		c.randomMaxDelayMean = 0
+	}
+
+	if c.maxRedirectsSet && c.maxRedirects < 0 {
+		vs = append(vs, violation{
+			field:    "max redirects",
+			rule:     "must not be negative",
+			value:    c.maxRedirects,
+			fallback: "the default redirect policy",
+			message:  "Invalid max redirects, using the default redirect policy",
+		})
+		c.maxRedirectsSet = false
+	}
+
+	if len(c.fallbackHosts) > 0 {
+		valid := make([]string, 0, len(c.fallbackHosts))
+		for _, host := range c.fallbackHosts {
+			if u, err := url.Parse("//" + host); host == "" || err != nil || u.Host != host {
+				vs = append(vs, violation{
+					field:    "fallback host",
+					rule:     "must be a host or host:port",
+					value:    fmt.Sprintf("%q", host),
+					fallback: "skipping it",
+					message:  "Invalid fallback host, skipping it",
+				})
+				continue
+			}
+			valid = append(valid, host)
+		}
+		c.fallbackHosts = valid
+	}
+
+	if len(c.retryableStatuses) > 0 {
+		ranges := make([]StatusRange, 0, len(c.retryableStatuses))
+		for _, s := range c.retryableStatuses {
+			if !s.valid() {
+				vs = append(vs, violation{
+					field:    "retryable status range",
+					rule:     "must be within [100, 599] with min <= max",
+					value:    fmt.Sprintf("[%d, %d]", s.Min, s.Max),
+					fallback: "ignored",
+					message:  "Invalid retryable status range, ignoring it",
+				})
+				continue
+			}
+			ranges = append(ranges, s)
+		}
+		c.retryableStatuses = ranges
+	}
+
+	if len(c.noRetryStatuses) > 0 {
+		statuses := make([]int, 0, len(c.noRetryStatuses))
+		for _, status := range c.noRetryStatuses {
+			if status < 100 || status > 599 {
+				vs = append(vs, violation{
+					field:    "no-retry status",
+					rule:     "must be within [100, 599]",
+					value:    status,
+					fallback: "ignored",
+					message:  "Invalid no-retry status, ignoring it",
+				})
+				continue
+			}
+			statuses = append(statuses, status)
+		}
+		c.noRetryStatuses = statuses
+	}
+
+	if len(c.statusDelays) > 0 {
+		// A filtered copy, the map being shared with the builder
+		delays := make(map[int]time.Duration, len(c.statusDelays))
+		for status, delay := range c.statusDelays {
+			if status < 100 || status > 599 || delay < 0 {
+				vs = append(vs, violation{
+					field:    "status delay",
+					rule:     "must have a status within [100, 599] and a delay >= 0",
+					value:    fmt.Sprintf("%d: %v", status, delay),
+					fallback: "ignored",
+					message:  "Invalid status delay, ignoring it",
+				})
+				continue
+			}
+			delays[status] = delay
+		}
+		c.statusDelays = delays
+	}
+
+	if c.expectedContentType != "" {
+		mediaType, params, err := mime.ParseMediaType(c.expectedContentType)
+		if err != nil || len(params) > 0 || !strings.Contains(mediaType, "/") {
+			vs = append(vs, violation{
+				field:    "expected content type",
+				rule:     "must be a media type",
+				value:    fmt.Sprintf("%q", c.expectedContentType),
+				fallback: "no check",
+				message:  "Invalid expected content type, not checking it",
+			})
+			mediaType = ""
+		}
+		c.expectedContentType = mediaType
+	}
+
+	if c.proxyURL != "" {
+		u, err := url.Parse(c.proxyURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			vs = append(vs, violation{
+				field:    "proxy URL",
+				rule:     "must be an absolute URL",
+				value:    fmt.Sprintf("%q", c.proxyURL),
+				fallback: "no proxy",
+				message:  "Invalid proxy URL, not using a proxy",
+			})
+		} else {
+			c.proxy = http.ProxyURL(u)
+		}
+		c.proxyURL = ""
+	}
+
+	if c.minTLSVersion < tls.VersionTLS10 || c.minTLSVersion > tls.VersionTLS13 {
+		vs = append(vs, violation{
+			field:    "min TLS version",
+			rule:     "must be one of tls.VersionTLS10 to tls.VersionTLS13",
+			value:    fmt.Sprintf("%#04x", c.minTLSVersion),
+			fallback: tls.VersionName(DefaultMinTLSVersion),
+			message:  "Invalid min TLS version, using default value",
+		})
+		c.minTLSVersion = DefaultMinTLSVersion
+	}
+
+	c.validateTLSFiles(&vs)
+
+	if c.unixSocket != "" && c.proxy != nil {
+		vs = append(vs, violation{
+			field:    "proxy",
+			rule:     "must not be set together with a Unix socket",
+			value:    "set",
+			fallback: "no proxy",
+			message:  "Proxy set together with a Unix socket, not using a proxy",
+		})
+		c.proxy = nil
+	}
+
+	return vs
+}
@@ -0,0 +1,126 @@
+package httpretrier
+
+import (
+	"cmp"
+	"fmt"
+	"time"
+)
+
+// Range is the inclusive range of values accepted for a builder setting
+type Range[T cmp.Ordered] struct {
+	Min T
+	Max T
+}
+
+// contains reports whether v is within the range. NaN is never within it
+func (r Range[T]) contains(v T) bool {
+	return v >= r.Min && v <= r.Max
+}
+
+// ValidationRanges holds the ranges of values Build accepts for the builder
+// settings. Values outside their range are replaced by the default value, or
+// rejected by BuildStrict.
+// DefaultValidationRanges returns the ranges used unless they are changed
+// with WithValidationRanges, which are the ValidMin* and ValidMax* constants.
+type ValidationRanges struct {
+	MaxIdleConns           Range[int]
+	MaxIdleConnsPerHost    Range[int]
+	MaxConnsPerHost        Range[int]   // 0, meaning no limit, is always accepted
+	WriteBufferSize        Range[int]   // 0, meaning net/http's default, is always accepted
+	ReadBufferSize         Range[int]   // 0, meaning net/http's default, is always accepted
+	MaxResponseHeaderBytes Range[int64] // 0, meaning net/http's default, is always accepted
+	IdleConnTimeout        Range[time.Duration]
+	TLSHandshakeTimeout    Range[time.Duration]
+	ExpectContinueTimeout  Range[time.Duration]
+	ResponseHeaderTimeout  Range[time.Duration] // 0, meaning no timeout, is always accepted
+	DialTimeout            Range[time.Duration]
+	DialKeepAlive          Range[time.Duration]
+	Timeout                Range[time.Duration]
+	MaxRetries             Range[int]
+	MaxAttempts            Range[int]
+	BaseDelay              Range[time.Duration]
+	MaxDelay               Range[time.Duration] // Also bounds the randomized max delay
+	RetryMultiplier        Range[float64]
+	FixedJitter            Range[float64]
+	DrainBufferSize        Range[int]
+}
+
+// DefaultValidationRanges returns the ranges accepted by default, made of the
+// ValidMin* and ValidMax* constants
+func DefaultValidationRanges() ValidationRanges {
+	return ValidationRanges{
+		MaxIdleConns:           Range[int]{ValidMinIdleConns, ValidMaxIdleConns},
+		MaxIdleConnsPerHost:    Range[int]{ValidMinIdleConnsPerHost, ValidMaxIdleConnsPerHost},
+		MaxConnsPerHost:        Range[int]{ValidMinMaxConnsPerHost, ValidMaxMaxConnsPerHost},
+		WriteBufferSize:        Range[int]{ValidMinWriteBufferSize, ValidMaxWriteBufferSize},
+		ReadBufferSize:         Range[int]{ValidMinReadBufferSize, ValidMaxReadBufferSize},
+		MaxResponseHeaderBytes: Range[int64]{ValidMinResponseHeaderBytes, ValidMaxResponseHeaderBytes},
+		IdleConnTimeout:        Range[time.Duration]{ValidMinIdleConnTimeout, ValidMaxIdleConnTimeout},
+		TLSHandshakeTimeout:    Range[time.Duration]{ValidMinTLSHandshakeTimeout, ValidMaxTLSHandshakeTimeout},
+		ExpectContinueTimeout:  Range[time.Duration]{ValidMinExpectContinueTimeout, ValidMaxExpectContinueTimeout},
+		ResponseHeaderTimeout:  Range[time.Duration]{ValidMinResponseHeaderTimeout, ValidMaxResponseHeaderTimeout},
+		DialTimeout:            Range[time.Duration]{ValidMinDialTimeout, ValidMaxDialTimeout},
+		DialKeepAlive:          Range[time.Duration]{ValidMinDialKeepAlive, ValidMaxDialKeepAlive},
+		Timeout:                Range[time.Duration]{ValidMinTimeout, ValidMaxTimeout},
+		MaxRetries:             Range[int]{ValidMinRetries, ValidMaxRetries},
+		MaxAttempts:            Range[int]{ValidMinAttempts, ValidMaxAttempts},
+		BaseDelay:              Range[time.Duration]{ValidMinBaseDelay, ValidMaxBaseDelay},
+		MaxDelay:               Range[time.Duration]{ValidMinMaxDelay, ValidMaxMaxDelay},
+		RetryMultiplier:        Range[float64]{ValidMinRetryMultiplier, ValidMaxRetryMultiplier},
+		FixedJitter:            Range[float64]{ValidMinFixedJitter, ValidMaxFixedJitter},
+		DrainBufferSize:        Range[int]{ValidMinDrainBufferSize, ValidMaxDrainBufferSize},
+	}
+}
+
+// validate replaces the zero ranges of r with their default and the invalid
+// ones, whose minimum is above their maximum, too, recording a violation
+func (r *ValidationRanges) validate(vs *violations) {
+	defaults := DefaultValidationRanges()
+
+	// Attempts follow the retries unless set on their own
+	if r.MaxAttempts == (Range[int]{}) && r.MaxRetries != (Range[int]{}) {
+		r.MaxAttempts = Range[int]{ValidMinAttempts, r.MaxRetries.Max + 1}
+	}
+
+	checkRange(vs, "max idle connections", &r.MaxIdleConns, defaults.MaxIdleConns)
+	checkRange(vs, "max idle connections per host", &r.MaxIdleConnsPerHost, defaults.MaxIdleConnsPerHost)
+	checkRange(vs, "max connections per host", &r.MaxConnsPerHost, defaults.MaxConnsPerHost)
+	checkRange(vs, "write buffer size", &r.WriteBufferSize, defaults.WriteBufferSize)
+	checkRange(vs, "read buffer size", &r.ReadBufferSize, defaults.ReadBufferSize)
+	checkRange(vs, "max response header bytes", &r.MaxResponseHeaderBytes, defaults.MaxResponseHeaderBytes)
+	checkRange(vs, "idle connection timeout", &r.IdleConnTimeout, defaults.IdleConnTimeout)
+	checkRange(vs, "TLS handshake timeout", &r.TLSHandshakeTimeout, defaults.TLSHandshakeTimeout)
+	checkRange(vs, "expect continue timeout", &r.ExpectContinueTimeout, defaults.ExpectContinueTimeout)
+	checkRange(vs, "response header timeout", &r.ResponseHeaderTimeout, defaults.ResponseHeaderTimeout)
+	checkRange(vs, "dial timeout", &r.DialTimeout, defaults.DialTimeout)
+	checkRange(vs, "dial keep-alive", &r.DialKeepAlive, defaults.DialKeepAlive)
+	checkRange(vs, "timeout", &r.Timeout, defaults.Timeout)
+	checkRange(vs, "max retries", &r.MaxRetries, defaults.MaxRetries)
+	checkRange(vs, "max attempts", &r.MaxAttempts, defaults.MaxAttempts)
+	checkRange(vs, "base delay", &r.BaseDelay, defaults.BaseDelay)
+	checkRange(vs, "max delay", &r.MaxDelay, defaults.MaxDelay)
+	checkRange(vs, "retry multiplier", &r.RetryMultiplier, defaults.RetryMultiplier)
+	checkRange(vs, "fixed jitter", &r.FixedJitter, defaults.FixedJitter)
+	checkRange(vs, "drain buffer size", &r.DrainBufferSize, defaults.DrainBufferSize)
+}
+
+// checkRange replaces r by its default if it is zero, or if its minimum is
+// above its maximum, recording a violation for the latter
+func checkRange[T cmp.Ordered](vs *violations, field string, r *Range[T], fallback Range[T]) {
+	if *r == (Range[T]{}) {
+		*r = fallback
+		return
+	}
+
+	// Written so that NaN bounds are rejected too
+	if !(r.Min <= r.Max) {
+		*vs = append(*vs, violation{
+			field:    field + " range",
+			rule:     "must have a minimum not above its maximum",
+			value:    fmt.Sprintf("[%v, %v]", r.Min, r.Max),
+			fallback: fmt.Sprintf("[%v, %v]", fallback.Min, fallback.Max),
+			message:  "Invalid " + field + " range, using the default range",
+		})
+		*r = fallback
+	}
+}
@@ -0,0 +1,74 @@
+package httpretrier
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientBuilder_WithValidationRanges(t *testing.T) {
+	// The default ranges reject a 60s timeout
+	client := NewClientBuilder().WithTimeout(60 * time.Second).Build()
+	if client.Timeout != DefaultTimeout {
+		t.Errorf("Expected the default timeout, got %v", client.Timeout)
+	}
+
+	builder := NewClientBuilder().
+		WithValidationRanges(ValidationRanges{
+			Timeout:    Range[time.Duration]{Min: 1 * time.Second, Max: 2 * time.Minute},
+			MaxRetries: Range[int]{Min: 1, Max: 20},
+		}).
+		WithTimeout(60 * time.Second).
+		WithMaxRetries(15)
+	client = builder.Build()
+
+	if len(builder.Warnings()) != 0 {
+		t.Errorf("Expected no warnings, got %v", builder.Warnings())
+	}
+	if client.Timeout != 60*time.Second {
+		t.Errorf("Expected a 60s timeout, got %v", client.Timeout)
+	}
+	if rt := client.Transport.(*retryTransport); rt.MaxRetries != 15 {
+		t.Errorf("Expected 15 retries, got %d", rt.MaxRetries)
+	}
+
+	// Unset ranges keep their default, the attempts follow the retries
+	if got := builder.client.ranges.BaseDelay; got != DefaultValidationRanges().BaseDelay {
+		t.Errorf("Expected the default base delay range, got %v", got)
+	}
+	if got := builder.client.ranges.MaxAttempts; got != (Range[int]{Min: 1, Max: 21}) {
+		t.Errorf("Expected max attempts up to 21, got %v", got)
+	}
+
+	// Values outside a custom range are still rejected
+	_, err := NewClientBuilder().
+		WithValidationRanges(ValidationRanges{Timeout: Range[time.Duration]{Min: 1 * time.Second, Max: 10 * time.Second}}).
+		WithTimeout(20 * time.Second).
+		BuildStrict()
+	var clientErr *ClientError
+	if !errors.As(err, &clientErr) || !strings.Contains(clientErr.Message, "timeout 20s must be between 1s and 10s") {
+		t.Errorf("Expected the timeout to be rejected, got %v", err)
+	}
+}
+
+func TestClientBuilder_WithValidationRangesInvalid(t *testing.T) {
+	builder := NewClientBuilder().
+		WithValidationRanges(ValidationRanges{
+			Timeout:         Range[time.Duration]{Min: 1 * time.Minute, Max: 1 * time.Second},
+			RetryMultiplier: Range[float64]{Min: math.NaN(), Max: 3},
+		}).
+		WithTimeout(20 * time.Second)
+	client := builder.Build()
+
+	if len(builder.Warnings()) != 2 {
+		t.Errorf("Expected 2 warnings for the ranges, got %v", builder.Warnings())
+	}
+	if got := builder.client.ranges.Timeout; got != DefaultValidationRanges().Timeout {
+		t.Errorf("Expected the default timeout range, got %v", got)
+	}
+	if client.Timeout != 20*time.Second {
+		t.Errorf("Expected the timeout within the default range to be kept, got %v", client.Timeout)
+	}
+}
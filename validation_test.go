@@ -0,0 +1,103 @@
+package httpretrier
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientBuilder_BuildStrict(t *testing.T) {
+	httpClient, err := NewClientBuilder().
+		WithMaxAttempts(2).
+		WithRetryBaseDelay(1 * time.Second).
+		BuildStrict()
+	assert.NoError(t, err)
+
+	rt, ok := httpClient.Transport.(*retryTransport)
+	assert.True(t, ok)
+	assert.Equal(t, 1, rt.MaxRetries)
+	assert.Equal(t, 1*time.Second, rt.config.BaseDelay)
+}
+
+func TestClientBuilder_BuildStrictAggregatesViolations(t *testing.T) {
+	builder := NewClientBuilder().
+		WithMaxRetries(-1).
+		WithRetryBaseDelay(1 * time.Millisecond).
+		WithTimeout(1 * time.Hour).
+		WithRetryStrategy("bogus")
+
+	httpClient, err := builder.BuildStrict()
+	assert.Nil(t, httpClient)
+
+	var clientErr *ClientError
+	if !errors.As(err, &clientErr) {
+		t.Fatalf("Expected a *ClientError, got %v", err)
+	}
+
+	// Every violation is listed, not only the first
+	for _, expected := range []string{
+		"max retries -1 must be between 0 and 10",
+		"base delay 1ms must be between 300ms and 5s",
+		"timeout 1h0m0s must be between 1s and 30s",
+		`retry strategy "bogus" is not a known strategy`,
+	} {
+		assert.Contains(t, err.Error(), expected)
+	}
+	assert.Equal(t, 3, strings.Count(err.Error(), ";"))
+
+	// The builder is left as configured, so Build still falls back to defaults
+	rt, _ := builder.Build().Transport.(*retryTransport)
+	assert.Equal(t, DefaultMaxRetries, rt.MaxRetries)
+	assert.Equal(t, DefaultBaseDelay, rt.config.BaseDelay)
+}
+
+func TestClientBuilder_Validate(t *testing.T) {
+	assert.NoError(t, NewClientBuilder().WithMaxRetries(5).Validate())
+
+	builder := NewClientBuilder().
+		WithMaxRetries(-1).
+		WithTimeout(1 * time.Hour)
+
+	err := builder.Validate()
+	var clientErr *ClientError
+	if !errors.As(err, &clientErr) {
+		t.Fatalf("Expected a *ClientError, got %v", err)
+	}
+	assert.Contains(t, err.Error(), "max retries -1 must be between 0 and 10")
+	assert.Contains(t, err.Error(), "timeout 1h0m0s must be between 1s and 30s")
+
+	// The builder keeps the invalid values and records no warnings
+	assert.Equal(t, -1, builder.MaxRetries())
+	assert.Equal(t, 1*time.Hour, builder.Timeout())
+	assert.Empty(t, builder.Warnings())
+
+	// It reports the same problems as BuildStrict
+	_, strictErr := builder.BuildStrict()
+	assert.Equal(t, strictErr, err)
+}
+
+func TestClientBuilder_BuildStrictMaxRetriesAndAttempts(t *testing.T) {
+	_, err := NewClientBuilder().WithMaxRetries(2).WithMaxAttempts(2).BuildStrict()
+	assert.ErrorContains(t, err, "max retries 2 must not be set together with max attempts")
+}
+
+func TestClientBuilder_Warnings(t *testing.T) {
+	builder := NewClientBuilder()
+	assert.Empty(t, builder.Warnings())
+
+	builder.WithMaxRetries(-1).WithRetryBaseDelay(1 * time.Millisecond).Build()
+	assert.Equal(t, []string{
+		"max retries: invalid value -1 (must be between 0 and 10), using 3",
+		"base delay: invalid value 1ms (must be between 300ms and 5s), using 500ms",
+	}, builder.Warnings())
+
+	// Each Build starts over; the clamped values are now valid
+	builder.Build()
+	assert.Empty(t, builder.Warnings())
+
+	builder.WithTimeout(0).Build()
+	assert.Equal(t, []string{"timeout: invalid value 0s (must be between 1s and 30s), using 5s"}, builder.Warnings())
+}
@@ -0,0 +1,43 @@
+package httpretrier
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// validateResponse buffers the body of resp, up to MaxValidateBodySize bytes,
+// and reports whether the ResponseValidator rejects it. The body is replaced
+// by the buffered bytes, so it can still be read. A body larger than the limit
+// is not validated, and is put back together from the bytes read and the rest.
+// If the body can't be read, it is closed and the error is returned.
+func (r *retryTransport) validateResponse(resp *http.Response) (invalid bool, err error) {
+	maxSize := r.MaxValidateBodySize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxValidateBodySize
+	}
+
+	body := resp.Body
+	buf, err := io.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		body.Close()
+		return false, fmt.Errorf("failed to read response body for validation: %w", err)
+	}
+
+	if int64(len(buf)) > maxSize {
+		r.logger().Debug("Response body too large to validate", "limit", maxSize)
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), body), body}
+		return false, nil
+	}
+
+	if err := body.Close(); err != nil {
+		return false, fmt.Errorf("failed to close response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(buf))
+
+	return !r.ResponseValidator(resp, buf), nil
+}
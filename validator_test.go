@@ -0,0 +1,138 @@
+package httpretrier
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport_ResponseValidator(t *testing.T) {
+	var attempts int
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			body := `{"status":"error"}`
+			if attempts == 3 {
+				body = `{"status":"ok"}`
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	var validated []string
+	retryRT := &retryTransport{
+		Transport:     mockRT,
+		MaxRetries:    3,
+		RetryStrategy: FixedDelay(1 * time.Millisecond),
+		ResponseValidator: func(resp *http.Response, body []byte) bool {
+			validated = append(validated, string(body))
+			return !bytes.Contains(body, []byte(`"error"`))
+		},
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if attempts != 3 || len(validated) != 3 {
+		t.Errorf("Expected 3 validated attempts, got %d attempts and %d validations", attempts, len(validated))
+	}
+
+	// The caller still reads the body
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || string(data) != `{"status":"ok"}` {
+		t.Errorf("Expected the buffered body, got %q (%v)", data, err)
+	}
+}
+
+func TestRetryTransport_ResponseValidatorSkipsFailures(t *testing.T) {
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       io.NopCloser(strings.NewReader("Unavailable")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:          mockRT,
+		MaxRetries:         1,
+		RetryStrategy:      FixedDelay(1 * time.Millisecond),
+		ReturnLastResponse: true,
+		ResponseValidator: func(resp *http.Response, body []byte) bool {
+			t.Error("Expected failed responses not to be validated")
+			return true
+		},
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected the last response, got %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestRetryTransport_ResponseValidatorBodyTooLarge(t *testing.T) {
+	body := strings.Repeat("x", 100)
+	mockRT := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	retryRT := &retryTransport{
+		Transport:           mockRT,
+		MaxRetries:          1,
+		RetryStrategy:       FixedDelay(1 * time.Millisecond),
+		MaxValidateBodySize: 10,
+		ResponseValidator: func(resp *http.Response, body []byte) bool {
+			t.Error("Expected a body over the limit not to be validated")
+			return false
+		},
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	resp, err := retryRT.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || string(data) != body {
+		t.Errorf("Expected the whole body, got %d bytes (%v)", len(data), err)
+	}
+}
+
+func TestClientBuilder_WithResponseValidator(t *testing.T) {
+	rt := NewClientBuilder().Build().Transport.(*retryTransport)
+	if rt.ResponseValidator != nil || rt.MaxValidateBodySize != DefaultMaxValidateBodySize {
+		t.Errorf("Expected no validator and the default size, got %d", rt.MaxValidateBodySize)
+	}
+
+	rt = NewClientBuilder().
+		WithResponseValidator(func(*http.Response, []byte) bool { return true }).
+		WithMaxValidateBodySize(-1).
+		Build().Transport.(*retryTransport)
+	if rt.ResponseValidator == nil || rt.MaxValidateBodySize != DefaultMaxValidateBodySize {
+		t.Errorf("Expected the validator with the default size, got %d", rt.MaxValidateBodySize)
+	}
+}